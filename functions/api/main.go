@@ -20,6 +20,8 @@ import (
 	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
 	"github.com/shoet/webpagesummary/pkg/presentation/server"
 	"github.com/shoet/webpagesummary/pkg/presentation/server/middleware"
+	"github.com/shoet/webpagesummary/pkg/usecase/get_summary"
+	"github.com/shoet/webpagesummary/pkg/usecase/request_task"
 )
 
 func ExitOnErr(err error) {
@@ -66,9 +68,20 @@ func BuildEchoServer() (*echo.Echo, error) {
 
 	setRequestContextMiddleware := middleware.NewSetRequestContextMiddleware(cfg.APIKey, cfg.CognitoJWKUrl)
 
+	// スナップショットを保存しない環境ではダウンロード用のURLを返さない
+	// アップロードされた文書も同じバケットに保存するため、バケットがない環境では受け付けない
+	var snapshotStorage get_summary.SnapshotStorage
+	var uploadStorage request_task.UploadStorage
+	if cfg.SnapshotBucket != "" {
+		objectStorage := adapter.NewObjectStorage(awsCfg, cfg.SnapshotBucket, cfg.SnapshotUsePathStyle)
+		snapshotStorage = objectStorage
+		uploadStorage = objectStorage
+	}
+
 	deps, err := server.NewServerDependencies(
 		&cfg.Env, validator, queueClient, ddb, rdbHandler,
 		cfg.GetCORSWhiteList(), rateLimitterMiddleware, setRequestContextMiddleware,
+		cfg.NewURLPolicy(), snapshotStorage, time.Duration(cfg.SnapshotLinkTTLSec)*time.Second, uploadStorage,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed create server dependencies: %s", err.Error())
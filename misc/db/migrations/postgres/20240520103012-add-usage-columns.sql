@@ -0,0 +1,14 @@
+
+-- +migrate Up
+ALTER TABLE tasks ADD COLUMN model VARCHAR(255) NULL;
+ALTER TABLE tasks ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN total_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN cost NUMERIC(12, 6) NOT NULL DEFAULT 0; -- USD
+
+-- +migrate Down
+ALTER TABLE tasks DROP COLUMN cost;
+ALTER TABLE tasks DROP COLUMN total_tokens;
+ALTER TABLE tasks DROP COLUMN completion_tokens;
+ALTER TABLE tasks DROP COLUMN prompt_tokens;
+ALTER TABLE tasks DROP COLUMN model;
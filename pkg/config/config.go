@@ -5,13 +5,14 @@ import (
 	"strings"
 
 	"github.com/caarlos0/env/v10"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
 )
 
 type Config struct {
 	Env                    string `env:"ENV,required"`
 	QueueUrl               string `env:"QUEUE_URL,required"`
 	BrowserPath            string `env:"BROWSER_PATH,required"`
-	OpenAIApiKey           string `env:"OPENAI_API_KEY,required"`
+	OpenAIApiKey           string `env:"OPENAI_API_KEY"`
 	ExecTimeout            int    `env:"EXEC_TIMEOUT_SEC" envDefault:"300"`
 	BrowserDownloadPath    string `env:"BROWSER_DOWNLOAD_PATH" envDefault:"/tmp/playwright/browser"`
 	CORSWhiteList          string `env:"CORS_WHITE_LIST,required"`
@@ -19,6 +20,129 @@ type Config struct {
 	RequestRateLimitMax    int    `env:"REQUEST_RATE_LIMIT_MAX,required" envDefault:"10"`
 	RequestRateLimitTTLSec int    `env:"REQUEST_RATE_LIMIT_TTL_SEC,required" envDefault:"86400"`
 	APIKey                 string `env:"API_KEY,required"`
+	CrawlerConfig
+	LLMConfig
+	URLPolicyConfig
+	SnapshotConfig
+	TaskRetryConfig
+}
+
+// CrawlerConfigはページの取得と本文の抽出の設定
+type CrawlerConfig struct {
+	// ScraperRulesPathはサイトごとの抽出ルールを定義したYAMLまたはJSONのファイルのパス
+	// s3://bucket/keyの形式の場合はS3のオブジェクトから読み込み、ETagが変わると読み込み直す
+	ScraperRulesPath string `env:"SCRAPER_RULES_PATH"`
+	// StaticFetchEnabledはブラウザを起動する前にHTTPでのページの取得を試みるか
+	// PDFはHTTPで取得した場合のみ本文を抽出できる
+	StaticFetchEnabled bool `env:"STATIC_FETCH_ENABLED" envDefault:"true"`
+	// StaticFetchMinContentLengthはHTTPで取得した本文として扱う最小の文字数。これより短い場合はブラウザで取得する
+	StaticFetchMinContentLength int `env:"STATIC_FETCH_MIN_CONTENT_LENGTH" envDefault:"500"`
+	StaticFetchTimeoutSec       int `env:"STATIC_FETCH_TIMEOUT_SEC" envDefault:"30"`
+	// PDFMaxPagesはPDFから本文として抽出する最大のページ数
+	PDFMaxPages int `env:"PDF_MAX_PAGES" envDefault:"50"`
+	// CrawlerUserAgentはページやrobots.txtを取得する際のUser-Agent。空の場合はクローラーの既定値を利用する
+	CrawlerUserAgent string `env:"CRAWLER_USER_AGENT"`
+	// CrawlerRobotsAgentはrobots.txtのUser-agentと照合するクローラーの名前
+	CrawlerRobotsAgent string `env:"CRAWLER_ROBOTS_AGENT" envDefault:"WebPageSummarizer"`
+	RobotsEnabled      bool   `env:"ROBOTS_ENABLED" envDefault:"true"`
+	RobotsCacheTTLSec  int    `env:"ROBOTS_CACHE_TTL_SEC" envDefault:"86400"`
+	// CrawlThrottleEnabledはDynamoDBでワーカー間のドメインごとの取得の間隔と同時取得数を制限するか
+	CrawlThrottleEnabled bool `env:"CRAWL_THROTTLE_ENABLED" envDefault:"true"`
+	// CrawlMinDelayMsは同じドメインから取得する最小の間隔。robots.txtのCrawl-delayの方が長い場合はそちらに従う
+	CrawlMinDelayMs int `env:"CRAWL_MIN_DELAY_MS" envDefault:"1000"`
+	// CrawlMaxCrawlDelaySecはrobots.txtのCrawl-delayとして従う最大の間隔
+	CrawlMaxCrawlDelaySec     int `env:"CRAWL_MAX_CRAWL_DELAY_SEC" envDefault:"30"`
+	CrawlMaxInFlightPerDomain int `env:"CRAWL_MAX_IN_FLIGHT_PER_DOMAIN" envDefault:"2"`
+	// CrawlThrottleMaxWaitSecはドメインの取得の順番を待つ最大の時間
+	CrawlThrottleMaxWaitSec int `env:"CRAWL_THROTTLE_MAX_WAIT_SEC" envDefault:"120"`
+	// BrowserBlockedResourceTypesはブラウザでページを表示する際に取得しないリソースの種類
+	BrowserBlockedResourceTypes []string `env:"BROWSER_BLOCKED_RESOURCE_TYPES" envSeparator:"," envDefault:"image,media,font"`
+	// BrowserBlockAdDomainsはクローラーの既定の広告やトラッキングのドメインを取得しないか
+	BrowserBlockAdDomains bool `env:"BROWSER_BLOCK_AD_DOMAINS" envDefault:"true"`
+	// BrowserBlockedDomainsは既定のドメインに加えて取得しないドメイン
+	BrowserBlockedDomains []string `env:"BROWSER_BLOCKED_DOMAINS" envSeparator:","`
+	// BrowserWaitUntilはページの表示の完了とみなすイベント(load、domcontentloaded、networkidle、commit)
+	BrowserWaitUntil string `env:"BROWSER_WAIT_UNTIL" envDefault:"domcontentloaded"`
+	// BrowserDomainWaitUntilはドメインごとのBrowserWaitUntil。"example.com:networkidle,example.org:load"の形式で指定する
+	BrowserDomainWaitUntil      map[string]string `env:"BROWSER_DOMAIN_WAIT_UNTIL" envSeparator:"," envKeyValSeparator:":"`
+	BrowserNavigationTimeoutSec int               `env:"BROWSER_NAVIGATION_TIMEOUT_SEC" envDefault:"60"`
+	// BrowserDismissConsentはCookieの同意ダイアログを閉じてから本文を抽出するか
+	BrowserDismissConsent bool `env:"BROWSER_DISMISS_CONSENT" envDefault:"true"`
+}
+
+// URLPolicyConfigは要約するURLと接続先の検証の設定
+type URLPolicyConfig struct {
+	URLAllowedSchemes []string `env:"URL_ALLOWED_SCHEMES" envSeparator:"," envDefault:"http,https"`
+	// URLAllowedDomainsが空でない場合は、このドメインとそのサブドメインのみ要約を許可する
+	URLAllowedDomains []string `env:"URL_ALLOWED_DOMAINS" envSeparator:","`
+	URLDeniedDomains  []string `env:"URL_DENIED_DOMAINS" envSeparator:","`
+	// URLAllowPrivateNetworksはローカルでの開発用に、プライベートネットワークへの接続を許可する
+	URLAllowPrivateNetworks bool `env:"URL_ALLOW_PRIVATE_NETWORKS" envDefault:"false"`
+}
+
+func (c *URLPolicyConfig) NewURLPolicy() *urlpolicy.Policy {
+	return urlpolicy.NewPolicy(&urlpolicy.Config{
+		AllowedSchemes:       c.URLAllowedSchemes,
+		AllowedDomains:       c.URLAllowedDomains,
+		DeniedDomains:        c.URLDeniedDomains,
+		AllowPrivateNetworks: c.URLAllowPrivateNetworks,
+	})
+}
+
+// SnapshotConfigは取得した時点のページのスナップショットをS3に保存する設定
+type SnapshotConfig struct {
+	// SnapshotBucketが空の場合はスナップショットを保存しない
+	SnapshotBucket string `env:"SNAPSHOT_BUCKET"`
+	// SnapshotUsePathStyleはLocalStackなどでバケット名をパスに含めてリクエストするか
+	SnapshotUsePathStyle bool `env:"SNAPSHOT_USE_PATH_STYLE" envDefault:"false"`
+	// SnapshotScreenshotとSnapshotMHTMLはブラウザで取得した場合にスクリーンショットとMHTMLを保存するか
+	SnapshotScreenshot bool `env:"SNAPSHOT_SCREENSHOT" envDefault:"true"`
+	SnapshotMHTML      bool `env:"SNAPSHOT_MHTML" envDefault:"true"`
+	// SnapshotLinkTTLSecは/get-summaryで返すダウンロード用のURLの有効期間
+	SnapshotLinkTTLSec int `env:"SNAPSHOT_LINK_TTL_SEC" envDefault:"900"`
+}
+
+// TaskRetryConfigは失敗したタスクの再実行とキャンセルの設定
+type TaskRetryConfig struct {
+	// TaskMaxAttemptsはタイムアウトなど一時的なエラーで失敗したタスクを実行する最大の回数
+	TaskMaxAttempts int `env:"TASK_MAX_ATTEMPTS" envDefault:"3"`
+	// TaskRetryBaseDelaySecは1回目の再実行までの待機時間 (以降は指数関数的に増加する)
+	TaskRetryBaseDelaySec int `env:"TASK_RETRY_BASE_DELAY_SEC" envDefault:"60"`
+	TaskRetryMaxDelaySec  int `env:"TASK_RETRY_MAX_DELAY_SEC" envDefault:"900"`
+	// TaskMaxReceiveCountはタスクのキューのリドライブポリシーのmaxReceiveCount
+	// この回数受信したメッセージのタスクが失敗した場合は、デッドレターキューに移される前に失敗した状態にする
+	TaskMaxReceiveCount int `env:"TASK_MAX_RECEIVE_COUNT" envDefault:"5"`
+	// TaskCancelPollIntervalSecは実行中のワーカーがキャンセルの依頼を確認する間隔
+	TaskCancelPollIntervalSec int `env:"TASK_CANCEL_POLL_INTERVAL_SEC" envDefault:"5"`
+	// DeadLetterQueueUrlは再実行しても成功しないタスクのメッセージを移すキュー。空の場合は削除する
+	DeadLetterQueueUrl string `env:"DEAD_LETTER_QUEUE_URL"`
+}
+
+// LLMConfigは要約に利用するLLMプロバイダーの設定
+type LLMConfig struct {
+	LLMProvider              string   `env:"LLM_PROVIDER" envDefault:"openai"`
+	LLMModel                 string   `env:"LLM_MODEL"`
+	LLMBaseUrl               string   `env:"LLM_BASE_URL"`
+	LLMTemperature           *float64 `env:"LLM_TEMPERATURE"`
+	LLMTopP                  *float64 `env:"LLM_TOP_P"`
+	LLMMaxTokens             *int     `env:"LLM_MAX_TOKENS"`
+	LLMSeed                  *int     `env:"LLM_SEED"`
+	OpenAIOrganization       string   `env:"OPENAI_ORGANIZATION"`
+	LLMStream                bool     `env:"LLM_STREAM" envDefault:"false"`
+	LLMStreamFlushIntervalMs int      `env:"LLM_STREAM_FLUSH_INTERVAL_MS" envDefault:"1000"`
+	// LLMTokenBudgetsはモデルごとのプロンプトのトークン予算 (例: gpt-4=4000,gpt-4o=60000)
+	LLMTokenBudgets map[string]int `env:"LLM_TOKEN_BUDGETS" envKeyValSeparator:"="`
+	// LLMMaxRetriesはレート制限やサーバーエラーなど一時的なエラーの場合のリトライ回数
+	LLMMaxRetries           int    `env:"LLM_MAX_RETRIES" envDefault:"3"`
+	LLMRetryBaseDelayMs     int    `env:"LLM_RETRY_BASE_DELAY_MS" envDefault:"1000"`
+	LLMRetryMaxDelayMs      int    `env:"LLM_RETRY_MAX_DELAY_MS" envDefault:"30000"`
+	AnthropicApiKey         string `env:"ANTHROPIC_API_KEY"`
+	AzureOpenAIApiKey       string `env:"AZURE_OPENAI_API_KEY"`
+	AzureOpenAIEndpoint     string `env:"AZURE_OPENAI_ENDPOINT"`
+	AzureOpenAIDeployment   string `env:"AZURE_OPENAI_DEPLOYMENT"`
+	AzureOpenAIApiVersion   string `env:"AZURE_OPENAI_API_VERSION" envDefault:"2024-02-01"`
+	OpenAICompatibleBaseUrl string `env:"OPENAI_COMPATIBLE_BASE_URL"`
+	OpenAICompatibleApiKey  string `env:"OPENAI_COMPATIBLE_API_KEY"`
 }
 
 func (c *Config) GetCORSWhiteList() []string {
@@ -0,0 +1,88 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectStorageはS3のバケットにオブジェクトを保存し、期限付きのダウンロード用のURLを発行する
+type ObjectStorage struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+}
+
+// NewObjectStorageはbucketのObjectStorageを返す
+// LocalStackなどバケット名をホスト名に含められない環境ではusePathStyleをtrueにする
+func NewObjectStorage(cfg aws.Config, bucket string, usePathStyle bool) *ObjectStorage {
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = usePathStyle
+	})
+	return &ObjectStorage{client: client, presign: s3.NewPresignClient(client), bucket: bucket}
+}
+
+func (o *ObjectStorage) PutObject(ctx context.Context, key string, contentType string, body []byte) error {
+	_, err := o.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(o.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(body),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(int64(len(body))),
+	})
+	if err != nil {
+		return fmt.Errorf("failed PutObject: %w", err)
+	}
+	return nil
+}
+
+// GetObjectはオブジェクトの内容を返す
+func (o *ObjectStorage) GetObject(ctx context.Context, key string) ([]byte, error) {
+	data, _, err := o.GetObjectWithETag(ctx, key)
+	return data, err
+}
+
+// GetObjectWithETagはオブジェクトの内容と、その内容のETagを返す
+func (o *ObjectStorage) GetObjectWithETag(ctx context.Context, key string) ([]byte, string, error) {
+	output, err := o.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(o.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed GetObject: %w", err)
+	}
+	defer output.Body.Close()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(output.Body); err != nil {
+		return nil, "", fmt.Errorf("failed read object: %w", err)
+	}
+	return buf.Bytes(), aws.ToString(output.ETag), nil
+}
+
+// GetObjectETagはオブジェクトの内容を取得せずにETagを返す
+func (o *ObjectStorage) GetObjectETag(ctx context.Context, key string) (string, error) {
+	output, err := o.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(o.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed HeadObject: %w", err)
+	}
+	return aws.ToString(output.ETag), nil
+}
+
+// PresignGetObjectはexpiresの間オブジェクトをダウンロードできるURLを返す
+func (o *ObjectStorage) PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error) {
+	req, err := o.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(o.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("failed PresignGetObject: %w", err)
+	}
+	return req.URL, nil
+}
@@ -0,0 +1,51 @@
+package adapter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/testutil"
+)
+
+func Test_ObjectStorage(t *testing.T) {
+	ctx := context.Background()
+	testAwsCfg, err := testutil.NewAwsConfigForTest(t, ctx)
+	if err != nil {
+		t.Fatalf("failed load aws config: %s\n", err.Error())
+	}
+	bucket := "test-snapshots"
+	if err := testutil.CreateS3BucketForTest(ctx, *testAwsCfg, bucket); err != nil {
+		t.Fatalf("failed create bucket: %s\n", err.Error())
+	}
+
+	sut := NewObjectStorage(*testAwsCfg, bucket, true)
+	key := "snapshots/task/hash/page.html"
+	body := []byte("<html><body>snapshot</body></html>")
+	if err := sut.PutObject(ctx, key, "text/html; charset=utf-8", body); err != nil {
+		t.Fatalf("failed PutObject: %s\n", err.Error())
+	}
+	got, err := sut.GetObject(ctx, key)
+	if err != nil {
+		t.Fatalf("failed GetObject: %s\n", err.Error())
+	}
+	if string(got) != string(body) {
+		t.Errorf("GetObject() = %s, want %s", got, body)
+	}
+
+	link, err := sut.PresignGetObject(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatalf("failed PresignGetObject: %s\n", err.Error())
+	}
+	resp, err := http.Get(link)
+	if err != nil {
+		t.Fatalf("failed get presigned url: %s\n", err.Error())
+	}
+	defer resp.Body.Close()
+	downloaded, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(downloaded) != string(body) {
+		t.Errorf("presigned url returned %d: %s", resp.StatusCode, downloaded)
+	}
+}
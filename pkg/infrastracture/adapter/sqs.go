@@ -2,10 +2,14 @@ package adapter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
 type QueueClient struct {
@@ -29,6 +33,51 @@ func (q *QueueClient) Queue(ctx context.Context, message string) error {
 	return nil
 }
 
+// sendMessageBatchLimitはSendMessageBatchで1回に送信できるメッセージの最大の数
+const sendMessageBatchLimit = 10
+
+// QueueBatchは複数のメッセージをSendMessageBatchでまとめて送信する
+// 送信できなかったメッセージのmessagesでの位置と、その原因のエラーを返す
+func (q *QueueClient) QueueBatch(ctx context.Context, messages []string) ([]int, error) {
+	var failed []int
+	var errs []error
+	for start := 0; start < len(messages); start += sendMessageBatchLimit {
+		end := start + sendMessageBatchLimit
+		if end > len(messages) {
+			end = len(messages)
+		}
+		entries := make([]types.SendMessageBatchRequestEntry, 0, end-start)
+		for i := start; i < end; i++ {
+			entries = append(entries, types.SendMessageBatchRequestEntry{
+				Id:          aws.String(strconv.Itoa(i)),
+				MessageBody: aws.String(messages[i]),
+			})
+		}
+		output, err := q.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			Entries:  entries,
+			QueueUrl: aws.String(q.queueUrl),
+		})
+		if err != nil {
+			for i := start; i < end; i++ {
+				failed = append(failed, i)
+			}
+			errs = append(errs, fmt.Errorf("failed SendMessageBatch: %w", err))
+			continue
+		}
+		for _, entry := range output.Failed {
+			i, err := strconv.Atoi(aws.ToString(entry.Id))
+			if err != nil {
+				return nil, fmt.Errorf("failed SendMessageBatch: unknown entry id: %s", aws.ToString(entry.Id))
+			}
+			failed = append(failed, i)
+			errs = append(errs, fmt.Errorf(
+				"failed SendMessageBatch entry %d: %s: %s", i, aws.ToString(entry.Code), aws.ToString(entry.Message),
+			))
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
 var ErrEmptyQueue = fmt.Errorf("empty queue")
 
 func (q *QueueClient) Dequeue(ctx context.Context) (string, error) {
@@ -61,3 +110,17 @@ func (q *QueueClient) DeleteMessage(ctx context.Context, receiptHandle string) e
 	}
 	return nil
 }
+
+// ChangeMessageVisibilityは受信したメッセージを再び受信できるまでの時間をtimeoutに変更する
+// 一時的なエラーで失敗したタスクを時間をおいて再実行するために利用する
+func (q *QueueClient) ChangeMessageVisibility(ctx context.Context, receiptHandle string, timeout time.Duration) error {
+	_, err := q.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.queueUrl),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: int32(timeout.Seconds()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed ChangeMessageVisibility: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package entities
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// MaxBatchSizeは1回のバッチで依頼できるURLの最大の数
+	MaxBatchSize = 500
+	// MaxBatchListBytesはアップロードできるURLの一覧(CSVや改行区切りのテキスト)の最大のサイズ
+	MaxBatchListBytes = 1 << 20
+)
+
+var (
+	// ErrEmptyBatchはバッチに要約するURLが1件も含まれない場合のエラー
+	ErrEmptyBatch = errors.New("batch has no urls")
+	// ErrBatchTooLargeはバッチのURLがMaxBatchSizeを超える場合のエラー
+	ErrBatchTooLarge = errors.New("batch is too large")
+)
+
+// Batchはまとめて依頼された要約のタスクのグループ
+type Batch struct {
+	Id     string `json:"id" dynamodbav:"id"`
+	UserId string `json:"userId,omitempty" dynamodbav:"user_id"`
+	// Nameは読書リストやカンファレンス名など、バッチを識別するための任意の名前
+	Name string `json:"name,omitempty" dynamodbav:"batch_name,omitempty"`
+	// TaskIdsは依頼された順のタスクのID
+	TaskIds []string `json:"taskIds" dynamodbav:"task_ids"`
+	// Rejectedは検証に失敗してタスクを作成しなかったURL
+	Rejected  []BatchRejectedUrl `json:"rejected,omitempty" dynamodbav:"rejected,omitempty"`
+	CreatedAt int64              `json:"createdAt" dynamodbav:"created_at"`
+}
+
+// BatchRejectedUrlはバッチで受け付けなかったURLとその理由
+type BatchRejectedUrl struct {
+	Url    string `json:"url" dynamodbav:"url"`
+	Reason string `json:"reason" dynamodbav:"reason"`
+}
+
+// ParseBatchUrlsはアップロードされたURLの一覧を読み込む
+// CSVの場合は見出しの行にurlの列があればその列を、なければ1列目をURLとして読み込む
+// 改行区切りの場合は1行を1件のURLとし、空行と#で始まる行は読み飛ばす
+func ParseBatchUrls(data []byte, isCSV bool) ([]string, error) {
+	if len(data) > MaxBatchListBytes {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrBatchTooLarge, len(data), MaxBatchListBytes)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if isCSV {
+		return parseBatchCSV(data)
+	}
+	var urls []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read url list: %w", err)
+	}
+	return urls, nil
+}
+
+func parseBatchCSV(data []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	column := 0
+	var urls []string
+	for line := 0; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		if line == 0 {
+			if i := indexOfUrlColumn(record); i >= 0 {
+				column = i
+				continue
+			}
+		}
+		if column >= len(record) {
+			continue
+		}
+		if url := strings.TrimSpace(record[column]); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls, nil
+}
+
+func indexOfUrlColumn(header []string) int {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "url") {
+			return i
+		}
+	}
+	return -1
+}
+
+// BatchProgressはバッチに含まれるタスクの状態ごとの件数
+type BatchProgress struct {
+	Total int `json:"total"`
+	// Queuedは実行を待っているタスク(queued、retrying)の件数
+	Queued int `json:"queued"`
+	// Runningはワーカーが実行しているタスク(crawling、crawled、summarizing)の件数
+	Running   int `json:"running"`
+	Complete  int `json:"complete"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+	// Percentは完了、失敗、キャンセルのいずれかになったタスクの割合
+	Percent int `json:"percent"`
+	// Doneはすべてのタスクが完了、失敗、キャンセルのいずれかになったか
+	Done bool `json:"done"`
+}
+
+// NewBatchProgressはバッチに含まれるタスクの状態を集計する
+func NewBatchProgress(summaries []*Summary) *BatchProgress {
+	p := &BatchProgress{Total: len(summaries)}
+	for _, s := range summaries {
+		switch status := s.TaskStatus.Normalize(); {
+		case status == TaskStatusComplete:
+			p.Complete++
+		case status == TaskStatusFailed:
+			p.Failed++
+		case status == TaskStatusCancelled:
+			p.Cancelled++
+		case status.InProgress():
+			p.Running++
+		default:
+			p.Queued++
+		}
+	}
+	finished := p.Complete + p.Failed + p.Cancelled
+	if p.Total > 0 {
+		p.Percent = finished * 100 / p.Total
+	}
+	p.Done = finished == p.Total
+	return p
+}
+
+// BatchStatusはバッチと、含まれるタスクの状態
+type BatchStatus struct {
+	*Batch
+	Progress *BatchProgress `json:"progress"`
+	// TasksはTaskIdsの順のタスク。本文は含まない
+	Tasks []*Summary `json:"tasks"`
+}
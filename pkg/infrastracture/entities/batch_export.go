@@ -0,0 +1,66 @@
+package entities
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// バッチの要約をまとめて出力する形式
+const (
+	BatchExportFormatJSON     = "json"
+	BatchExportFormatCSV      = "csv"
+	BatchExportFormatMarkdown = "markdown"
+)
+
+var batchExportHeader = []string{"task_id", "url", "title", "status", "failed_reason", "summary"}
+
+// WriteBatchCSVはバッチのタスクを1行1件のCSVで出力する
+func WriteBatchCSV(w io.Writer, status *BatchStatus) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(batchExportHeader); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, s := range status.Tasks {
+		record := []string{s.Id, s.PageUrl, s.Title, string(s.TaskStatus), s.TaskFailedReason, s.Summary}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write csv record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteBatchMarkdownはバッチのタスクを1件ごとに見出しを付けたMarkdownで出力する
+// 完了していないタスクは要約の代わりに状態を出力する
+func WriteBatchMarkdown(w io.Writer, status *BatchStatus) error {
+	var b strings.Builder
+	name := status.Name
+	if name == "" {
+		name = status.Id
+	}
+	fmt.Fprintf(&b, "# %s\n", name)
+	for _, s := range status.Tasks {
+		title := s.Title
+		if title == "" {
+			title = s.PageUrl
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", title)
+		if s.PageUrl != "" {
+			fmt.Fprintf(&b, "<%s>\n\n", s.PageUrl)
+		}
+		switch {
+		case s.TaskStatus.Normalize() == TaskStatusComplete:
+			fmt.Fprintf(&b, "%s\n", strings.TrimSpace(s.Summary))
+		case s.TaskFailedReason != "":
+			fmt.Fprintf(&b, "_%s: %s_\n", s.TaskStatus, s.TaskFailedReason)
+		default:
+			fmt.Fprintf(&b, "_%s_\n", s.TaskStatus)
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write markdown: %w", err)
+	}
+	return nil
+}
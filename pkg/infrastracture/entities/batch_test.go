@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ParseBatchUrls(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		isCSV   bool
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "newline list",
+			data: "https://example.com/a\r\n\n# 読書リスト\n  https://example.com/b?x=1,2  \n",
+			want: []string{"https://example.com/a", "https://example.com/b?x=1,2"},
+		},
+		{
+			name:  "csv with url header",
+			data:  "\xef\xbb\xbftitle,URL\n基調講演,https://example.com/keynote\n\"セッション, 1\",https://example.com/s1\n空行,\n",
+			isCSV: true,
+			want:  []string{"https://example.com/keynote", "https://example.com/s1"},
+		},
+		{
+			name:  "csv without header",
+			data:  "https://example.com/a,記事A\nhttps://example.com/b\n",
+			isCSV: true,
+			want:  []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{name: "broken csv", data: "\"https://example.com/a\n", isCSV: true, wantErr: csv.ErrQuote},
+		{name: "too large", data: strings.Repeat("a", MaxBatchListBytes+1), wantErr: ErrBatchTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBatchUrls([]byte(tt.data), tt.isCSV)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseBatchUrls() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseBatchUrls() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_NewBatchProgress(t *testing.T) {
+	summaries := []*Summary{
+		{TaskStatus: TaskStatusComplete},
+		{TaskStatus: TaskStatusComplete},
+		{TaskStatus: TaskStatusFailed},
+		{TaskStatus: TaskStatusSummarizing},
+		// 以前の形式の状態
+		{TaskStatus: "processing"},
+		{TaskStatus: TaskStatusRetrying},
+		{TaskStatus: TaskStatusQueued},
+		{TaskStatus: TaskStatusCancelled},
+	}
+	want := &BatchProgress{
+		Total: 8, Queued: 2, Running: 2, Complete: 2, Failed: 1, Cancelled: 1, Percent: 50,
+	}
+	if diff := cmp.Diff(want, NewBatchProgress(summaries)); diff != "" {
+		t.Errorf("NewBatchProgress() mismatch (-want +got):\n%s", diff)
+	}
+
+	done := NewBatchProgress([]*Summary{{TaskStatus: TaskStatusComplete}, {TaskStatus: TaskStatusFailed}})
+	if !done.Done || done.Percent != 100 {
+		t.Errorf("NewBatchProgress() = %+v, want done", done)
+	}
+}
+
+func Test_WriteBatchExport(t *testing.T) {
+	status := &BatchStatus{
+		Batch: &Batch{Id: "batch1", Name: "読書リスト"},
+		Tasks: []*Summary{
+			{Id: "task1", PageUrl: "https://example.com/a", Title: "記事A", TaskStatus: TaskStatusComplete, Summary: "要約, その1\n"},
+			{Id: "task2", PageUrl: "https://example.com/b", TaskStatus: TaskStatusFailed, TaskFailedReason: "timeout"},
+			{Id: "task3", PageUrl: "https://example.com/c", TaskStatus: TaskStatusQueued},
+		},
+	}
+
+	var csvBuffer bytes.Buffer
+	if err := WriteBatchCSV(&csvBuffer, status); err != nil {
+		t.Fatalf("WriteBatchCSV() error = %v", err)
+	}
+	wantCSV := "task_id,url,title,status,failed_reason,summary\n" +
+		"task1,https://example.com/a,記事A,complete,,\"要約, その1\n\"\n" +
+		"task2,https://example.com/b,,failed,timeout,\n" +
+		"task3,https://example.com/c,,queued,,\n"
+	if diff := cmp.Diff(wantCSV, csvBuffer.String()); diff != "" {
+		t.Errorf("WriteBatchCSV() mismatch (-want +got):\n%s", diff)
+	}
+
+	var markdownBuffer bytes.Buffer
+	if err := WriteBatchMarkdown(&markdownBuffer, status); err != nil {
+		t.Fatalf("WriteBatchMarkdown() error = %v", err)
+	}
+	wantMarkdown := "# 読書リスト\n" +
+		"\n## 記事A\n\n<https://example.com/a>\n\n要約, その1\n" +
+		"\n## https://example.com/b\n\n<https://example.com/b>\n\n_failed: timeout_\n" +
+		"\n## https://example.com/c\n\n<https://example.com/c>\n\n_queued_\n"
+	if diff := cmp.Diff(wantMarkdown, markdownBuffer.String()); diff != "" {
+		t.Errorf("WriteBatchMarkdown() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,89 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxPageRangesは指定できるページ範囲の最大数
+const MaxPageRanges = 20
+
+// ErrInvalidPageRangesはページ範囲の指定が不正な場合のエラー
+var ErrInvalidPageRanges = errors.New("invalid page ranges")
+
+// PageRangeはPDFのページ範囲。ページ番号は1から始まり、Lastを含む
+type PageRange struct {
+	First int
+	Last  int
+}
+
+// PageRangesはPDFの要約対象のページ範囲 (例: "1-5,8,10-12")
+type PageRanges []PageRange
+
+// ParsePageRangesは"1-5,8"の形式のページ範囲を読み込む
+// 空文字の場合はすべてのページを対象とするnilを返す
+func ParsePageRanges(s string) (PageRanges, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) > MaxPageRanges {
+		return nil, fmt.Errorf("%w: too many ranges", ErrInvalidPageRanges)
+	}
+	ranges := make(PageRanges, 0, len(parts))
+	for _, part := range parts {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		r := PageRange{}
+		var err error
+		if r.First, err = parsePageNumber(first); err != nil {
+			return nil, err
+		}
+		r.Last = r.First
+		if isRange {
+			if r.Last, err = parsePageNumber(last); err != nil {
+				return nil, err
+			}
+		}
+		if r.First > r.Last {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPageRanges, part)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parsePageNumber(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: invalid page number: %q", ErrInvalidPageRanges, s)
+	}
+	return n, nil
+}
+
+// Containsはページ番号pageが範囲に含まれるかを返す。範囲が空の場合はすべてのページを含む
+func (r PageRanges) Contains(page int) bool {
+	if len(r) == 0 {
+		return true
+	}
+	for _, pr := range r {
+		if pr.First <= page && page <= pr.Last {
+			return true
+		}
+	}
+	return false
+}
+
+func (r PageRanges) String() string {
+	parts := make([]string, 0, len(r))
+	for _, pr := range r {
+		if pr.First == pr.Last {
+			parts = append(parts, strconv.Itoa(pr.First))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d-%d", pr.First, pr.Last))
+	}
+	return strings.Join(parts, ",")
+}
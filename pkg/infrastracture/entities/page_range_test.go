@@ -0,0 +1,54 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ParsePageRanges(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PageRanges
+		wantErr bool
+	}{
+		{input: "", want: nil},
+		{input: "3", want: PageRanges{{First: 3, Last: 3}}},
+		{input: "1-5, 8,10-12", want: PageRanges{{First: 1, Last: 5}, {First: 8, Last: 8}, {First: 10, Last: 12}}},
+		{input: "0", wantErr: true},
+		{input: "5-1", wantErr: true},
+		{input: "1-", wantErr: true},
+		{input: "a", wantErr: true},
+		{input: "1,,2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePageRanges(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePageRanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPageRanges) {
+				t.Errorf("error should be ErrInvalidPageRanges: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParsePageRanges() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_PageRanges_Contains(t *testing.T) {
+	ranges := PageRanges{{First: 1, Last: 3}, {First: 7, Last: 7}}
+	for page, want := range map[int]bool{1: true, 3: true, 4: false, 7: true, 8: false} {
+		if got := ranges.Contains(page); got != want {
+			t.Errorf("Contains(%d) = %v, want %v", page, got, want)
+		}
+	}
+	if !PageRanges(nil).Contains(100) {
+		t.Errorf("empty ranges should contain all pages")
+	}
+	if got := ranges.String(); got != "1-3,7" {
+		t.Errorf("String() = %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+package entities
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// 組み込みの要約スタイル
+const (
+	SummaryStyleDefault        = "default"
+	SummaryStyleBulletPoints   = "bullet_points"
+	SummaryStyleTLDR           = "tldr"
+	SummaryStyleExecutiveBrief = "executive_brief"
+	SummaryStyleQA             = "qa"
+	SummaryStyleKeyQuotes      = "key_quotes"
+)
+
+var BuiltinSummaryStyles = []string{
+	SummaryStyleDefault,
+	SummaryStyleBulletPoints,
+	SummaryStyleTLDR,
+	SummaryStyleExecutiveBrief,
+	SummaryStyleQA,
+	SummaryStyleKeyQuotes,
+}
+
+func IsBuiltinSummaryStyle(style string) bool {
+	for _, s := range BuiltinSummaryStyles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+PromptTemplateはユーザーが定義した要約のプロンプトテンプレート
+BodyはGoのtext/templateの形式で、{{.Title}}、{{.Content}}、{{.Language}}を参照できる
+{{.Language}}を参照しない場合は出力言語の指示が末尾に追加される
+*/
+type PromptTemplate struct {
+	UserId    string `json:"userId" dynamodbav:"user_id"`
+	Name      string `json:"name" dynamodbav:"template_name"`
+	Body      string `json:"body" dynamodbav:"body"`
+	CreatedAt int64  `json:"createdAt" dynamodbav:"created_at"`
+	UpdatedAt int64  `json:"updatedAt" dynamodbav:"updated_at"`
+}
+
+// ValidateはテンプレートがTitleとContentで実行でき、本文(Content)を参照していることを検証する
+func (p *PromptTemplate) Validate() error {
+	if IsBuiltinSummaryStyle(p.Name) {
+		return fmt.Errorf("template name is reserved: %s", p.Name)
+	}
+	tmpl, err := ParsePromptTemplate(p.Name, p.Body)
+	if err != nil {
+		return err
+	}
+	tmpl = tmpl.Option("missingkey=error")
+	const contentMarker = "__content__"
+	var buffer bytes.Buffer
+	input := struct {
+		Title    string
+		Content  string
+		Language string
+	}{
+		Title:    "title",
+		Content:  contentMarker,
+		Language: "English",
+	}
+	if err := tmpl.Execute(&buffer, input); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	if !bytes.Contains(buffer.Bytes(), []byte(contentMarker)) {
+		return fmt.Errorf("template must contain {{.Content}}")
+	}
+	return nil
+}
+
+// PromptTemplateFuncsはプロンプトのテンプレートで利用できる関数
+var PromptTemplateFuncs = template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}
+
+// promptLanguageTemplateは出力言語が指定されている場合に言語を指示する共通のテンプレート
+const promptLanguageTemplate = `{{define "language"}}{{if .Language}}
+要約は必ず{{.Language}}で出力してください。{{end}}{{end}}`
+
+// ParsePromptTemplateはPromptTemplateFuncsと共通のテンプレート(language)を登録してtextを解析する
+// ワーカーでのプロンプトの生成とAPIでのテンプレートの検証で同じ設定で解析する
+func ParsePromptTemplate(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(PromptTemplateFuncs).Parse(promptLanguageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	tmpl, err = tmpl.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,26 @@
+package entities
+
+import "testing"
+
+func Test_PromptTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    PromptTemplate
+		wantErr bool
+	}{
+		{name: "content", tmpl: PromptTemplate{Name: "mine", Body: "{{.Title}}を要約してください。\n{{.Content}}"}},
+		{name: "language sub-template", tmpl: PromptTemplate{Name: "mine", Body: `{{.Content}}{{template "language" .}}`}},
+		{name: "inc func", tmpl: PromptTemplate{Name: "mine", Body: "{{inc 1}}つの段落で要約してください。\n{{.Content}}"}},
+		{name: "without content", tmpl: PromptTemplate{Name: "mine", Body: "{{.Title}}を要約してください。"}, wantErr: true},
+		{name: "unknown field", tmpl: PromptTemplate{Name: "mine", Body: "{{.Content}}{{.Author}}"}, wantErr: true},
+		{name: "unknown func", tmpl: PromptTemplate{Name: "mine", Body: "{{dec 1}}{{.Content}}"}, wantErr: true},
+		{name: "reserved name", tmpl: PromptTemplate{Name: SummaryStyleTLDR, Body: "{{.Content}}"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tmpl.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package entities
 
+import "errors"
+
+// ErrRateLimitExceededはリクエスト回数の上限を超える場合のエラー
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
 /*
 AuthRateLimitはID単位でアクセス回数を表現する構造体
 */
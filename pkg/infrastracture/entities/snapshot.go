@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// FetchOptionsはページの取得時に利用するタスクごとの設定
+type FetchOptions struct {
+	// PagesはPDFの場合に本文として抽出するページ範囲
+	Pages PageRanges
+	// Captureが指定された場合、クローラーは取得した時点のページの内容を設定する
+	Capture *PageCapture
+}
+
+// PageCaptureはクローラーがページを取得した時点の生の内容
+// FetchOptions.Captureが指定された場合にクローラーが設定する
+type PageCapture struct {
+	// Urlはリダイレクト後の最終的なURL
+	Url string
+	// ContentTypeはRawのメディアタイプ
+	ContentType string
+	// Rawは取得した生のHTMLやPDF。ブラウザで取得した場合は描画後のHTML
+	Raw []byte
+	// Screenshotはページ全体のPNGのスクリーンショット。ブラウザで取得した場合のみ設定する
+	Screenshot []byte
+	// MHTMLはサブリソースを含むページのMHTML。ブラウザで取得した場合のみ設定する
+	MHTML []byte
+}
+
+// Emptyは取得した内容がないかを返す
+func (c *PageCapture) Empty() bool {
+	return c == nil || len(c.Raw) == 0
+}
+
+// ContentHashはRawのSHA-256を16進数で返す
+func (c *PageCapture) ContentHash() string {
+	sum := sha256.Sum256(c.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// PageSnapshotはオブジェクトストレージに保存したページのスナップショット
+// 要約の結果が想定と異なる場合に、取得した時点のページを確認するために利用する
+type PageSnapshot struct {
+	Url         string `json:"url" dynamodbav:"url"`
+	ContentHash string `json:"contentHash" dynamodbav:"content_hash"`
+	ContentType string `json:"contentType,omitempty" dynamodbav:"content_type,omitempty"`
+	// オブジェクトのキーは公開せず、Linksのダウンロード用のURLを返す
+	RawKey        string         `json:"-" dynamodbav:"raw_key"`
+	ScreenshotKey string         `json:"-" dynamodbav:"screenshot_key,omitempty"`
+	MHTMLKey      string         `json:"-" dynamodbav:"mhtml_key,omitempty"`
+	CapturedAt    int64          `json:"capturedAt" dynamodbav:"captured_at"`
+	Links         *SnapshotLinks `json:"links,omitempty" dynamodbav:"-"`
+}
+
+// SnapshotLinksはスナップショットの期限付きのダウンロード用のURL
+type SnapshotLinks struct {
+	Raw        string `json:"raw,omitempty"`
+	Screenshot string `json:"screenshot,omitempty"`
+	MHTML      string `json:"mhtml,omitempty"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+// SnapshotKeyPrefixはタスクと内容のハッシュごとのスナップショットのキーの接頭辞を返す
+func SnapshotKeyPrefix(taskId string, contentHash string) string {
+	return fmt.Sprintf("snapshots/%s/%s/", taskId, contentHash)
+}
+
+// SnapshotRawFileNameはRawのメディアタイプに応じたファイル名を返す
+func SnapshotRawFileName(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/pdf":
+		return "page.pdf"
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || mediaType == "":
+		return "page.html"
+	case strings.HasPrefix(mediaType, "text/"):
+		return "page.txt"
+	}
+	return "page.bin"
+}
@@ -0,0 +1,180 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 構造化された要約の感情
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+	SentimentMixed    = "mixed"
+)
+
+var Sentiments = []string{SentimentPositive, SentimentNeutral, SentimentNegative, SentimentMixed}
+
+// 構造化された要約に含める固有表現の種類
+const (
+	EntityTypePerson       = "person"
+	EntityTypeOrganization = "organization"
+	EntityTypeLocation     = "location"
+	EntityTypeProduct      = "product"
+	EntityTypeEvent        = "event"
+	EntityTypeOther        = "other"
+)
+
+var EntityTypes = []string{
+	EntityTypePerson,
+	EntityTypeOrganization,
+	EntityTypeLocation,
+	EntityTypeProduct,
+	EntityTypeEvent,
+	EntityTypeOther,
+}
+
+// 構造化された要約の各項目の上限
+const (
+	MaxHeadlineLength = 200
+	MaxKeyPoints      = 10
+	MaxEntities       = 20
+	MaxTags           = 10
+	MaxTagLength      = 50
+)
+
+// StructuredSummaryは構造化出力で生成した要約
+// JSONのキーはLLMに渡すJSON Schemaと一致させる
+type StructuredSummary struct {
+	Headline  string          `json:"headline" dynamodbav:"headline"`
+	KeyPoints []string        `json:"keyPoints" dynamodbav:"key_points"`
+	Entities  []SummaryEntity `json:"entities" dynamodbav:"entities"`
+	Sentiment string          `json:"sentiment" dynamodbav:"sentiment"`
+	Tags      []string        `json:"tags" dynamodbav:"tags"`
+}
+
+// SummaryEntityは要約の対象に登場する人物や組織などの固有表現
+type SummaryEntity struct {
+	Name string `json:"name" dynamodbav:"name"`
+	Type string `json:"type" dynamodbav:"type"`
+}
+
+// ParseStructuredSummaryはLLMが出力したJSONを構造化された要約に変換し、正規化と検証を行う
+// ツールに対応していないモデルがコードブロックで囲んで出力した場合も受け付ける
+func ParseStructuredSummary(text string) (*StructuredSummary, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(text, "```")
+	}
+	var s StructuredSummary
+	if err := json.Unmarshal([]byte(text), &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal structured summary: %w", err)
+	}
+	s.Normalize()
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Normalizeは前後の空白や空の項目を取り除き、タグを小文字にして重複を除く
+func (s *StructuredSummary) Normalize() {
+	s.Headline = strings.TrimSpace(s.Headline)
+	s.Sentiment = strings.ToLower(strings.TrimSpace(s.Sentiment))
+
+	keyPoints := make([]string, 0, len(s.KeyPoints))
+	for _, p := range s.KeyPoints {
+		if p = strings.TrimSpace(p); p != "" {
+			keyPoints = append(keyPoints, p)
+		}
+	}
+	s.KeyPoints = keyPoints
+
+	entities := make([]SummaryEntity, 0, len(s.Entities))
+	for _, e := range s.Entities {
+		e.Name = strings.TrimSpace(e.Name)
+		e.Type = strings.ToLower(strings.TrimSpace(e.Type))
+		if e.Name != "" {
+			entities = append(entities, e)
+		}
+	}
+	s.Entities = entities
+
+	tags := make([]string, 0, len(s.Tags))
+	seen := make(map[string]bool, len(s.Tags))
+	for _, t := range s.Tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	s.Tags = tags
+}
+
+// Validateは構造化された要約が必須項目と上限を満たしているかを検証する
+// 違反している項目はすべてまとめて返す
+func (s *StructuredSummary) Validate() error {
+	var errs []error
+	if s.Headline == "" {
+		errs = append(errs, fmt.Errorf("headline is empty"))
+	} else if utf8.RuneCountInString(s.Headline) > MaxHeadlineLength {
+		errs = append(errs, fmt.Errorf("headline exceeds %d characters", MaxHeadlineLength))
+	}
+	if len(s.KeyPoints) == 0 {
+		errs = append(errs, fmt.Errorf("keyPoints is empty"))
+	} else if len(s.KeyPoints) > MaxKeyPoints {
+		errs = append(errs, fmt.Errorf("keyPoints exceeds %d items", MaxKeyPoints))
+	}
+	if len(s.Entities) > MaxEntities {
+		errs = append(errs, fmt.Errorf("entities exceeds %d items", MaxEntities))
+	}
+	for _, e := range s.Entities {
+		if !contains(EntityTypes, e.Type) {
+			errs = append(errs, fmt.Errorf("unknown entity type: %s", e.Type))
+		}
+	}
+	if !contains(Sentiments, s.Sentiment) {
+		errs = append(errs, fmt.Errorf("unknown sentiment: %s", s.Sentiment))
+	}
+	if len(s.Tags) > MaxTags {
+		errs = append(errs, fmt.Errorf("tags exceeds %d items", MaxTags))
+	}
+	for _, t := range s.Tags {
+		if utf8.RuneCountInString(t) > MaxTagLength {
+			errs = append(errs, fmt.Errorf("tag exceeds %d characters: %s", MaxTagLength, t))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid structured summary: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Textは構造化された要約を見出しと要点の箇条書きの文章に変換する
+// 構造化出力に対応していないクライアントのためにSummary.Summaryへ保存する
+func (s *StructuredSummary) Text() string {
+	b := strings.Builder{}
+	b.WriteString(s.Headline)
+	b.WriteString("\n")
+	for _, p := range s.KeyPoints {
+		b.WriteString("\n- ")
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
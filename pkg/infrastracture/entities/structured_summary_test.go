@@ -0,0 +1,55 @@
+package entities
+
+import "testing"
+
+func Test_ParseStructuredSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			text: `{"headline":" 見出し ","keyPoints":["要点1",""],"entities":[{"name":"OpenAI","type":"Organization"}],"sentiment":"Positive","tags":["Go","go"]}`,
+		},
+		{
+			name: "code block",
+			text: "```json\n{\"headline\":\"見出し\",\"keyPoints\":[\"要点\"],\"entities\":[],\"sentiment\":\"mixed\",\"tags\":[]}\n```",
+		},
+		{
+			name:    "empty key points",
+			text:    `{"headline":"見出し","keyPoints":[],"entities":[],"sentiment":"neutral","tags":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown sentiment",
+			text:    `{"headline":"見出し","keyPoints":["要点"],"entities":[],"sentiment":"happy","tags":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown entity type",
+			text:    `{"headline":"見出し","keyPoints":["要点"],"entities":[{"name":"x","type":"animal"}],"sentiment":"neutral","tags":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			text:    "見出し",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStructuredSummary(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStructuredSummary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.name == "valid" {
+				if got.Headline != "見出し" || len(got.KeyPoints) != 1 ||
+					got.Entities[0].Type != EntityTypeOrganization ||
+					got.Sentiment != SentimentPositive || len(got.Tags) != 1 {
+					t.Errorf("structured summary is not normalized: %+v", got)
+				}
+			}
+		})
+	}
+}
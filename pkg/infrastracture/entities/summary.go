@@ -7,20 +7,62 @@ import (
 )
 
 type Summary struct {
-	Id               string `json:"id" dynamodbav:"id"`
-	TaskStatus       string `json:"taskStatus" dynamodbav:"task_status,omitempty"`
-	PageUrl          string `json:"pageUrl" dynamodbav:"page_url,omitempty"`
-	Title            string `json:"title,omitempty" dynamodbav:"title,omitempty"`
-	Content          string `json:"content,omitempty" dynamodbav:"content,omitempty"`
-	UserId           string `json:"userId,omitempty" dynamodbav:"user_id,omitempty"`
-	Summary          string `json:"summary,omitempty" dynamodbav:"summary,omitempty"`
-	TaskFailedReason string `json:"taskFailedReason,omitempty" dynamodbav:"task_failed_reason,omitempty"`
-	CreatedAt        int64  `json:"createdAt" dynamodbav:"created_at,omitempty"`
+	Id                string             `json:"id" dynamodbav:"id"`
+	TaskStatus        TaskStatus         `json:"taskStatus" dynamodbav:"task_status,omitempty"`
+	PageUrl           string             `json:"pageUrl" dynamodbav:"page_url,omitempty"`
+	Title             string             `json:"title,omitempty" dynamodbav:"title,omitempty"`
+	Content           string             `json:"content,omitempty" dynamodbav:"content,omitempty"`
+	UserId            string             `json:"userId,omitempty" dynamodbav:"user_id,omitempty"`
+	Summary           string             `json:"summary,omitempty" dynamodbav:"summary,omitempty"`
+	TaskFailedReason  string             `json:"taskFailedReason,omitempty" dynamodbav:"task_failed_reason,omitempty"`
+	CreatedAt         int64              `json:"createdAt" dynamodbav:"created_at,omitempty"`
+	Options           *SummaryOptions    `json:"options,omitempty" dynamodbav:"summary_options,omitempty"`
+	Usage             *SummaryUsage      `json:"usage,omitempty" dynamodbav:"token_usage,omitempty"`
+	Style             string             `json:"style,omitempty" dynamodbav:"summary_style,omitempty"`
+	Language          string             `json:"language,omitempty" dynamodbav:"output_language,omitempty"`
+	SourceLanguage    string             `json:"sourceLanguage,omitempty" dynamodbav:"source_language,omitempty"`
+	Structured        bool               `json:"structured,omitempty" dynamodbav:"structured,omitempty"`
+	StructuredSummary *StructuredSummary `json:"structuredSummary,omitempty" dynamodbav:"structured_summary,omitempty"`
+	// PdfPagesはPDFの場合に要約するページ範囲 (例: "1-5,8")。空の場合はすべてのページ
+	PdfPages string `json:"pdfPages,omitempty" dynamodbav:"pdf_pages,omitempty"`
+	// Uploadはアップロードされた文書。設定されている場合はページを取得せずにこの文書を要約する
+	Upload *UploadedDocument `json:"upload,omitempty" dynamodbav:"uploaded_document,omitempty"`
+	// Snapshotは取得した時点のページのスナップショット
+	Snapshot *PageSnapshot `json:"snapshot,omitempty" dynamodbav:"page_snapshot,omitempty"`
+	// StatusUpdatedAtは最後に状態が遷移した時刻(UNIXミリ秒)
+	StatusUpdatedAt int64 `json:"statusUpdatedAt,omitempty" dynamodbav:"status_updated_at,omitempty"`
+	// StatusHistoryは状態の遷移の履歴。追記のみ行う
+	StatusHistory []TaskStatusChange `json:"statusHistory,omitempty" dynamodbav:"status_history,omitempty"`
+	// Attemptsはワーカーがタスクを実行した回数。再実行を依頼した場合は0に戻す
+	Attempts int `json:"attempts,omitempty" dynamodbav:"attempts,omitempty"`
+	// CancelRequestedはユーザーがキャンセルを依頼したか。実行中のワーカーはこれを確認して処理を中断する
+	CancelRequested bool `json:"cancelRequested,omitempty" dynamodbav:"cancel_requested,omitempty"`
+	// BatchIdはまとめて依頼された場合のバッチのID
+	BatchId string `json:"batchId,omitempty" dynamodbav:"batch_id,omitempty"`
+}
+
+// SummaryOptionsはタスク単位で要約に利用するLLMのパラメータを上書きするための設定
+type SummaryOptions struct {
+	Model       string   `json:"model,omitempty" dynamodbav:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty" dynamodbav:"temperature,omitempty"`
+	TopP        *float64 `json:"topP,omitempty" dynamodbav:"top_p,omitempty"`
+	MaxTokens   *int     `json:"maxTokens,omitempty" dynamodbav:"max_tokens,omitempty"`
+	Seed        *int     `json:"seed,omitempty" dynamodbav:"seed,omitempty"`
+}
+
+// SummaryUsageは要約に消費したトークン数と料金(USD)
+// 長い本文を分割して要約した場合はすべてのリクエストの合計になる
+type SummaryUsage struct {
+	Model            string  `json:"model" dynamodbav:"model"`
+	PromptTokens     int     `json:"promptTokens" dynamodbav:"prompt_tokens"`
+	CompletionTokens int     `json:"completionTokens" dynamodbav:"completion_tokens"`
+	TotalTokens      int     `json:"totalTokens" dynamodbav:"total_tokens"`
+	Cost             float64 `json:"cost" dynamodbav:"cost"`
 }
 
 func (s Summary) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("id", s.Id).
-		Str("taskStatus", s.TaskStatus).
+		Str("taskStatus", string(s.TaskStatus)).
 		Str("taskFailedReason", s.TaskFailedReason).
 		Int64("createdAt", s.CreatedAt)
 }
@@ -46,6 +88,16 @@ func (t *Task) JSON() string {
 
 type Tasks []*Task
 
+// UserUsageはユーザーごとのトークン使用量と料金(USD)の集計
+type UserUsage struct {
+	UserId           string  `json:"userId" db:"user_id"`
+	TaskCount        int     `json:"taskCount" db:"task_count"`
+	PromptTokens     int     `json:"promptTokens" db:"prompt_tokens"`
+	CompletionTokens int     `json:"completionTokens" db:"completion_tokens"`
+	TotalTokens      int     `json:"totalTokens" db:"total_tokens"`
+	Cost             float64 `json:"cost" db:"cost"`
+}
+
 func (t Tasks) JSON() string {
 	b, err := json.MarshalIndent(t, "", "  ")
 	if err != nil {
@@ -0,0 +1,35 @@
+package entities
+
+// TaskFailureCodeはタスクが失敗した原因の分類
+// エラーの内容には内部の情報が含まれるため、失敗の理由としては分類のみを記録する
+type TaskFailureCode string
+
+const (
+	// 時間をおいて再実行すれば成功する可能性のある失敗
+	TaskFailureTimeout             TaskFailureCode = "timeout"
+	TaskFailureRateLimited         TaskFailureCode = "rate_limited"
+	TaskFailureUpstreamUnavailable TaskFailureCode = "upstream_unavailable"
+	// TaskFailureInterruptedはワーカーのタイムアウトなどで前回の実行が中断された場合
+	TaskFailureInterrupted TaskFailureCode = "interrupted"
+	// TaskFailureUnknownは分類できない失敗。一時的な失敗の可能性があるため再実行する
+	TaskFailureUnknown TaskFailureCode = "unknown"
+
+	// 再実行しても成功しない失敗
+	TaskFailureInvalidRequest     TaskFailureCode = "invalid_request"
+	TaskFailureBlockedURL         TaskFailureCode = "blocked_url"
+	TaskFailureDisallowedByRobots TaskFailureCode = "disallowed_by_robots"
+	TaskFailureNoContent          TaskFailureCode = "no_content"
+	TaskFailureContentTooLong     TaskFailureCode = "content_too_long"
+	TaskFailureInvalidOutput      TaskFailureCode = "invalid_output"
+	TaskFailureConfiguration      TaskFailureCode = "configuration"
+)
+
+// Retryableは時間をおいて再実行すれば成功する可能性のある失敗かを返す
+func (c TaskFailureCode) Retryable() bool {
+	switch c {
+	case TaskFailureTimeout, TaskFailureRateLimited, TaskFailureUpstreamUnavailable,
+		TaskFailureInterrupted, TaskFailureUnknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+TaskMessageはAPIから要約タスクにSQSで渡すメッセージ
+以前はタスクIDのみをメッセージ本文にしていたため、JSONでない場合はタスクIDとして扱う
+*/
+type TaskMessage struct {
+	TaskId string `json:"taskId"`
+	// Languageは要約を出力する言語 (BCP 47の言語タグ 例: ja, en)
+	Language string `json:"language,omitempty"`
+}
+
+func (m *TaskMessage) Marshal() (string, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal task message: %w", err)
+	}
+	return string(b), nil
+}
+
+func ParseTaskMessage(body string) (*TaskMessage, error) {
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "{") {
+		return &TaskMessage{TaskId: body}, nil
+	}
+	var m TaskMessage
+	if err := json.Unmarshal([]byte(body), &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal task message: %w", err)
+	}
+	if m.TaskId == "" {
+		return nil, fmt.Errorf("task id is empty")
+	}
+	return &m, nil
+}
@@ -0,0 +1,164 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TaskStatusは要約タスクの状態
+type TaskStatus string
+
+const (
+	// TaskStatusQueuedはキューに登録され、ワーカーの処理を待っている状態
+	TaskStatusQueued TaskStatus = "queued"
+	// TaskStatusCrawlingはページや文書から本文を取得している状態
+	TaskStatusCrawling TaskStatus = "crawling"
+	// TaskStatusCrawledは本文を取得し、要約を待っている状態
+	TaskStatusCrawled TaskStatus = "crawled"
+	// TaskStatusSummarizingはLLMで要約している状態
+	TaskStatusSummarizing TaskStatus = "summarizing"
+	TaskStatusComplete    TaskStatus = "complete"
+	TaskStatusFailed      TaskStatus = "failed"
+	TaskStatusCancelled   TaskStatus = "cancelled"
+	// TaskStatusRetryingは一時的なエラーで失敗し、再実行を待っている状態
+	TaskStatusRetrying TaskStatus = "retrying"
+)
+
+// ErrInvalidTransitionは許可されていない状態の遷移の場合や、遷移の前に他の処理で状態が変わった場合のエラー
+var ErrInvalidTransition = errors.New("invalid task status transition")
+
+// taskStatusTransitionsは状態ごとに遷移できる状態
+// complete、cancelledは終了した状態のため遷移できない
+var taskStatusTransitions = map[TaskStatus][]TaskStatus{
+	TaskStatusQueued:      {TaskStatusCrawling, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusCrawling:    {TaskStatusCrawled, TaskStatusRetrying, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusCrawled:     {TaskStatusSummarizing, TaskStatusRetrying, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusSummarizing: {TaskStatusComplete, TaskStatusRetrying, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusRetrying:    {TaskStatusCrawling, TaskStatusFailed, TaskStatusCancelled},
+	// 失敗したタスクは再実行を依頼された場合のみ再開する
+	TaskStatusFailed:    {TaskStatusRetrying},
+	TaskStatusComplete:  {},
+	TaskStatusCancelled: {},
+}
+
+// legacyTaskStatusesは状態を定義する前に保存されたタスクの状態と、対応する状態
+var legacyTaskStatuses = map[TaskStatus]TaskStatus{
+	"request":    TaskStatusQueued,
+	"processing": TaskStatusCrawling,
+}
+
+// Normalizeは以前の形式の状態を対応する状態に変換する
+func (s TaskStatus) Normalize() TaskStatus {
+	if status, ok := legacyTaskStatuses[s]; ok {
+		return status
+	}
+	return s
+}
+
+// Validは定義された状態かを返す
+func (s TaskStatus) Valid() bool {
+	_, ok := taskStatusTransitions[s.Normalize()]
+	return ok
+}
+
+// Terminalはこれ以上処理しない終了した状態かを返す
+func (s TaskStatus) Terminal() bool {
+	s = s.Normalize()
+	return s == TaskStatusComplete || s == TaskStatusCancelled
+}
+
+// InProgressはワーカーが実行している状態かを返す
+// ワーカーが終了した後もこの状態の場合は、タイムアウトなどで実行が中断されている
+func (s TaskStatus) InProgress() bool {
+	switch s.Normalize() {
+	case TaskStatusCrawling, TaskStatusCrawled, TaskStatusSummarizing:
+		return true
+	}
+	return false
+}
+
+// CanTransitionToはnextに遷移できるかを返す
+func (s TaskStatus) CanTransitionTo(next TaskStatus) bool {
+	for _, status := range taskStatusTransitions[s.Normalize()] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransitionはnextに遷移できない場合にErrInvalidTransitionを返す
+func (s TaskStatus) ValidateTransition(next TaskStatus) error {
+	if !s.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, s, next)
+	}
+	return nil
+}
+
+// TaskStatusChangeは状態の履歴の1件
+type TaskStatusChange struct {
+	Status TaskStatus `json:"status" dynamodbav:"status"`
+	From   TaskStatus `json:"from,omitempty" dynamodbav:"from,omitempty"`
+	// Atは遷移した時刻(UNIXミリ秒)
+	At int64 `json:"at" dynamodbav:"at"`
+	// DurationMsは遷移する前の状態で過ごした時間(ミリ秒)
+	DurationMs int64 `json:"durationMs,omitempty" dynamodbav:"duration_ms,omitempty"`
+	// Reasonは失敗やキャンセルなどの理由。失敗と再実行の場合はTaskFailureCode
+	Reason string `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	// Attemptは遷移した後の実行回数
+	Attempt int `json:"attempt,omitempty" dynamodbav:"attempt,omitempty"`
+}
+
+// NewTaskStatusChangeはsummaryの現在の状態からnextへの遷移を返す
+// 遷移できない場合はErrInvalidTransitionを返す
+func NewTaskStatusChange(summary *Summary, next TaskStatus, reason string, now time.Time) (*TaskStatusChange, error) {
+	if err := summary.TaskStatus.ValidateTransition(next); err != nil {
+		return nil, err
+	}
+	change := &TaskStatusChange{
+		Status:  next,
+		From:    summary.TaskStatus.Normalize(),
+		At:      now.UnixMilli(),
+		Reason:  reason,
+		Attempt: summary.Attempts,
+	}
+	switch {
+	case next == TaskStatusCrawling:
+		change.Attempt++
+	case change.From == TaskStatusFailed && next == TaskStatusRetrying:
+		// 失敗したタスクの再実行を依頼された場合は、実行回数を数え直す
+		change.Attempt = 0
+	}
+	if summary.StatusUpdatedAt > 0 && change.At > summary.StatusUpdatedAt {
+		change.DurationMs = change.At - summary.StatusUpdatedAt
+	}
+	return change, nil
+}
+
+// InitTaskStatusは登録したタスクをqueuedの状態にし、履歴の最初の1件を追加する
+func (s *Summary) InitTaskStatus(now time.Time) {
+	s.TaskStatus = TaskStatusQueued
+	s.StatusUpdatedAt = now.UnixMilli()
+	s.StatusHistory = []TaskStatusChange{{Status: TaskStatusQueued, At: s.StatusUpdatedAt}}
+}
+
+// Applyはsummaryの状態を遷移させ、履歴に追加する
+func (c *TaskStatusChange) Apply(summary *Summary) {
+	summary.TaskStatus = c.Status
+	summary.StatusUpdatedAt = c.At
+	summary.Attempts = c.Attempt
+	summary.StatusHistory = append(summary.StatusHistory, *c)
+}
+
+// StageDurationsは状態の履歴から状態ごとに過ごした時間の合計(ミリ秒)を返す
+// 再実行などで同じ状態を複数回経由した場合は合計する
+func StageDurations(history []TaskStatusChange) map[TaskStatus]int64 {
+	durations := map[TaskStatus]int64{}
+	for _, change := range history {
+		if change.From != "" && change.DurationMs > 0 {
+			durations[change.From] += change.DurationMs
+		}
+	}
+	return durations
+}
@@ -0,0 +1,111 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_TaskStatus_ValidateTransition(t *testing.T) {
+	tests := []struct {
+		from    TaskStatus
+		to      TaskStatus
+		wantErr bool
+	}{
+		{from: TaskStatusQueued, to: TaskStatusCrawling},
+		{from: TaskStatusCrawling, to: TaskStatusCrawled},
+		{from: TaskStatusCrawled, to: TaskStatusSummarizing},
+		{from: TaskStatusSummarizing, to: TaskStatusComplete},
+		{from: TaskStatusSummarizing, to: TaskStatusRetrying},
+		{from: TaskStatusRetrying, to: TaskStatusCrawling},
+		{from: TaskStatusFailed, to: TaskStatusRetrying},
+		{from: TaskStatusQueued, to: TaskStatusCancelled},
+		// 以前の形式の状態
+		{from: "request", to: TaskStatusCrawling},
+		{from: "processing", to: TaskStatusCrawled},
+		{from: TaskStatusQueued, to: TaskStatusSummarizing, wantErr: true},
+		{from: TaskStatusCrawling, to: TaskStatusComplete, wantErr: true},
+		{from: TaskStatusComplete, to: TaskStatusFailed, wantErr: true},
+		{from: TaskStatusCancelled, to: TaskStatusRetrying, wantErr: true},
+		{from: TaskStatusFailed, to: TaskStatusCrawling, wantErr: true},
+		{from: "unknown", to: TaskStatusCrawling, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			err := tt.from.ValidateTransition(tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTransition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTransition) {
+				t.Errorf("error should be ErrInvalidTransition: %v", err)
+			}
+		})
+	}
+}
+
+func Test_StageDurations(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	s := &Summary{}
+	s.InitTaskStatus(start)
+	steps := []struct {
+		status TaskStatus
+		after  time.Duration
+	}{
+		{status: TaskStatusCrawling, after: 1 * time.Second},
+		{status: TaskStatusRetrying, after: 3 * time.Second},
+		{status: TaskStatusCrawling, after: 10 * time.Second},
+		{status: TaskStatusCrawled, after: 12 * time.Second},
+		{status: TaskStatusSummarizing, after: 12 * time.Second},
+		{status: TaskStatusComplete, after: 20 * time.Second},
+	}
+	for _, step := range steps {
+		change, err := NewTaskStatusChange(s, step.status, "", start.Add(step.after))
+		if err != nil {
+			t.Fatalf("NewTaskStatusChange() error = %v", err)
+		}
+		change.Apply(s)
+	}
+	if s.TaskStatus != TaskStatusComplete || len(s.StatusHistory) != len(steps)+1 {
+		t.Fatalf("unexpected summary: %v, %v", s.TaskStatus, s.StatusHistory)
+	}
+	want := map[TaskStatus]int64{
+		TaskStatusQueued:   1000,
+		TaskStatusCrawling: 4000,
+		TaskStatusRetrying: 7000,
+		// crawledからsummarizingへは同時に遷移したため含まない
+		TaskStatusSummarizing: 8000,
+	}
+	if diff := cmp.Diff(want, StageDurations(s.StatusHistory)); diff != "" {
+		t.Errorf("StageDurations() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_NewTaskStatusChange_Attempt(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	s := &Summary{}
+	s.InitTaskStatus(now)
+	steps := []struct {
+		status      TaskStatus
+		wantAttempt int
+	}{
+		{status: TaskStatusCrawling, wantAttempt: 1},
+		{status: TaskStatusRetrying, wantAttempt: 1},
+		{status: TaskStatusCrawling, wantAttempt: 2},
+		{status: TaskStatusFailed, wantAttempt: 2},
+		// 再実行を依頼された場合は数え直す
+		{status: TaskStatusRetrying, wantAttempt: 0},
+		{status: TaskStatusCrawling, wantAttempt: 1},
+	}
+	for _, step := range steps {
+		change, err := NewTaskStatusChange(s, step.status, "", now)
+		if err != nil {
+			t.Fatalf("NewTaskStatusChange() error = %v", err)
+		}
+		change.Apply(s)
+		if s.Attempts != step.wantAttempt {
+			t.Errorf("Attempts after %s = %d, want %d", step.status, s.Attempts, step.wantAttempt)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package entities
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	UploadContentTypeText     = "text/plain"
+	UploadContentTypeMarkdown = "text/markdown"
+	UploadContentTypeHTML     = "text/html"
+	UploadContentTypePDF      = "application/pdf"
+
+	// MaxUploadBytesはアップロードできる文書の最大のサイズ
+	// 文書から抽出した本文は要約と同じDynamoDBの項目(最大400KB)に書き込むため余裕を持たせる
+	MaxUploadBytes = 150 << 10
+)
+
+var (
+	// ErrUploadTooLargeはアップロードされた文書がMaxUploadBytesを超える場合のエラー
+	ErrUploadTooLarge = errors.New("uploaded document is too large")
+	// ErrUnsupportedUploadはテキスト、Markdown、HTML、PDF以外の文書がアップロードされた場合のエラー
+	ErrUnsupportedUpload = errors.New("unsupported uploaded document")
+)
+
+// UploadedDocumentはURLから取得する代わりにアップロードされた要約の対象の文書
+type UploadedDocument struct {
+	FileName    string `json:"fileName,omitempty" dynamodbav:"file_name,omitempty"`
+	ContentType string `json:"contentType" dynamodbav:"content_type"`
+	Size        int    `json:"size" dynamodbav:"size"`
+	// Keyは文書の内容を保存したオブジェクトのキー
+	Key string `json:"-" dynamodbav:"key"`
+	// Dataは文書の内容。DynamoDBには保存せず、Keyのオブジェクトに保存する
+	Data []byte `json:"-" dynamodbav:"-"`
+}
+
+// UploadKeyはタスクごとのアップロードされた文書のオブジェクトのキーを返す
+func UploadKey(taskId string) string {
+	return fmt.Sprintf("uploads/%s/document", taskId)
+}
+
+// NewUploadedDocumentはアップロードされた文書の形式を判定して検証する
+// contentTypeが空やapplication/octet-streamの場合はファイル名の拡張子と内容から判定する
+func NewUploadedDocument(fileName string, contentType string, data []byte) (*UploadedDocument, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: document is empty", ErrUnsupportedUpload)
+	}
+	if len(data) > MaxUploadBytes {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d bytes", ErrUploadTooLarge, len(data), MaxUploadBytes)
+	}
+	mediaType := detectUploadContentType(fileName, contentType, data)
+	switch mediaType {
+	case UploadContentTypeText, UploadContentTypeMarkdown:
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("%w: text must be encoded in utf-8", ErrUnsupportedUpload)
+		}
+	case UploadContentTypeHTML:
+		// HTMLはmetaで指定された文字コードで読み込むため検証しない
+	case UploadContentTypePDF:
+		if !bytes.HasPrefix(data, []byte("%PDF-")) {
+			return nil, fmt.Errorf("%w: invalid pdf", ErrUnsupportedUpload)
+		}
+	default:
+		return nil, fmt.Errorf("%w: content type %s", ErrUnsupportedUpload, mediaType)
+	}
+	document := &UploadedDocument{ContentType: mediaType, Size: len(data), Data: data}
+	if fileName != "" {
+		// ブラウザによってはクライアントのパスを含むため、ファイル名のみを保存する
+		document.FileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	}
+	return document, nil
+}
+
+func detectUploadContentType(fileName string, contentType string, data []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/x-markdown":
+		return UploadContentTypeMarkdown
+	case "application/xhtml+xml":
+		return UploadContentTypeHTML
+	case "", "application/octet-stream":
+	default:
+		return mediaType
+	}
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".txt":
+		return UploadContentTypeText
+	case ".md", ".markdown":
+		return UploadContentTypeMarkdown
+	case ".html", ".htm":
+		return UploadContentTypeHTML
+	case ".pdf":
+		return UploadContentTypePDF
+	}
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		return UploadContentTypePDF
+	}
+	mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	return mediaType
+}
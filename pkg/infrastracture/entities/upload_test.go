@@ -0,0 +1,44 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func Test_NewUploadedDocument(t *testing.T) {
+	tests := []struct {
+		name            string
+		fileName        string
+		contentType     string
+		data            string
+		wantContentType string
+		wantFileName    string
+		wantErr         error
+	}{
+		{name: "text", contentType: "text/plain; charset=utf-8", data: "社内向けの資料です", wantContentType: UploadContentTypeText},
+		{name: "markdown by extension", fileName: "notes.md", contentType: "application/octet-stream", data: "# 議事録", wantContentType: UploadContentTypeMarkdown, wantFileName: "notes.md"},
+		{name: "windows path", fileName: `C:\docs\spec.html`, data: "<html><body>仕様</body></html>", wantContentType: UploadContentTypeHTML, wantFileName: "spec.html"},
+		{name: "sniff pdf", data: "%PDF-1.4\n", wantContentType: UploadContentTypePDF},
+		{name: "sniff html", data: "<!DOCTYPE html><html></html>", wantContentType: UploadContentTypeHTML},
+		{name: "broken pdf", fileName: "a.pdf", data: "not a pdf", wantErr: ErrUnsupportedUpload},
+		{name: "invalid utf-8", contentType: "text/plain", data: "\xff\xfe", wantErr: ErrUnsupportedUpload},
+		{name: "image", contentType: "image/png", data: "\x89PNG", wantErr: ErrUnsupportedUpload},
+		{name: "empty", contentType: "text/plain", data: "", wantErr: ErrUnsupportedUpload},
+		{name: "too large", contentType: "text/plain", data: strings.Repeat("a", MaxUploadBytes+1), wantErr: ErrUploadTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewUploadedDocument(tt.fileName, tt.contentType, []byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewUploadedDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.ContentType != tt.wantContentType || got.FileName != tt.wantFileName || got.Size != len(tt.data) {
+				t.Errorf("NewUploadedDocument() = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+/*
+batch.goはまとめて依頼された要約のタスクのグループを
+DynamoDBのsummary_batchテーブルに保存するリポジトリを提供するファイルです。
+タスク自体はweb_page_summaryテーブルにbatch_idを付けて保存します。
+*/
+
+type BatchRepository struct {
+	db  *dynamodb.Client
+	env *string
+}
+
+func NewBatchRepository(db *dynamodb.Client, env *string) *BatchRepository {
+	return &BatchRepository{db: db, env: env}
+}
+
+func (r *BatchRepository) TableName() string {
+	tableName := "summary_batch"
+	if r.env != nil {
+		return tableName + "_" + *r.env
+	}
+	return tableName
+}
+
+func (r *BatchRepository) CreateBatch(ctx context.Context, batch *entities.Batch) error {
+	av, err := attributevalue.MarshalMap(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %w", err)
+	}
+	if _, err := r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.TableName()),
+		Item:      av,
+	}); err != nil {
+		return fmt.Errorf("failed to put item: %w", err)
+	}
+	return nil
+}
+
+// GetBatchはバッチを取得する。userIdが指定された場合はそのユーザーのバッチのみを取得する
+func (r *BatchRepository) GetBatch(ctx context.Context, id string, userId *string) (*entities.Batch, error) {
+	keyConditionExpression := "id = :id"
+	expressionAttributeValues := map[string]types.AttributeValue{
+		":id": &types.AttributeValueMemberS{Value: id},
+	}
+	if userId != nil {
+		keyConditionExpression += " and user_id = :user_id"
+		expressionAttributeValues[":user_id"] = &types.AttributeValueMemberS{Value: *userId}
+	}
+	output, err := r.db.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(r.TableName()),
+		KeyConditionExpression:    aws.String(keyConditionExpression),
+		ExpressionAttributeValues: expressionAttributeValues,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", err)
+	}
+	if len(output.Items) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	var batch entities.Batch
+	if err := attributevalue.UnmarshalMap(output.Items[0], &batch); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal map: %w", err)
+	}
+	return &batch, nil
+}
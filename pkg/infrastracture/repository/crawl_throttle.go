@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// crawlThrottleRetentionは取得の予約や枠の項目をTTLで削除するまでの猶予
+const crawlThrottleRetention = time.Hour
+
+// CrawlThrottleRepositoryは複数のワーカーで共有するドメインごとの取得間隔と同時取得数を管理する
+// 取得間隔は"fetch#ドメイン"の項目の次の取得時刻、同時取得数は"slot#ドメイン#番号"の項目で管理する
+type CrawlThrottleRepository struct {
+	db  *dynamodb.Client
+	env *string
+}
+
+func NewCrawlThrottleRepository(db *dynamodb.Client, env *string) *CrawlThrottleRepository {
+	return &CrawlThrottleRepository{db: db, env: env}
+}
+
+func (r *CrawlThrottleRepository) TableName() string {
+	tableName := "crawl_domain_throttle"
+	if r.env != nil {
+		return tableName + "_" + *r.env
+	}
+	return tableName
+}
+
+// ReserveFetchはドメインの次の取得時刻がnow以前の場合に、次の取得時刻をnow+minDelayに更新して取得を予約する
+// 予約できない場合はfalseと現在の次の取得時刻を返す
+func (r *CrawlThrottleRepository) ReserveFetch(
+	ctx context.Context, domain string, now time.Time, minDelay time.Duration,
+) (bool, time.Time, error) {
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.TableName()),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: "fetch#" + domain},
+		},
+		UpdateExpression:    aws.String("SET next_fetch_at = :next, #ttl = :ttl"),
+		ConditionExpression: aws.String("attribute_not_exists(next_fetch_at) OR next_fetch_at <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#ttl": "ttl",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":next": unixMilli(now.Add(minDelay)),
+			":now":  unixMilli(now),
+			":ttl":  &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(crawlThrottleRetention).Unix(), 10)},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var conditionalErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionalErr) {
+			return false, time.Time{}, fmt.Errorf("failed to update item: %w", err)
+		}
+		next := now
+		if v, ok := conditionalErr.Item["next_fetch_at"].(*types.AttributeValueMemberN); ok {
+			if ms, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+				next = time.UnixMilli(ms)
+			}
+		}
+		return false, next, nil
+	}
+	return true, time.Time{}, nil
+}
+
+// AcquireSlotはドメインの同時取得数の枠を確保し、確保した枠のIDを返す
+// 枠はleaseの期限を過ぎると解放されていなくても再利用するため、ワーカーが異常終了しても枠が失われない
+func (r *CrawlThrottleRepository) AcquireSlot(
+	ctx context.Context, domain string, maxInFlight int, owner string, now time.Time, lease time.Duration,
+) (string, bool, error) {
+	for i := 0; i < maxInFlight; i++ {
+		slotId := fmt.Sprintf("slot#%s#%d", domain, i)
+		_, err := r.db.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(r.TableName()),
+			Item: map[string]types.AttributeValue{
+				"id":          &types.AttributeValueMemberS{Value: slotId},
+				"owner":       &types.AttributeValueMemberS{Value: owner},
+				"lease_until": unixMilli(now.Add(lease)),
+				"ttl":         &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(lease+crawlThrottleRetention).Unix(), 10)},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(id) OR lease_until < :now"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":now": unixMilli(now),
+			},
+		})
+		if err == nil {
+			return slotId, true, nil
+		}
+		var conditionalErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionalErr) {
+			return "", false, fmt.Errorf("failed to put item: %w", err)
+		}
+	}
+	return "", false, nil
+}
+
+// ReleaseSlotは確保した枠を解放する
+// leaseの期限を過ぎて他のワーカーが確保済みの場合は何もしない
+func (r *CrawlThrottleRepository) ReleaseSlot(ctx context.Context, slotId string, owner string) error {
+	_, err := r.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.TableName()),
+		Key: map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: slotId},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: owner},
+		},
+	})
+	if err != nil {
+		var conditionalErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalErr) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	return nil
+}
+
+func unixMilli(t time.Time) *types.AttributeValueMemberN {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.UnixMilli(), 10)}
+}
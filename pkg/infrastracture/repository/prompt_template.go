@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+/*
+prompt_template.goはユーザーが定義したプロンプトテンプレートを
+DynamoDBのprompt_templateテーブルに保存するリポジトリを提供するファイルです。
+*/
+
+type PromptTemplateRepository struct {
+	db  *dynamodb.Client
+	env *string
+}
+
+func NewPromptTemplateRepository(db *dynamodb.Client, env *string) *PromptTemplateRepository {
+	return &PromptTemplateRepository{db: db, env: env}
+}
+
+func (r *PromptTemplateRepository) TableName() string {
+	tableName := "prompt_template"
+	if r.env != nil {
+		return tableName + "_" + *r.env
+	}
+	return tableName
+}
+
+func (r *PromptTemplateRepository) key(userId string, name string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"user_id":       &types.AttributeValueMemberS{Value: userId},
+		"template_name": &types.AttributeValueMemberS{Value: name},
+	}
+}
+
+func (r *PromptTemplateRepository) GetPromptTemplate(
+	ctx context.Context, userId string, name string,
+) (*entities.PromptTemplate, error) {
+	output, err := r.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.TableName()),
+		Key:       r.key(userId, name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if len(output.Item) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	var t entities.PromptTemplate
+	if err := attributevalue.UnmarshalMap(output.Item, &t); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal map: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *PromptTemplateRepository) ListPromptTemplates(
+	ctx context.Context, userId string,
+) ([]*entities.PromptTemplate, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName()),
+		KeyConditionExpression: aws.String("user_id = :user_id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user_id": &types.AttributeValueMemberS{Value: userId},
+		},
+	}
+	templates := make([]*entities.PromptTemplate, 0)
+	paginator := dynamodb.NewQueryPaginator(r.db, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query: %w", err)
+		}
+		var page []*entities.PromptTemplate
+		if err := attributevalue.UnmarshalListOfMaps(output.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal list of maps: %w", err)
+		}
+		templates = append(templates, page...)
+	}
+	return templates, nil
+}
+
+func (r *PromptTemplateRepository) PutPromptTemplate(ctx context.Context, t *entities.PromptTemplate) error {
+	av, err := attributevalue.MarshalMap(t)
+	if err != nil {
+		return fmt.Errorf("failed to marshal map: %w", err)
+	}
+	if _, err := r.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.TableName()),
+		Item:      av,
+	}); err != nil {
+		return fmt.Errorf("failed to put item: %w", err)
+	}
+	return nil
+}
+
+func (r *PromptTemplateRepository) DeletePromptTemplate(ctx context.Context, userId string, name string) error {
+	_, err := r.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(r.TableName()),
+		Key:                 r.key(userId, name),
+		ConditionExpression: aws.String("attribute_exists(user_id)"),
+	})
+	if err != nil {
+		var conditionalErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalErr) {
+			return ErrRecordNotFound
+		}
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+	return nil
+}
@@ -76,6 +76,10 @@ func CreateTableInputWebPageSummary() *dynamodb.CreateTableInput {
 				AttributeName: aws.String("task_status"),
 				AttributeType: types.ScalarAttributeTypeS,
 			},
+			{
+				AttributeName: aws.String("user_id"),
+				AttributeType: types.ScalarAttributeTypeS,
+			},
 		},
 		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
 			{
@@ -96,6 +100,10 @@ func CreateTableInputWebPageSummary() *dynamodb.CreateTableInput {
 				AttributeName: aws.String("id"),
 				KeyType:       types.KeyTypeHash,
 			},
+			{
+				AttributeName: aws.String("user_id"),
+				KeyType:       types.KeyTypeRange,
+			},
 		},
 		BillingMode: types.BillingModePayPerRequest,
 	}
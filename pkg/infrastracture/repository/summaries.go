@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -49,14 +51,14 @@ func (r *SummaryRepository) GetSummary(
 		TableName:                 aws.String(r.TableName()),
 		KeyConditionExpression:    aws.String(keyConditionExpression),
 		ExpressionAttributeValues: expressionAttributeValues,
-		ProjectionExpression:      aws.String("id, task_status, page_url, summary, user_id, created_at"),
+		ProjectionExpression:      aws.String("id, task_status, page_url, title, summary, user_id, created_at, summary_options, summary_style, output_language, source_language, structured, structured_summary, token_usage, pdf_pages, page_snapshot, task_failed_reason, status_updated_at, status_history, attempts, cancel_requested, batch_id"),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed GetItem: %w", err)
 	}
 
 	if len(output.Items) == 0 {
-		return nil, fmt.Errorf("not found")
+		return nil, ErrRecordNotFound
 	}
 
 	var s []*entities.Summary
@@ -222,15 +224,21 @@ func (r *SummaryRepository) UpdateSummary(ctx context.Context, summary *entities
 	}
 
 	updateExpression := "SET"
+	expressionAttributeNames := map[string]string{}
 	expressionAttributeValues := map[string]types.AttributeValue{}
 	for k, v := range av {
-		// key項目は更新対象に含めない
-		if k != "id" && k != "user_id" {
-			updateExpression += fmt.Sprintf(" %s = :%s,", k, k)
+		// key項目と、TransitionTaskStatusでのみ更新する状態の項目は更新対象に含めない
+		if k != "id" && k != "user_id" && !statusAttributes[k] {
+			// 属性名がDynamoDBの予約語と衝突しないようにプレースホルダーを利用する
+			updateExpression += fmt.Sprintf(" #%s = :%s,", k, k)
+			expressionAttributeNames["#"+k] = k
 			expressionAttributeValues[":"+k] = v
 		}
 	}
 	updateExpression = strings.TrimRight(updateExpression, ",")
+	if len(expressionAttributeValues) == 0 {
+		return nil
+	}
 
 	updateInput := &dynamodb.UpdateItemInput{
 		TableName: aws.String(r.TableName()),
@@ -239,6 +247,7 @@ func (r *SummaryRepository) UpdateSummary(ctx context.Context, summary *entities
 			"user_id": &types.AttributeValueMemberS{Value: summary.UserId},
 		},
 		UpdateExpression:          aws.String(updateExpression),
+		ExpressionAttributeNames:  expressionAttributeNames,
 		ExpressionAttributeValues: expressionAttributeValues,
 		ConditionExpression:       aws.String("attribute_exists(id)"),
 	}
@@ -249,3 +258,265 @@ func (r *SummaryRepository) UpdateSummary(ctx context.Context, summary *entities
 	}
 	return nil
 }
+
+// statusAttributesはタスクの状態の項目。状態の遷移を検証するため、UpdateSummaryでは更新しない
+var statusAttributes = map[string]bool{
+	"task_status":       true,
+	"status_updated_at": true,
+	"status_history":    true,
+	"attempts":          true,
+	"cancel_requested":  true,
+}
+
+// TransitionTaskStatusはsummaryの状態をnextに遷移させ、状態の履歴に追記する
+// 読み込んだ後に他の処理で状態が変わっていないことを条件付き書き込みで確認し、
+// 遷移できない場合や状態が変わっていた場合はentities.ErrInvalidTransitionを返す
+// 成功した場合はsummaryの状態と履歴を更新する
+func (r *SummaryRepository) TransitionTaskStatus(
+	ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string,
+) error {
+	change, err := entities.NewTaskStatusChange(summary, next, reason, time.Now())
+	if err != nil {
+		return err
+	}
+	av, err := attributevalue.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed Marshal status change: %w", err)
+	}
+	updateExpression := "SET task_status = :next, status_updated_at = :at, attempts = :attempt, " +
+		"status_history = list_append(if_not_exists(status_history, :empty), :change)"
+	expressionAttributeValues := map[string]types.AttributeValue{
+		":next":    &types.AttributeValueMemberS{Value: string(next)},
+		":at":      &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", change.At)},
+		":attempt": &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", change.Attempt)},
+		":empty":   &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+		":change":  &types.AttributeValueMemberL{Value: []types.AttributeValue{av}},
+	}
+	if next == entities.TaskStatusFailed && reason != "" {
+		updateExpression += ", task_failed_reason = :reason"
+		expressionAttributeValues[":reason"] = &types.AttributeValueMemberS{Value: reason}
+	}
+	if change.From == entities.TaskStatusFailed {
+		// 再実行する場合は前回の失敗の理由を残さない。理由は履歴に残る
+		// 失敗する前に依頼されたキャンセルで再実行がすぐに中断されないよう、キャンセルの依頼も取り消す
+		updateExpression += " REMOVE task_failed_reason, cancel_requested"
+	}
+	conditionExpression := "attribute_exists(id) and attribute_not_exists(task_status)"
+	if summary.TaskStatus != "" {
+		conditionExpression = "task_status = :current"
+		expressionAttributeValues[":current"] = &types.AttributeValueMemberS{Value: string(summary.TaskStatus)}
+	}
+	_, err = r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.TableName()),
+		Key: map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: summary.Id},
+			"user_id": &types.AttributeValueMemberS{Value: summary.UserId},
+		},
+		UpdateExpression:                    aws.String(updateExpression),
+		ConditionExpression:                 aws.String(conditionExpression),
+		ExpressionAttributeValues:           expressionAttributeValues,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err != nil {
+		var conditionalErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionalErr) {
+			return fmt.Errorf("failed UpdateItem task status: %w", err)
+		}
+		current := "not found"
+		if v, ok := conditionalErr.Item["task_status"].(*types.AttributeValueMemberS); ok {
+			current = v.Value
+		}
+		return fmt.Errorf("%w: %s -> %s: current status is %s",
+			entities.ErrInvalidTransition, summary.TaskStatus, next, current)
+	}
+	if next == entities.TaskStatusFailed && reason != "" {
+		summary.TaskFailedReason = reason
+	}
+	if change.From == entities.TaskStatusFailed {
+		summary.TaskFailedReason = ""
+		summary.CancelRequested = false
+	}
+	change.Apply(summary)
+	return nil
+}
+
+// ErrNotCancellableは終了したタスクや失敗したタスクのキャンセルを依頼された場合のエラー
+var ErrNotCancellable = errors.New("task is not cancellable")
+
+// RequestCancelはタスクにキャンセルの依頼を記録する
+// 終了したタスクや失敗したタスクの場合はErrNotCancellableを返す
+func (r *SummaryRepository) RequestCancel(ctx context.Context, summary *entities.Summary) error {
+	_, err := r.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(r.TableName()),
+		Key: map[string]types.AttributeValue{
+			"id":      &types.AttributeValueMemberS{Value: summary.Id},
+			"user_id": &types.AttributeValueMemberS{Value: summary.UserId},
+		},
+		UpdateExpression:    aws.String("SET cancel_requested = :true"),
+		ConditionExpression: aws.String("attribute_exists(id) and not task_status in (:complete, :cancelled, :failed)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true":      &types.AttributeValueMemberBOOL{Value: true},
+			":complete":  &types.AttributeValueMemberS{Value: string(entities.TaskStatusComplete)},
+			":cancelled": &types.AttributeValueMemberS{Value: string(entities.TaskStatusCancelled)},
+			":failed":    &types.AttributeValueMemberS{Value: string(entities.TaskStatusFailed)},
+		},
+	})
+	if err != nil {
+		var conditionalErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalErr) {
+			return ErrNotCancellable
+		}
+		return fmt.Errorf("failed UpdateItem cancel requested: %w", err)
+	}
+	summary.CancelRequested = true
+	return nil
+}
+
+// IsCancelRequestedはタスクのキャンセルが依頼されているかを返す
+// 実行中のワーカーが定期的に確認するため、キャンセルの依頼の項目のみを取得する
+func (r *SummaryRepository) IsCancelRequested(ctx context.Context, id string) (bool, error) {
+	output, err := r.db.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName()),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: id},
+		},
+		ProjectionExpression: aws.String("cancel_requested"),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed Query: %w", err)
+	}
+	if len(output.Items) == 0 {
+		return false, ErrRecordNotFound
+	}
+	v, ok := output.Items[0]["cancel_requested"].(*types.AttributeValueMemberBOOL)
+	return ok && v.Value, nil
+}
+
+// GetUploadedDocumentはタスクにアップロードされた文書のキーと形式を返す
+// GetSummaryでは取得しないため、ワーカーが文書を読み込む場合に利用する。文書がない場合はnilを返す
+func (r *SummaryRepository) GetUploadedDocument(ctx context.Context, id string) (*entities.UploadedDocument, error) {
+	output, err := r.db.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName()),
+		KeyConditionExpression: aws.String("id = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: id},
+		},
+		ProjectionExpression: aws.String("uploaded_document"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed Query: %w", err)
+	}
+	if len(output.Items) == 0 {
+		return nil, ErrRecordNotFound
+	}
+	av, ok := output.Items[0]["uploaded_document"]
+	if !ok {
+		return nil, nil
+	}
+	var document entities.UploadedDocument
+	if err := attributevalue.Unmarshal(av, &document); err != nil {
+		return nil, fmt.Errorf("failed Unmarshal uploaded document: %w", err)
+	}
+	return &document, nil
+}
+
+const (
+	// batchWriteItemLimitはBatchWriteItemで1回に書き込める項目の最大の数
+	batchWriteItemLimit = 25
+	// batchGetItemLimitはBatchGetItemで1回に取得できる項目の最大の数
+	batchGetItemLimit = 100
+	// maxUnprocessedRetriesはスロットリングなどで処理されなかった項目を再試行する最大の回数
+	maxUnprocessedRetries = 5
+)
+
+// CreateSummariesは複数のタスクをBatchWriteItemでまとめて作成する
+func (r *SummaryRepository) CreateSummaries(ctx context.Context, summaries []*entities.Summary) error {
+	for start := 0; start < len(summaries); start += batchWriteItemLimit {
+		end := min(start+batchWriteItemLimit, len(summaries))
+		requests := make([]types.WriteRequest, 0, end-start)
+		for _, summary := range summaries[start:end] {
+			av, err := attributevalue.MarshalMap(summary)
+			if err != nil {
+				return fmt.Errorf("failed MarshalMap summary: %w", err)
+			}
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+		}
+		items := map[string][]types.WriteRequest{r.TableName(): requests}
+		for retry := 0; len(items) > 0; retry++ {
+			if retry > maxUnprocessedRetries {
+				return fmt.Errorf("failed BatchWriteItem summary: %d items are unprocessed", len(items[r.TableName()]))
+			}
+			if retry > 0 {
+				if err := waitUnprocessedRetry(ctx, retry); err != nil {
+					return fmt.Errorf("failed BatchWriteItem summary: %w", err)
+				}
+			}
+			output, err := r.db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: items})
+			if err != nil {
+				return fmt.Errorf("failed BatchWriteItem summary: %w", err)
+			}
+			items = output.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// GetSummariesはuserIdの複数のタスクをBatchGetItemでまとめて取得する
+// 本文やアップロードされた文書などの大きな項目は取得しない。存在しないタスクは結果に含めない
+func (r *SummaryRepository) GetSummaries(
+	ctx context.Context, ids []string, userId string,
+) ([]*entities.Summary, error) {
+	summaries := make([]*entities.Summary, 0, len(ids))
+	for start := 0; start < len(ids); start += batchGetItemLimit {
+		end := min(start+batchGetItemLimit, len(ids))
+		keys := make([]map[string]types.AttributeValue, 0, end-start)
+		for _, id := range ids[start:end] {
+			keys = append(keys, map[string]types.AttributeValue{
+				"id":      &types.AttributeValueMemberS{Value: id},
+				"user_id": &types.AttributeValueMemberS{Value: userId},
+			})
+		}
+		items := map[string]types.KeysAndAttributes{
+			r.TableName(): {
+				Keys:                     keys,
+				ProjectionExpression:     aws.String("id, task_status, page_url, title, #summary, user_id, created_at, summary_style, output_language, structured, structured_summary, task_failed_reason, token_usage, status_updated_at, attempts, cancel_requested, batch_id"),
+				ExpressionAttributeNames: map[string]string{"#summary": "summary"},
+			},
+		}
+		for retry := 0; len(items) > 0; retry++ {
+			if retry > maxUnprocessedRetries {
+				return nil, fmt.Errorf("failed BatchGetItem summary: %d keys are unprocessed", len(items[r.TableName()].Keys))
+			}
+			if retry > 0 {
+				if err := waitUnprocessedRetry(ctx, retry); err != nil {
+					return nil, fmt.Errorf("failed BatchGetItem summary: %w", err)
+				}
+			}
+			output, err := r.db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: items})
+			if err != nil {
+				return nil, fmt.Errorf("failed BatchGetItem summary: %w", err)
+			}
+			var page []*entities.Summary
+			if err := attributevalue.UnmarshalListOfMaps(output.Responses[r.TableName()], &page); err != nil {
+				return nil, fmt.Errorf("failed UnmarshalListOfMaps: %w", err)
+			}
+			summaries = append(summaries, page...)
+			items = output.UnprocessedKeys
+		}
+	}
+	return summaries, nil
+}
+
+// waitUnprocessedRetryは処理されなかった項目を再試行するまで待機する
+// ctxがキャンセルされた場合は待機をやめてctxのエラーを返す
+func waitUnprocessedRetry(ctx context.Context, retry int) error {
+	timer := time.NewTimer(time.Duration(retry*retry) * 50 * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
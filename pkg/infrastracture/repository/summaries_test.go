@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"testing"
@@ -29,7 +30,15 @@ func Test_SummaryRepository_GetSummary(t *testing.T) {
 	id := "test_Test_SummaryRepository_GetSummary"
 	wantSummary := &entities.Summary{
 		Id:      id,
+		UserId:  "test_user",
 		PageUrl: "test_url",
+		Usage: &entities.SummaryUsage{
+			Model:            "gpt-4o-mini",
+			PromptTokens:     1200,
+			CompletionTokens: 300,
+			TotalTokens:      1500,
+			Cost:             0.00036,
+		},
 	}
 
 	av, err := attributevalue.MarshalMap(wantSummary)
@@ -45,7 +54,7 @@ func Test_SummaryRepository_GetSummary(t *testing.T) {
 		t.Fatalf("failed PutItem summary: %s\n", err.Error())
 	}
 
-	summary, err := sut.GetSummary(ctx, id)
+	summary, err := sut.GetSummary(ctx, id, nil)
 	if err != nil {
 		t.Fatalf("failed get summary: %s\n", err.Error())
 	}
@@ -67,6 +76,7 @@ func Test_SummaryRepository_CreateSummary(t *testing.T) {
 
 	argsSummary := &entities.Summary{
 		Id:        "test_Test_SummaryRepository_CreateSummary",
+		UserId:    "test_user",
 		PageUrl:   "test_url",
 		CreatedAt: time.Now().Unix(),
 	}
@@ -82,7 +92,8 @@ func Test_SummaryRepository_CreateSummary(t *testing.T) {
 	output, err := db.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(sut.TableName()),
 		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: id},
+			"id":      &types.AttributeValueMemberS{Value: id},
+			"user_id": &types.AttributeValueMemberS{Value: argsSummary.UserId},
 		},
 	})
 	if err != nil {
@@ -110,11 +121,11 @@ func Test_SummaryRepository_UpdateSummary(t *testing.T) {
 	sut := NewSummaryRepository(db, nil)
 
 	id := "test_Test_SummaryRepository_UpdateSummary"
-	wantStatus := "complete"
 	argsSummary := &entities.Summary{
 		Id:         id,
+		UserId:     "test_user",
 		PageUrl:    "test_url",
-		TaskStatus: "request",
+		TaskStatus: entities.TaskStatusQueued,
 		CreatedAt:  time.Now().Unix(),
 	}
 
@@ -131,15 +142,20 @@ func Test_SummaryRepository_UpdateSummary(t *testing.T) {
 		t.Fatalf("failed PutItem summary: %s\n", err.Error())
 	}
 
-	argsSummary.TaskStatus = wantStatus
-	if err := sut.UpdateSummary(ctx, argsSummary); err != nil {
+	// 状態はTransitionTaskStatusでのみ遷移させるため、UpdateSummaryでは更新しない
+	update := *argsSummary
+	update.Title = "test_title"
+	update.TaskStatus = entities.TaskStatusComplete
+	if err := sut.UpdateSummary(ctx, &update); err != nil {
 		t.Fatalf("failed update summary: %s\n", err.Error())
 	}
+	argsSummary.Title = update.Title
 
 	output, err := db.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(sut.TableName()),
 		Key: map[string]types.AttributeValue{
-			"id": &types.AttributeValueMemberS{Value: argsSummary.Id},
+			"id":      &types.AttributeValueMemberS{Value: argsSummary.Id},
+			"user_id": &types.AttributeValueMemberS{Value: argsSummary.UserId},
 		},
 	})
 	if err != nil {
@@ -151,7 +167,7 @@ func Test_SummaryRepository_UpdateSummary(t *testing.T) {
 		t.Fatalf("failed UnmarshalMap: %s\n", err.Error())
 	}
 
-	if diff := cmp.Diff(&s, argsSummary); diff != "" {
+	if diff := cmp.Diff(argsSummary, &s); diff != "" {
 		t.Fatalf("failed update summary: %s\n", diff)
 	}
 }
@@ -165,7 +181,7 @@ func Test_SummaryRepository_ListTask(t *testing.T) {
 	sut := NewSummaryRepository(ddb, nil)
 
 	cleanupFunc := func() error {
-		tableKeys := []string{"id"}
+		tableKeys := []string{"id", "user_id"}
 		if err := testutil.CleanUpTable(t, ddb, sut.TableName(), tableKeys); err != nil {
 			return fmt.Errorf("failed to CleanUpTable: %v", err)
 		}
@@ -200,7 +216,8 @@ func Test_SummaryRepository_ListTask(t *testing.T) {
 				for i := 0; i < 5; i++ {
 					items = append(items, &entities.Summary{
 						Id:         fmt.Sprintf("%d", i+1),
-						TaskStatus: "complete",
+						UserId:     "test_user",
+						TaskStatus: entities.TaskStatusComplete,
 					})
 				}
 				if err := testutil.InsertItems(t, ddb, sut.TableName(), items); err != nil {
@@ -219,7 +236,7 @@ func Test_SummaryRepository_ListTask(t *testing.T) {
 					for i := 0; i < 5; i++ {
 						s = append(s, &entities.Summary{
 							Id:         fmt.Sprintf("%d", i+1),
-							TaskStatus: "complete",
+							TaskStatus: entities.TaskStatusComplete,
 						})
 					}
 					return s
@@ -253,3 +270,80 @@ func Test_SummaryRepository_ListTask(t *testing.T) {
 		})
 	}
 }
+
+func Test_SummaryRepository_TransitionTaskStatus(t *testing.T) {
+	ctx := context.Background()
+
+	testAwsCfg, err := testutil.NewAwsConfigForTest(t, ctx)
+	if err != nil {
+		t.Fatalf("failed load aws config: %s\n", err.Error())
+	}
+	db := dynamodb.NewFromConfig(*testAwsCfg)
+	sut := NewSummaryRepository(db, nil)
+
+	summary := &entities.Summary{
+		Id:        "test_Test_SummaryRepository_TransitionTaskStatus",
+		UserId:    "test_user",
+		PageUrl:   "test_url",
+		CreatedAt: time.Now().Unix(),
+	}
+	summary.InitTaskStatus(time.Now())
+	if _, err := sut.CreateSummary(ctx, summary); err != nil {
+		t.Fatalf("failed create summary: %s\n", err.Error())
+	}
+	// 他のワーカーが読み込んだ時点の状態
+	stale := *summary
+
+	for _, next := range []entities.TaskStatus{entities.TaskStatusCrawling, entities.TaskStatusCrawled} {
+		if err := sut.TransitionTaskStatus(ctx, summary, next, ""); err != nil {
+			t.Fatalf("failed transition task status to %s: %s\n", next, err.Error())
+		}
+	}
+
+	// 読み込んだ後に状態が変わっているため、条件付き書き込みが失敗する
+	err = sut.TransitionTaskStatus(ctx, &stale, entities.TaskStatusCrawling, "")
+	if !errors.Is(err, entities.ErrInvalidTransition) {
+		t.Fatalf("TransitionTaskStatus() error = %v, want ErrInvalidTransition", err)
+	}
+
+	got, err := sut.GetSummary(ctx, summary.Id, &summary.UserId)
+	if err != nil {
+		t.Fatalf("failed get summary: %s\n", err.Error())
+	}
+	if got.TaskStatus != entities.TaskStatusCrawled || got.Attempts != 1 {
+		t.Errorf("TaskStatus = %v, Attempts = %v, want crawled, 1", got.TaskStatus, got.Attempts)
+	}
+	var history []entities.TaskStatus
+	for _, change := range got.StatusHistory {
+		history = append(history, change.Status)
+	}
+	wantHistory := []entities.TaskStatus{
+		entities.TaskStatusQueued, entities.TaskStatusCrawling, entities.TaskStatusCrawled,
+	}
+	if diff := cmp.Diff(wantHistory, history); diff != "" {
+		t.Errorf("StatusHistory mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(summary.StatusHistory, got.StatusHistory); diff != "" {
+		t.Errorf("StatusHistory should be applied to summary (-want +got):\n%s", diff)
+	}
+
+	// 実行中にキャンセルが依頼された後に別のエラーで失敗したタスクを再実行する
+	if err := sut.RequestCancel(ctx, summary); err != nil {
+		t.Fatalf("failed request cancel: %s\n", err.Error())
+	}
+	for _, next := range []entities.TaskStatus{entities.TaskStatusFailed, entities.TaskStatusRetrying} {
+		if err := sut.TransitionTaskStatus(ctx, summary, next, string(entities.TaskFailureUpstreamUnavailable)); err != nil {
+			t.Fatalf("failed transition task status to %s: %s\n", next, err.Error())
+		}
+	}
+	if summary.CancelRequested || summary.TaskFailedReason != "" {
+		t.Errorf("CancelRequested = %v, TaskFailedReason = %q, want cleared", summary.CancelRequested, summary.TaskFailedReason)
+	}
+	got, err = sut.GetSummary(ctx, summary.Id, &summary.UserId)
+	if err != nil {
+		t.Fatalf("failed get summary: %s\n", err.Error())
+	}
+	if got.CancelRequested || got.TaskFailedReason != "" {
+		t.Errorf("stored CancelRequested = %v, TaskFailedReason = %q, want cleared", got.CancelRequested, got.TaskFailedReason)
+	}
+}
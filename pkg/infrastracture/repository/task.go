@@ -26,13 +26,16 @@ func (r *TaskRepository) AddTask(ctx context.Context, tx infrastracture.Transact
 	now := time.Now()
 	query := `
 	INSERT INTO tasks
-		(task_id, task_status, title, page_url, user_id, created_at, updated_at)
+		(task_id, task_status, title, page_url, user_id, created_at, updated_at,
+		 model, prompt_tokens, completion_tokens, total_tokens, cost)
 	VALUES
-		($1, $2, $3, $4, $5, $6, $7)
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
+	u := newTaskUsage(t.Usage)
 	if _, err := tx.ExecContext(
 		ctx, query,
 		t.Id, t.TaskStatus, t.Title, t.PageUrl, t.UserId, now.Unix(), now.Unix(),
+		u.model, u.promptTokens, u.completionTokens, u.totalTokens, u.cost,
 	); err != nil {
 		return fmt.Errorf("failed ExecContext: %w", err)
 	}
@@ -48,18 +51,48 @@ func (r *TaskRepository) UpdateTask(ctx context.Context, tx infrastracture.Trans
 		task_status = $2,
 		title = $3,
 		page_url = $4,
-		updated_at = $5
+		updated_at = $5,
+		model = $6,
+		prompt_tokens = $7,
+		completion_tokens = $8,
+		total_tokens = $9,
+		cost = $10
 	WHERE task_id = $1
 	`
+	u := newTaskUsage(t.Usage)
 	if _, err := tx.ExecContext(
 		ctx, query,
 		t.Id, t.TaskStatus, t.Title, t.PageUrl, now.Unix(),
+		u.model, u.promptTokens, u.completionTokens, u.totalTokens, u.cost,
 	); err != nil {
 		return fmt.Errorf("failed ExecContext: %w", err)
 	}
 	return nil
 }
 
+// taskUsageはtasksテーブルの使用量カラムに書き込む値
+// 要約が完了していない場合はmodelをNULL、トークン数と料金を0とする
+type taskUsage struct {
+	model            *string
+	promptTokens     int
+	completionTokens int
+	totalTokens      int
+	cost             float64
+}
+
+func newTaskUsage(u *entities.SummaryUsage) taskUsage {
+	if u == nil {
+		return taskUsage{}
+	}
+	return taskUsage{
+		model:            &u.Model,
+		promptTokens:     u.PromptTokens,
+		completionTokens: u.CompletionTokens,
+		totalTokens:      u.TotalTokens,
+		cost:             u.Cost,
+	}
+}
+
 type ListTaskInput struct {
 	Status *string
 	Limit  *uint
@@ -110,3 +143,57 @@ func (r *TaskRepository) ListTask(
 
 	return tasks, nil
 }
+
+type GetUsageInput struct {
+	// From, Toはcreated_at(UNIX秒)の範囲 [From, To)
+	From int64
+	To   int64
+}
+
+// GetUsageは期間内に作成されたタスクのトークン使用量と料金をユーザーごとに集計する
+func (r *TaskRepository) GetUsage(
+	ctx context.Context, tx infrastracture.Transactor, input *GetUsageInput,
+) ([]*entities.UserUsage, error) {
+	userSub, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sub: %w", err)
+	}
+
+	builder := goqu.
+		From("tasks").
+		Select(
+			goqu.COALESCE(goqu.I("user_id"), "").As("user_id"),
+			goqu.COUNT("*").As("task_count"),
+			goqu.SUM("prompt_tokens").As("prompt_tokens"),
+			goqu.SUM("completion_tokens").As("completion_tokens"),
+			goqu.SUM("total_tokens").As("total_tokens"),
+			goqu.SUM("cost").As("cost"),
+		).
+		Where(
+			goqu.C("created_at").Gte(input.From),
+			goqu.C("created_at").Lt(input.To),
+		)
+
+	if userSub != util.APIKeyUserSub {
+		// APIキーでのリクエストでない場合は自分の使用量のみ取得できる
+		builder = builder.Where(goqu.Ex{"user_id": userSub})
+	}
+
+	builder = builder.
+		GroupBy(goqu.I("user_id")).
+		Order(goqu.L("cost").Desc())
+
+	query, _, err := builder.ToSQL()
+	if err != nil {
+		return nil, fmt.Errorf("failed to goqu.ToSQL: %v", err)
+	}
+
+	var usages []*entities.UserUsage
+	if err := tx.SelectContext(ctx, &usages, query); err != nil {
+		return nil, fmt.Errorf("failed to SelectContext: %v", err)
+	}
+	if usages == nil {
+		usages = make([]*entities.UserUsage, 0)
+	}
+	return usages, nil
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/usecase/request_batch"
+	"github.com/shoet/webpagesummary/pkg/usecase/request_task"
+)
+
+type BatchTaskHandler struct {
+	Validator *validator.Validate
+	Usecase   *request_batch.Usecase
+}
+
+func NewBatchTaskHandler(
+	validate *validator.Validate, usecase *request_batch.Usecase,
+) *BatchTaskHandler {
+	return &BatchTaskHandler{
+		Validator: validate,
+		Usecase:   usecase,
+	}
+}
+
+// HandlerはURLの一覧をまとめて要約するバッチを依頼する
+// URLはJSONのurls、multipart/form-dataのfile(CSVもしくは改行区切り)、text/csvやtext/plainの本文で受け付ける
+func (b *BatchTaskHandler) Handler(c echo.Context) error {
+	c.Logger().Info("batch task handler")
+
+	body := batchTaskRequestBody{}
+	requestCtx := c.Request().Context()
+	defer c.Request().Body.Close()
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, entities.MaxBatchListBytes+maxMultipartOverheadBytes)
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case echo.MIMEMultipartForm:
+		if err := bindBatchMultipartForm(c, &body); err != nil {
+			return batchBindError(err)
+		}
+	case "text/csv", echo.MIMETextPlain:
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return batchBindError(err)
+		}
+		// 本文がURLの一覧のため、要約の設定はクエリパラメータで受け付ける
+		if err := bindBatchValues(c.QueryParam, &body); err != nil {
+			return batchBindError(err)
+		}
+		urls, err := entities.ParseBatchUrls(data, mediaType == "text/csv")
+		if err != nil {
+			return batchBindError(err)
+		}
+		body.Urls = urls
+	default:
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return batchBindError(err)
+		}
+	}
+
+	if err := b.Validator.Struct(body); err != nil {
+		return echo.NewHTTPError(400, fmt.Errorf("failed validate body: %s", err.Error()))
+	}
+
+	batch, err := b.Usecase.Run(requestCtx, request_batch.UsecaseInput{
+		Name:       body.Name,
+		Urls:       body.Urls,
+		Options:    body.Options.SummaryOptions(),
+		Style:      body.Style,
+		Language:   body.Language,
+		Structured: body.Structured,
+	})
+	if errors.Is(err, entities.ErrEmptyBatch) || errors.Is(err, entities.ErrBatchTooLarge) ||
+		errors.Is(err, request_task.ErrUnknownStyle) || errors.Is(err, request_task.ErrStyleWithStructured) {
+		return echo.NewHTTPError(400, err.Error())
+	}
+	if errors.Is(err, entities.ErrRateLimitExceeded) {
+		return echo.NewHTTPError(429, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(500, fmt.Errorf("failed run usecase: %s", err.Error()))
+	}
+
+	resp := struct {
+		BatchID  string                      `json:"batch_id"`
+		TaskIDs  []string                    `json:"task_ids"`
+		Rejected []entities.BatchRejectedUrl `json:"rejected"`
+	}{
+		BatchID:  batch.Id,
+		TaskIDs:  batch.TaskIds,
+		Rejected: batch.Rejected,
+	}
+	if resp.Rejected == nil {
+		resp.Rejected = []entities.BatchRejectedUrl{}
+	}
+	return c.JSON(202, resp)
+}
+
+type batchTaskRequestBody struct {
+	Name       string              `json:"name" validate:"max=256"`
+	Urls       []string            `json:"urls" validate:"dive,max=2048"`
+	Style      string              `json:"style" validate:"max=64"`
+	Language   string              `json:"language" validate:"omitempty,bcp47_language_tag"`
+	Structured bool                `json:"structured"`
+	Options    *summaryOptionsBody `json:"options"`
+}
+
+func batchBindError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, entities.ErrBatchTooLarge) {
+		return echo.NewHTTPError(413, fmt.Sprintf("url list exceeds %d bytes", entities.MaxBatchListBytes))
+	}
+	return echo.NewHTTPError(400, fmt.Errorf("failed decode body: %s", err.Error()))
+}
+
+// bindBatchMultipartFormはfileのURLの一覧と、フォームの要約の設定をbodyに読み込む
+// fileの拡張子が.csvもしくはContent-Typeがtext/csvの場合はCSVとして読み込む
+func bindBatchMultipartForm(c echo.Context, body *batchTaskRequestBody) error {
+	req := c.Request()
+	if err := req.ParseMultipartForm(entities.MaxBatchListBytes + maxMultipartOverheadBytes); err != nil {
+		return err
+	}
+	if err := bindBatchValues(req.FormValue, body); err != nil {
+		return err
+	}
+	file, fileHeader, err := req.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		// ファイルの代わりに改行区切りのurlsの項目でも受け付ける
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, entities.MaxBatchListBytes+1))
+	if err != nil {
+		return err
+	}
+	contentType, _, _ := mime.ParseMediaType(fileHeader.Header.Get("Content-Type"))
+	isCSV := contentType == "text/csv" || strings.EqualFold(path.Ext(fileHeader.Filename), ".csv")
+	urls, err := entities.ParseBatchUrls(data, isCSV)
+	if err != nil {
+		return err
+	}
+	body.Urls = append(body.Urls, urls...)
+	return nil
+}
+
+// bindBatchValuesはフォームやクエリパラメータの要約の設定をbodyに読み込む
+// urlsは改行区切りで、optionsはJSONの文字列で受け付ける
+func bindBatchValues(value func(name string) string, body *batchTaskRequestBody) error {
+	body.Name = value("name")
+	body.Style = value("style")
+	body.Language = value("language")
+	if v := value("structured"); v != "" {
+		structured, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid structured: %w", err)
+		}
+		body.Structured = structured
+	}
+	if v := value("options"); v != "" {
+		if err := json.Unmarshal([]byte(v), &body.Options); err != nil {
+			return fmt.Errorf("invalid options: %w", err)
+		}
+	}
+	if v := value("urls"); v != "" {
+		urls, err := entities.ParseBatchUrls([]byte(v), false)
+		if err != nil {
+			return err
+		}
+		body.Urls = urls
+	}
+	return nil
+}
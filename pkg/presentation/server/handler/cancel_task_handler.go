@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/cancel_task"
+)
+
+type CancelTaskHandler struct {
+	Usecase *cancel_task.Usecase
+}
+
+func NewCancelTaskHandler(usecase *cancel_task.Usecase) *CancelTaskHandler {
+	return &CancelTaskHandler{
+		Usecase: usecase,
+	}
+}
+
+func (c *CancelTaskHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("cancel task handler")
+
+	id := ctx.Param("id")
+	if id == "" {
+		return response.RespondBadRequest(ctx, nil)
+	}
+
+	summary, err := c.Usecase.Run(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return response.RespondNotFound(ctx, nil)
+	}
+	if errors.Is(err, repository.ErrNotCancellable) {
+		return echo.NewHTTPError(409, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(500, fmt.Errorf("failed run usecase: %s", err.Error()))
+	}
+
+	// 実行中のタスクはワーカーが中断するまでcancelRequestedのみがtrueになる
+	resp := struct {
+		TaskID          string              `json:"task_id"`
+		TaskStatus      entities.TaskStatus `json:"taskStatus"`
+		CancelRequested bool                `json:"cancelRequested"`
+	}{
+		TaskID:          summary.Id,
+		TaskStatus:      summary.TaskStatus,
+		CancelRequested: summary.CancelRequested,
+	}
+	return ctx.JSON(202, resp)
+}
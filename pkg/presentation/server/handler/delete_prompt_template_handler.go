@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/delete_prompt_template"
+)
+
+type DeletePromptTemplateHandler struct {
+	Usecase *delete_prompt_template.Usecase
+}
+
+func NewDeletePromptTemplateHandler(usecase *delete_prompt_template.Usecase) *DeletePromptTemplateHandler {
+	return &DeletePromptTemplateHandler{
+		Usecase: usecase,
+	}
+}
+
+func (d *DeletePromptTemplateHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("delete prompt template handler")
+
+	name := ctx.Param("name")
+	if name == "" {
+		return response.RespondBadRequest(ctx, nil)
+	}
+
+	if err := d.Usecase.Run(ctx.Request().Context(), name); err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return response.RespondNotFound(ctx, nil)
+		}
+		ctx.Logger().Errorf("failed to Usecase.Run: %v", err)
+		return response.RespondInternalServerError(ctx, nil)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
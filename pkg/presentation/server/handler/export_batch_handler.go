@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/get_batch"
+)
+
+type ExportBatchHandler struct {
+	Usecase *get_batch.Usecase
+}
+
+func NewExportBatchHandler(usecase *get_batch.Usecase) *ExportBatchHandler {
+	return &ExportBatchHandler{
+		Usecase: usecase,
+	}
+}
+
+// Handlerはバッチのタスクの要約をformat(json、csv、markdown)でまとめて返す
+func (e *ExportBatchHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("export batch handler")
+
+	id := ctx.Param("id")
+	if id == "" {
+		return response.RespondBadRequest(ctx, nil)
+	}
+	format := ctx.QueryParam("format")
+	if format == "" {
+		format = entities.BatchExportFormatJSON
+	}
+	var contentType, extension string
+	switch format {
+	case entities.BatchExportFormatJSON:
+	case entities.BatchExportFormatCSV:
+		contentType, extension = "text/csv; charset=UTF-8", "csv"
+	case entities.BatchExportFormatMarkdown:
+		contentType, extension = "text/markdown; charset=UTF-8", "md"
+	default:
+		return echo.NewHTTPError(400, fmt.Sprintf("unsupported format: %s", format))
+	}
+
+	status, err := e.Usecase.Run(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return response.RespondNotFound(ctx, nil)
+	}
+	if err != nil {
+		return echo.NewHTTPError(500, fmt.Errorf("failed run usecase: %s", err.Error()))
+	}
+
+	if format == entities.BatchExportFormatJSON {
+		return ctx.JSON(200, status)
+	}
+	var buffer bytes.Buffer
+	if format == entities.BatchExportFormatCSV {
+		err = entities.WriteBatchCSV(&buffer, status)
+	} else {
+		err = entities.WriteBatchMarkdown(&buffer, status)
+	}
+	if err != nil {
+		return echo.NewHTTPError(500, fmt.Errorf("failed export batch: %s", err.Error()))
+	}
+	ctx.Response().Header().Set(
+		echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", "batch-"+status.Id+"."+extension),
+	)
+	return ctx.Blob(200, contentType, buffer.Bytes())
+}
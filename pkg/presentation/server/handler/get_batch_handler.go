@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/get_batch"
+)
+
+type GetBatchHandler struct {
+	Usecase *get_batch.Usecase
+}
+
+func NewGetBatchHandler(usecase *get_batch.Usecase) *GetBatchHandler {
+	return &GetBatchHandler{
+		Usecase: usecase,
+	}
+}
+
+// Handlerはバッチの進捗と、含まれるタスクの状態を返す
+func (g *GetBatchHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("get batch handler")
+
+	id := ctx.Param("id")
+	if id == "" {
+		return response.RespondBadRequest(ctx, nil)
+	}
+
+	status, err := g.Usecase.Run(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return response.RespondNotFound(ctx, nil)
+	}
+	if err != nil {
+		return echo.NewHTTPError(500, fmt.Errorf("failed run usecase: %s", err.Error()))
+	}
+	return ctx.JSON(200, status)
+}
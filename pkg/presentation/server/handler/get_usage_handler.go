@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/get_usage"
+)
+
+type GetUsageHandler struct {
+	Usecase *get_usage.Usecase
+}
+
+func NewGetUsageHandler(usecase *get_usage.Usecase) *GetUsageHandler {
+	return &GetUsageHandler{
+		Usecase: usecase,
+	}
+}
+
+const usageDateLayout = "2006-01-02"
+
+// Handlerはユーザーごとのトークン使用量と料金を返す
+// from, to(YYYY-MM-DD)が指定されない場合は当月を集計する
+func (g *GetUsageHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("get usage handler")
+
+	type Request struct {
+		From string `query:"from"`
+		To   string `query:"to"`
+	}
+
+	var request Request
+	if err := ctx.Bind(&request); err != nil {
+		ctx.Logger().Errorf("failed to Bind: %v", err)
+		return response.RespondBadRequest(ctx, nil)
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+	if request.From != "" {
+		t, err := time.ParseInLocation(usageDateLayout, request.From, time.Local)
+		if err != nil {
+			return response.RespondBadRequest(ctx, &response.Errors{"from must be YYYY-MM-DD"})
+		}
+		from = t
+	}
+	if request.To != "" {
+		t, err := time.ParseInLocation(usageDateLayout, request.To, time.Local)
+		if err != nil {
+			return response.RespondBadRequest(ctx, &response.Errors{"to must be YYYY-MM-DD"})
+		}
+		to = t
+	}
+	if !from.Before(to) {
+		return response.RespondBadRequest(ctx, &response.Errors{"from must be before to"})
+	}
+
+	usages, err := g.Usecase.Run(ctx.Request().Context(), get_usage.UsecaseInput{From: from, To: to})
+	if err != nil {
+		ctx.Logger().Errorf("failed to Usecase.Run: %v", err)
+		return response.RespondInternalServerError(ctx, nil)
+	}
+
+	resp := struct {
+		From  string                `json:"from"`
+		To    string                `json:"to"`
+		Users []*entities.UserUsage `json:"users"`
+	}{
+		From:  from.Format(usageDateLayout),
+		To:    to.Format(usageDateLayout),
+		Users: usages,
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/list_prompt_template"
+)
+
+type ListPromptTemplateHandler struct {
+	Usecase *list_prompt_template.Usecase
+}
+
+func NewListPromptTemplateHandler(usecase *list_prompt_template.Usecase) *ListPromptTemplateHandler {
+	return &ListPromptTemplateHandler{
+		Usecase: usecase,
+	}
+}
+
+// Handlerは組み込みのスタイルとユーザーが定義したテンプレートの一覧を返す
+func (l *ListPromptTemplateHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("list prompt template handler")
+
+	templates, err := l.Usecase.Run(ctx.Request().Context())
+	if err != nil {
+		ctx.Logger().Errorf("failed to Usecase.Run: %v", err)
+		return response.RespondInternalServerError(ctx, nil)
+	}
+
+	resp := struct {
+		BuiltinStyles []string                   `json:"builtinStyles"`
+		Templates     []*entities.PromptTemplate `json:"templates"`
+	}{
+		BuiltinStyles: entities.BuiltinSummaryStyles,
+		Templates:     templates,
+	}
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/put_prompt_template"
+)
+
+type PutPromptTemplateHandler struct {
+	Validator *validator.Validate
+	Usecase   *put_prompt_template.Usecase
+}
+
+func NewPutPromptTemplateHandler(
+	validate *validator.Validate, usecase *put_prompt_template.Usecase,
+) *PutPromptTemplateHandler {
+	return &PutPromptTemplateHandler{
+		Validator: validate,
+		Usecase:   usecase,
+	}
+}
+
+func (p *PutPromptTemplateHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("put prompt template handler")
+
+	body := struct {
+		Name string `json:"name" validate:"required,max=64,excludesall=/ "`
+		Body string `json:"body" validate:"required,max=10000"`
+	}{}
+
+	defer ctx.Request().Body.Close()
+	if err := json.NewDecoder(ctx.Request().Body).Decode(&body); err != nil {
+		ctx.Logger().Errorf("failed to decode body: %v", err)
+		return response.RespondBadRequest(ctx, nil)
+	}
+	if err := p.Validator.Struct(body); err != nil {
+		return response.RespondBadRequest(ctx, &response.Errors{err.Error()})
+	}
+
+	t, err := p.Usecase.Run(ctx.Request().Context(), put_prompt_template.UsecaseInput{
+		Name: body.Name,
+		Body: body.Body,
+	})
+	if err != nil {
+		if errors.Is(err, put_prompt_template.ErrInvalidTemplate) {
+			return response.RespondBadRequest(ctx, &response.Errors{err.Error()})
+		}
+		ctx.Logger().Errorf("failed to Usecase.Run: %v", err)
+		return response.RespondInternalServerError(ctx, nil)
+	}
+	return ctx.JSON(http.StatusOK, t)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/presentation/response"
+	"github.com/shoet/webpagesummary/pkg/usecase/retry_task"
+)
+
+type RetryTaskHandler struct {
+	Usecase *retry_task.Usecase
+}
+
+func NewRetryTaskHandler(usecase *retry_task.Usecase) *RetryTaskHandler {
+	return &RetryTaskHandler{
+		Usecase: usecase,
+	}
+}
+
+func (r *RetryTaskHandler) Handler(ctx echo.Context) error {
+	ctx.Logger().Info("retry task handler")
+
+	id := ctx.Param("id")
+	if id == "" {
+		return response.RespondBadRequest(ctx, nil)
+	}
+
+	summary, err := r.Usecase.Run(ctx.Request().Context(), id)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return response.RespondNotFound(ctx, nil)
+	}
+	if errors.Is(err, retry_task.ErrNotFailed) || errors.Is(err, entities.ErrInvalidTransition) {
+		// 失敗していないタスクや、同時に再実行を依頼されたタスクは再実行しない
+		return echo.NewHTTPError(409, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(500, fmt.Errorf("failed run usecase: %s", err.Error()))
+	}
+
+	resp := struct {
+		TaskID     string              `json:"task_id"`
+		TaskStatus entities.TaskStatus `json:"taskStatus"`
+	}{
+		TaskID:     summary.Id,
+		TaskStatus: summary.TaskStatus,
+	}
+	return ctx.JSON(202, resp)
+}
@@ -2,10 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
 	"github.com/shoet/webpagesummary/pkg/usecase/request_task"
 )
 
@@ -26,15 +33,25 @@ func NewSummaryTaskHandler(
 func (s *SummaryTaskHandler) Handler(c echo.Context) error {
 	c.Logger().Info("summary task handler")
 
-	body := struct {
-		Url string `json:"url" validate:"required"`
-	}{}
-
+	body := summaryTaskRequestBody{}
 	requestCtx := c.Request().Context()
 	defer c.Request().Body.Close()
-	if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
-		fmt.Printf("failed deserialize body: %s\n", err.Error())
-		return echo.NewHTTPError(400, fmt.Errorf("failed decode body: %s", err.Error()))
+	var upload *entities.UploadedDocument
+	if isMultipartForm(c.Request()) {
+		// 文書のファイルはmultipart/form-dataのfileで受け付ける
+		u, err := bindMultipartForm(c, &body)
+		if errors.Is(err, entities.ErrUploadTooLarge) {
+			return echo.NewHTTPError(413, err.Error())
+		}
+		if err != nil {
+			return echo.NewHTTPError(400, fmt.Errorf("failed decode form: %s", err.Error()))
+		}
+		upload = u
+	} else {
+		if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+			fmt.Printf("failed deserialize body: %s\n", err.Error())
+			return echo.NewHTTPError(400, fmt.Errorf("failed decode body: %s", err.Error()))
+		}
 	}
 
 	if err := s.Validator.Struct(body); err != nil {
@@ -42,7 +59,40 @@ func (s *SummaryTaskHandler) Handler(c echo.Context) error {
 		return echo.NewHTTPError(400, fmt.Errorf("failed validate body: %s", err.Error()))
 	}
 
-	taskId, err := s.Usecase.Run(requestCtx, body.Url)
+	if body.Text != "" {
+		if upload != nil {
+			return echo.NewHTTPError(400, "text cannot be specified with file")
+		}
+		contentType := body.ContentType
+		if contentType == "" {
+			contentType = entities.UploadContentTypeText
+		}
+		u, err := entities.NewUploadedDocument("", contentType, []byte(body.Text))
+		if errors.Is(err, entities.ErrUploadTooLarge) {
+			return echo.NewHTTPError(413, err.Error())
+		}
+		if err != nil {
+			return echo.NewHTTPError(400, err.Error())
+		}
+		upload = u
+	}
+
+	input := request_task.UsecaseInput{
+		Url:        body.Url,
+		Style:      body.Style,
+		Language:   body.Language,
+		Structured: body.Structured,
+		PdfPages:   body.PdfPages,
+		Upload:     upload,
+		Title:      body.Title,
+	}
+	input.Options = body.Options.SummaryOptions()
+	taskId, err := s.Usecase.Run(requestCtx, input)
+	if errors.Is(err, request_task.ErrUnknownStyle) || errors.Is(err, request_task.ErrStyleWithStructured) ||
+		errors.Is(err, entities.ErrInvalidPageRanges) || errors.Is(err, request_task.ErrMissingSource) ||
+		errors.Is(err, urlpolicy.ErrInvalidURL) || errors.Is(err, urlpolicy.ErrBlockedURL) {
+		return echo.NewHTTPError(400, err.Error())
+	}
 	if err != nil {
 		return echo.NewHTTPError(500, fmt.Errorf("failed run usecase: %s", err.Error()))
 	}
@@ -56,3 +106,94 @@ func (s *SummaryTaskHandler) Handler(c echo.Context) error {
 
 	return c.JSON(200, resp)
 }
+
+type summaryTaskRequestBody struct {
+	// Urlは要約するページ。文書をアップロードする場合は省略でき、出典として記録する
+	Url        string `json:"url" validate:"omitempty,max=2048"`
+	Style      string `json:"style" validate:"max=64"`
+	Language   string `json:"language" validate:"omitempty,bcp47_language_tag"`
+	Structured bool   `json:"structured"`
+	PdfPages   string `json:"pdfPages" validate:"max=100"`
+	// TextはURLの代わりに要約するテキスト。ContentTypeでMarkdownやHTMLを指定できる
+	Text        string `json:"text"`
+	ContentType string `json:"contentType" validate:"omitempty,oneof=text/plain text/markdown text/html"`
+	// Titleはアップロードした文書のタイトル
+	Title   string              `json:"title" validate:"max=256"`
+	Options *summaryOptionsBody `json:"options"`
+}
+
+type summaryOptionsBody struct {
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature" validate:"omitempty,min=0,max=2"`
+	TopP        *float64 `json:"topP" validate:"omitempty,min=0,max=1"`
+	MaxTokens   *int     `json:"maxTokens" validate:"omitempty,min=1"`
+	Seed        *int     `json:"seed"`
+}
+
+func (o *summaryOptionsBody) SummaryOptions() *entities.SummaryOptions {
+	if o == nil {
+		return nil
+	}
+	return &entities.SummaryOptions{
+		Model:       o.Model,
+		Temperature: o.Temperature,
+		TopP:        o.TopP,
+		MaxTokens:   o.MaxTokens,
+		Seed:        o.Seed,
+	}
+}
+
+// maxMultipartOverheadBytesはmultipart/form-dataのファイル以外の項目や区切りに許容するサイズ
+const maxMultipartOverheadBytes = 64 << 10
+
+func isMultipartForm(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == echo.MIMEMultipartForm
+}
+
+// bindMultipartFormはフォームの項目をbodyに読み込み、fileの項目をアップロードされた文書として返す
+// optionsはJSONの文字列で受け付ける
+func bindMultipartForm(c echo.Context, body *summaryTaskRequestBody) (*entities.UploadedDocument, error) {
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, entities.MaxUploadBytes+maxMultipartOverheadBytes)
+	if err := req.ParseMultipartForm(entities.MaxUploadBytes + maxMultipartOverheadBytes); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, fmt.Errorf("%w: request body exceeds %d bytes", entities.ErrUploadTooLarge, maxBytesErr.Limit)
+		}
+		return nil, err
+	}
+	body.Url = req.FormValue("url")
+	body.Style = req.FormValue("style")
+	body.Language = req.FormValue("language")
+	body.PdfPages = req.FormValue("pdfPages")
+	body.Text = req.FormValue("text")
+	body.ContentType = req.FormValue("contentType")
+	body.Title = req.FormValue("title")
+	if v := req.FormValue("structured"); v != "" {
+		structured, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid structured: %w", err)
+		}
+		body.Structured = structured
+	}
+	if v := req.FormValue("options"); v != "" {
+		if err := json.Unmarshal([]byte(v), &body.Options); err != nil {
+			return nil, fmt.Errorf("invalid options: %w", err)
+		}
+	}
+
+	file, fileHeader, err := req.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, entities.MaxUploadBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	return entities.NewUploadedDocument(fileHeader.Filename, fileHeader.Header.Get("Content-Type"), data)
+}
@@ -48,6 +48,7 @@ func NewAuthRateLimitMiddleware(
 // x-api-keyに有効なAPIキーが設定されている場合はリクエスト回数制限をかけない
 // 後続のためにContextにTokenSubをセットする (TokenSubContextKey)
 // 後続のためにAPIKeyによる認証を行ったかをContextにセットする (HasAPIKeyContextKey)
+// 後続のためにリクエスト回数制限の対象であることをContextにセットする (RateLimitedContextKey)
 func (a *AuthRateLimitMiddleware) Handle(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(ctx echo.Context) error {
 		if a.Env == "prod" {
@@ -92,6 +93,8 @@ func (a *AuthRateLimitMiddleware) Handle(next echo.HandlerFunc) echo.HandlerFunc
 					return echo.NewHTTPError(500, "Failed to create rate limit record")
 				}
 			}
+			// contextにリクエスト回数制限の対象であることをセット
+			ctx.SetRequest(ctx.Request().WithContext(context.WithValue(ctx.Request().Context(), util.RateLimitedContextKey{}, true)))
 		}
 		return next(ctx)
 	}
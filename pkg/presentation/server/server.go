@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/go-playground/validator/v10"
@@ -15,9 +16,18 @@ import (
 	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
 	"github.com/shoet/webpagesummary/pkg/presentation/server/handler"
 	"github.com/shoet/webpagesummary/pkg/presentation/server/middleware"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
+	"github.com/shoet/webpagesummary/pkg/usecase/cancel_task"
+	"github.com/shoet/webpagesummary/pkg/usecase/delete_prompt_template"
+	"github.com/shoet/webpagesummary/pkg/usecase/get_batch"
 	"github.com/shoet/webpagesummary/pkg/usecase/get_summary"
+	"github.com/shoet/webpagesummary/pkg/usecase/get_usage"
+	"github.com/shoet/webpagesummary/pkg/usecase/list_prompt_template"
 	"github.com/shoet/webpagesummary/pkg/usecase/list_task"
+	"github.com/shoet/webpagesummary/pkg/usecase/put_prompt_template"
+	"github.com/shoet/webpagesummary/pkg/usecase/request_batch"
 	"github.com/shoet/webpagesummary/pkg/usecase/request_task"
+	"github.com/shoet/webpagesummary/pkg/usecase/retry_task"
 )
 
 type ServerDependencies struct {
@@ -25,7 +35,15 @@ type ServerDependencies struct {
 	Validator                   *validator.Validate
 	GetSummaryUsecase           *get_summary.Usecase
 	RequestSummaryUsecase       *request_task.Usecase
+	RetryTaskUsecase            *retry_task.Usecase
+	CancelTaskUsecase           *cancel_task.Usecase
+	RequestBatchUsecase         *request_batch.Usecase
+	GetBatchUsecase             *get_batch.Usecase
 	ListTaskUsecase             *list_task.Usecase
+	GetUsageUsecase             *get_usage.Usecase
+	PutPromptTemplateUsecase    *put_prompt_template.Usecase
+	ListPromptTemplateUsecase   *list_prompt_template.Usecase
+	DeletePromptTemplateUsecase *delete_prompt_template.Usecase
 	CORSWhiteList               []string
 	RateLimitterMiddleware      *middleware.AuthRateLimitMiddleware
 	SetRequestContextMiddleware *middleware.SetRequestContextMiddleware
@@ -40,20 +58,47 @@ func NewServerDependencies(
 	corsWhiteList []string,
 	rateLimitterMiddleware *middleware.AuthRateLimitMiddleware,
 	setRequestContextMiddleware *middleware.SetRequestContextMiddleware,
+	urlPolicy *urlpolicy.Policy,
+	snapshotStorage get_summary.SnapshotStorage,
+	snapshotLinkTTL time.Duration,
+	uploadStorage request_task.UploadStorage,
 ) (*ServerDependencies, error) {
 
 	summaryRepository := repository.NewSummaryRepository(ddbClient, env)
 	taskRepository := repository.NewTaskRepository()
-
-	getSummaryUsecase := get_summary.NewUsecase(summaryRepository)
-	requestTaskUsecase := request_task.NewUsecase(summaryRepository, queueClient)
+	promptTemplateRepository := repository.NewPromptTemplateRepository(ddbClient, env)
+	batchRepository := repository.NewBatchRepository(ddbClient, env)
+
+	getSummaryUsecase := get_summary.NewUsecase(summaryRepository, snapshotStorage, snapshotLinkTTL)
+	requestTaskUsecase := request_task.NewUsecase(
+		summaryRepository, promptTemplateRepository, queueClient, urlPolicy, uploadStorage,
+	)
+	retryTaskUsecase := retry_task.NewUsecase(summaryRepository, queueClient)
+	cancelTaskUsecase := cancel_task.NewUsecase(summaryRepository)
+	requestBatchUsecase := request_batch.NewUsecase(
+		summaryRepository, batchRepository, promptTemplateRepository, queueClient, urlPolicy,
+		rateLimitterMiddleware.RequestRateLimitRepository, rateLimitterMiddleware.RequestRateLimitMax,
+	)
+	getBatchUsecase := get_batch.NewUsecase(batchRepository, summaryRepository)
 	listTaskUsecase := list_task.NewUsecase(rdbHandler, taskRepository)
+	getUsageUsecase := get_usage.NewUsecase(rdbHandler, taskRepository)
+	putPromptTemplateUsecase := put_prompt_template.NewUsecase(promptTemplateRepository)
+	listPromptTemplateUsecase := list_prompt_template.NewUsecase(promptTemplateRepository)
+	deletePromptTemplateUsecase := delete_prompt_template.NewUsecase(promptTemplateRepository)
 
 	return &ServerDependencies{
 		Validator:                   validator,
 		GetSummaryUsecase:           getSummaryUsecase,
 		RequestSummaryUsecase:       requestTaskUsecase,
+		RetryTaskUsecase:            retryTaskUsecase,
+		CancelTaskUsecase:           cancelTaskUsecase,
+		RequestBatchUsecase:         requestBatchUsecase,
+		GetBatchUsecase:             getBatchUsecase,
 		ListTaskUsecase:             listTaskUsecase,
+		GetUsageUsecase:             getUsageUsecase,
+		PutPromptTemplateUsecase:    putPromptTemplateUsecase,
+		ListPromptTemplateUsecase:   listPromptTemplateUsecase,
+		DeletePromptTemplateUsecase: deletePromptTemplateUsecase,
 		CORSWhiteList:               corsWhiteList,
 		RateLimitterMiddleware:      rateLimitterMiddleware,
 		SetRequestContextMiddleware: setRequestContextMiddleware,
@@ -82,11 +127,54 @@ func NewServer(dep *ServerDependencies) (*echo.Echo, error) {
 	sthmm := dep.SetRequestContextMiddleware.Handle(sthm)
 	server.POST("/task", sthmm)
 
+	// 失敗したタスクの再実行
+	rth := handler.NewRetryTaskHandler(dep.RetryTaskUsecase)
+	rthm := dep.RateLimitterMiddleware.Handle(rth.Handler) // RateLimit
+	rthmm := dep.SetRequestContextMiddleware.Handle(rthm)
+	server.POST("/task/:id/retry", rthmm)
+
+	// タスクのキャンセル
+	cth := handler.NewCancelTaskHandler(dep.CancelTaskUsecase)
+	cthm := dep.SetRequestContextMiddleware.Handle(cth.Handler)
+	server.POST("/task/:id/cancel", cthm)
+
+	// URLの一覧のまとめての依頼
+	bth := handler.NewBatchTaskHandler(dep.Validator, dep.RequestBatchUsecase)
+	bthm := dep.RateLimitterMiddleware.Handle(bth.Handler) // RateLimit
+	bthmm := dep.SetRequestContextMiddleware.Handle(bthm)
+	server.POST("/tasks/batch", bthmm)
+
+	gbh := handler.NewGetBatchHandler(dep.GetBatchUsecase)
+	gbhm := dep.SetRequestContextMiddleware.Handle(gbh.Handler)
+	server.GET("/tasks/batch/:id", gbhm)
+
+	ebh := handler.NewExportBatchHandler(dep.GetBatchUsecase)
+	ebhm := dep.SetRequestContextMiddleware.Handle(ebh.Handler)
+	server.GET("/tasks/batch/:id/export", ebhm)
+
 	// 一覧取得
 	lth := handler.NewListTaskHandler(dep.ListTaskUsecase)
 	lthm := dep.SetRequestContextMiddleware.Handle(lth.Handler)
 	server.GET("/task", lthm)
 
+	// 使用量取得
+	guh := handler.NewGetUsageHandler(dep.GetUsageUsecase)
+	guhm := dep.SetRequestContextMiddleware.Handle(guh.Handler)
+	server.GET("/usage", guhm)
+
+	// プロンプトテンプレート
+	pth := handler.NewPutPromptTemplateHandler(dep.Validator, dep.PutPromptTemplateUsecase)
+	pthm := dep.SetRequestContextMiddleware.Handle(pth.Handler)
+	server.POST("/template", pthm)
+
+	lpth := handler.NewListPromptTemplateHandler(dep.ListPromptTemplateUsecase)
+	lpthm := dep.SetRequestContextMiddleware.Handle(lpth.Handler)
+	server.GET("/template", lpthm)
+
+	dpth := handler.NewDeletePromptTemplateHandler(dep.DeletePromptTemplateUsecase)
+	dpthm := dep.SetRequestContextMiddleware.Handle(dpth.Handler)
+	server.DELETE("/template/:name", dpthm)
+
 	return server, nil
 }
 
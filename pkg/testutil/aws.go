@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
@@ -87,6 +88,19 @@ func CreateDynamoDBForTest(
 	return nil
 }
 
+// CreateS3BucketForTestはLocalStackにバケットを作成する。既に存在する場合は何もしない
+func CreateS3BucketForTest(ctx context.Context, awsCfg aws.Config, bucket string) error {
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) { o.UsePathStyle = true })
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err == nil {
+		return nil
+	}
+	_, err := client.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return fmt.Errorf("failed CreateBucket: %w", err)
+	}
+	return nil
+}
+
 func DropDynamoDBForTest(
 	ctx context.Context, awsCfg aws.Config, tableName string,
 ) error {
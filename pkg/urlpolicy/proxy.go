@@ -0,0 +1,169 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/netip"
+	"net/url"
+	"time"
+)
+
+// Proxyはブラウザの接続を中継し、接続先をPolicyで検証するHTTPプロキシ
+// ブラウザ自身は名前解決せず、プロキシが名前解決して検証したIPアドレスにのみ接続するため、
+// 検証後に名前解決の結果を変えるDNSリバインディングでも内部のアドレスに接続しない
+type Proxy struct {
+	policy    *Policy
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+	forward   *httputil.ReverseProxy
+}
+
+// StartProxyはループバックアドレスの空いているポートでプロキシを起動する
+func (p *Policy) StartProxy() (*Proxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen proxy: %w", err)
+	}
+	x := &Proxy{
+		policy:   p,
+		listener: listener,
+		transport: &http.Transport{
+			DialContext:           p.dialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+	x.forward = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			// 接続元のアドレスは転送しない
+			req.Header["X-Forwarded-For"] = nil
+		},
+		Transport: x.transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), proxyErrorStatus(err))
+		},
+	}
+	x.server = &http.Server{Handler: x, ReadHeaderTimeout: 30 * time.Second}
+	go x.server.Serve(listener)
+	return x, nil
+}
+
+// URLはブラウザに指定するプロキシのURLを返す
+func (x *Proxy) URL() string {
+	return "http://" + x.listener.Addr().String()
+}
+
+func (x *Proxy) Close() error {
+	x.transport.CloseIdleConnections()
+	if err := x.server.Close(); err != nil {
+		return fmt.Errorf("failed to close proxy: %w", err)
+	}
+	return nil
+}
+
+func (x *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		x.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "proxy request must have an absolute url", http.StatusBadRequest)
+		return
+	}
+	if err := x.policy.CheckURL(r.Context(), r.URL); err != nil {
+		http.Error(w, err.Error(), proxyErrorStatus(err))
+		return
+	}
+	x.forward.ServeHTTP(w, r)
+}
+
+// tunnelはHTTPSなどのCONNECTのリクエストで、検証したIPアドレスとの接続を中継する
+func (x *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	if err := x.policy.CheckURL(r.Context(), &url.URL{Scheme: "https", Host: r.Host}); err != nil {
+		http.Error(w, err.Error(), proxyErrorStatus(err))
+		return
+	}
+	conn, err := x.policy.dialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), proxyErrorStatus(err))
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		conn.Close()
+		http.Error(w, "proxy does not support hijacking", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		client.Close()
+		return
+	}
+	// いずれかの接続が閉じられた場合は、もう一方の接続も閉じて中継を終える
+	go func() {
+		_, _ = io.Copy(conn, buffered)
+		conn.Close()
+	}()
+	_, _ = io.Copy(client, conn)
+	client.Close()
+}
+
+// dialContextはホスト名を解決したすべてのIPアドレスを検証し、検証したIPアドレスに接続する
+// 接続時にもcontrolで接続先のIPアドレスを検証する
+func (p *Policy) dialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBlockedURL, err)
+	}
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		addrs, err = p.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("failed to resolve %s: no addresses", host)
+	}
+	for _, addr := range addrs {
+		if err := p.CheckAddr(addr); err != nil {
+			return nil, fmt.Errorf("%w (resolved from %s)", err, host)
+		}
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.control,
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("failed to dial %s: %w", address, errors.Join(errs...))
+}
+
+func proxyErrorStatus(err error) int {
+	if errors.Is(err, ErrBlockedURL) || errors.Is(err, ErrInvalidURL) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadGateway
+}
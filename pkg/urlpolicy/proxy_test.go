@@ -0,0 +1,124 @@
+package urlpolicy
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// rebindingResolverは1回目の名前解決で公開アドレスを返し、以降はループバックアドレスを返す
+type rebindingResolver struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (r *rebindingResolver) LookupNetIP(ctx context.Context, network string, host string) ([]netip.Addr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls == 1 {
+		return []netip.Addr{netip.MustParseAddr("93.184.216.34")}, nil
+	}
+	return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+}
+
+func Test_Proxy(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	httpServer := httptest.NewServer(handler)
+	t.Cleanup(httpServer.Close)
+	tlsServer := httptest.NewTLSServer(handler)
+	t.Cleanup(tlsServer.Close)
+
+	tests := []struct {
+		name       string
+		server     *httptest.Server
+		config     *Config
+		wantStatus int
+	}{
+		{
+			name:   "http",
+			server: httpServer,
+			config: &Config{
+				AllowPrivateNetworks: true,
+				Resolver:             fakeResolver{"site.test": {netip.MustParseAddr("127.0.0.1")}},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "https",
+			server: tlsServer,
+			config: &Config{
+				AllowPrivateNetworks: true,
+				Resolver:             fakeResolver{"site.test": {netip.MustParseAddr("127.0.0.1")}},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			// 検証時と接続時で名前解決の結果が変わっても、接続時に解決したアドレスを検証する
+			name:       "http rebinding",
+			server:     httpServer,
+			config:     &Config{Resolver: &rebindingResolver{}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "https rebinding",
+			server:     tlsServer,
+			config:     &Config{Resolver: &rebindingResolver{}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, err := NewPolicy(tt.config).StartProxy()
+			if err != nil {
+				t.Fatalf("StartProxy() error = %v", err)
+			}
+			t.Cleanup(func() { proxy.Close() })
+			proxyURL, err := url.Parse(proxy.URL())
+			if err != nil {
+				t.Fatalf("failed to parse proxy url: %v", err)
+			}
+
+			serverURL, err := url.Parse(tt.server.URL)
+			if err != nil {
+				t.Fatalf("failed to parse server url: %v", err)
+			}
+			_, port, _ := net.SplitHostPort(serverURL.Host)
+			serverURL.Host = net.JoinHostPort("site.test", port)
+
+			transport := tt.server.Client().Transport.(*http.Transport).Clone()
+			transport.Proxy = http.ProxyURL(proxyURL)
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			// httptestの証明書はexample.comに対して発行されている
+			transport.TLSClientConfig.ServerName = "example.com"
+			resp, err := (&http.Client{Transport: transport}).Get(serverURL.String())
+			if tt.wantStatus != http.StatusOK && serverURL.Scheme == "https" {
+				// CONNECTを拒否した場合はクライアントのエラーになる
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("Get() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				body, _ := io.ReadAll(resp.Body)
+				t.Errorf("StatusCode = %d, want %d: %s", resp.StatusCode, tt.wantStatus, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	// ErrInvalidURLはURLとして解釈できない場合や、認証情報を含むなど受け付けない形式の場合のエラー
+	ErrInvalidURL = errors.New("invalid url")
+	// ErrBlockedURLはスキームやドメイン、接続先のIPアドレスがポリシーで許可されていない場合のエラー
+	ErrBlockedURL = errors.New("blocked url")
+)
+
+const (
+	// hostCacheTTLはホストの名前解決と判定の結果をキャッシュする期間
+	// ブラウザはサブリソースごとに判定するため、同じホストの名前解決を繰り返さない
+	hostCacheTTL = time.Minute
+	// maxRedirectsはHTTPクライアントが追従するリダイレクトの最大の回数
+	maxRedirects = 10
+)
+
+var (
+	// numericHostPatternは"2130706433"や"0x7f.1"など、ブラウザがIPv4アドレスとして解釈する数値のみのホスト名
+	numericHostPattern = regexp.MustCompile(`^(0x[0-9a-f]*|[0-9]+)(\.(0x[0-9a-f]*|[0-9]+))*\.?$`)
+
+	// blockedPrefixesはnetip.Addrのメソッドで判定できない、外部から到達できないIPアドレスの範囲
+	blockedPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("192.0.0.0/24"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+		netip.MustParsePrefix("240.0.0.0/4"),
+		netip.MustParsePrefix("64:ff9b::/96"),
+		netip.MustParsePrefix("64:ff9b:1::/48"),
+		netip.MustParsePrefix("2002::/16"),
+	}
+)
+
+// Normalizeはユーザーが入力したURLを正規化する
+// スキームがない場合はhttpsとみなし、スキームとホスト名の小文字化、国際化ドメイン名のPunycodeへの変換、
+// 既定のポートとフラグメントの除去を行う
+func Normalize(rawURL string) (*url.URL, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return nil, fmt.Errorf("%w: url is empty", ErrInvalidURL)
+	}
+	if !strings.Contains(rawURL, "://") && !strings.Contains(rawURL, ":") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Opaque != "" || u.Host == "" {
+		return nil, fmt.Errorf("%w: host is empty: %s", ErrInvalidURL, rawURL)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("%w: url must not contain credentials", ErrInvalidURL)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if !strings.Contains(host, ":") {
+		if host, err = idna.Lookup.ToASCII(host); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+		}
+		if numericHostPattern.MatchString(host) {
+			if _, err := netip.ParseAddr(host); err != nil {
+				return nil, fmt.Errorf("%w: ambiguous ip address: %s", ErrInvalidURL, host)
+			}
+		}
+	}
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// Resolverはホスト名をIPアドレスに解決する
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network string, host string) ([]netip.Addr, error)
+}
+
+type Config struct {
+	// AllowedSchemesは取得を許可するスキーム。空の場合はhttpとhttps
+	AllowedSchemes []string
+	// AllowedDomainsが空でない場合は、このドメインとそのサブドメインのみ取得を許可する
+	AllowedDomains []string
+	// DeniedDomainsはこのドメインとそのサブドメインの取得を拒否する
+	DeniedDomains []string
+	// AllowPrivateNetworksがtrueの場合はプライベートネットワークやループバックのIPアドレスへの接続を許可する
+	// ローカルでの開発用で、本番環境では有効にしない
+	AllowPrivateNetworks bool
+	// Resolverは未指定の場合net.DefaultResolverを利用する
+	Resolver Resolver
+}
+
+// Policyは要約するURLと接続先のIPアドレスを検証する
+type Policy struct {
+	allowedSchemes       []string
+	allowedDomains       []string
+	deniedDomains        []string
+	allowPrivateNetworks bool
+	resolver             Resolver
+	now                  func() time.Time
+
+	mu        sync.Mutex
+	hostCache map[string]hostCheck
+}
+
+type hostCheck struct {
+	err       error
+	expiresAt time.Time
+}
+
+func NewPolicy(config *Config) *Policy {
+	p := &Policy{
+		allowedSchemes: []string{"http", "https"},
+		resolver:       net.DefaultResolver,
+		now:            time.Now,
+		hostCache:      map[string]hostCheck{},
+	}
+	if config != nil {
+		if len(config.AllowedSchemes) > 0 {
+			p.allowedSchemes = lowerAll(config.AllowedSchemes)
+		}
+		p.allowedDomains = lowerAll(config.AllowedDomains)
+		p.deniedDomains = lowerAll(config.DeniedDomains)
+		p.allowPrivateNetworks = config.AllowPrivateNetworks
+		if config.Resolver != nil {
+			p.resolver = config.Resolver
+		}
+	}
+	return p
+}
+
+// Checkはurlを正規化して検証し、正規化したURLを返す
+func (p *Policy) Check(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := Normalize(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.CheckURL(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// CheckURLはスキームとドメインを検証し、ホスト名を解決したすべてのIPアドレスが接続を許可されているかを検証する
+func (p *Policy) CheckURL(ctx context.Context, u *url.URL) error {
+	if !contains(p.allowedSchemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrBlockedURL, u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: host is empty", ErrInvalidURL)
+	}
+	if len(p.allowedDomains) > 0 && !matchDomain(p.allowedDomains, host) {
+		return fmt.Errorf("%w: domain %s is not allowed", ErrBlockedURL, host)
+	}
+	if matchDomain(p.deniedDomains, host) {
+		return fmt.Errorf("%w: domain %s is denied", ErrBlockedURL, host)
+	}
+	return p.checkHost(ctx, host)
+}
+
+func (p *Policy) checkHost(ctx context.Context, host string) error {
+	if p.allowPrivateNetworks {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s is a loopback host", ErrBlockedURL, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(addr)
+	}
+
+	p.mu.Lock()
+	cached, ok := p.hostCache[host]
+	p.mu.Unlock()
+	if ok && p.now().Before(cached.expiresAt) {
+		return cached.err
+	}
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		// 名前解決の失敗は一時的な場合があるためキャッシュしない
+		return fmt.Errorf("%w: failed to resolve %s: %w", ErrBlockedURL, host, err)
+	}
+	var checkErr error
+	for _, addr := range addrs {
+		if err := p.CheckAddr(addr); err != nil {
+			checkErr = fmt.Errorf("%w (resolved from %s)", err, host)
+			break
+		}
+	}
+	p.mu.Lock()
+	p.hostCache[host] = hostCheck{err: checkErr, expiresAt: p.now().Add(hostCacheTTL)}
+	p.mu.Unlock()
+	return checkErr
+}
+
+// CheckAddrはIPアドレスへの接続が許可されているかを検証する
+func (p *Policy) CheckAddr(addr netip.Addr) error {
+	if p.allowPrivateNetworks {
+		return nil
+	}
+	if IsBlockedAddr(addr) {
+		return fmt.Errorf("%w: ip address %s is not allowed", ErrBlockedURL, addr)
+	}
+	return nil
+}
+
+// IsBlockedAddrはプライベートネットワーク、ループバック、リンクローカル(クラウドのメタデータを含む)など、
+// 外部から到達できないIPアドレスかを返す
+func IsBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return true
+	}
+	if addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// HTTPClientはリダイレクト先のURLと、名前解決後に実際に接続するIPアドレスを検証するHTTPクライアントを返す
+// 接続時に検証するため、検証後に名前解決の結果を変えるDNSリバインディングでも内部のアドレスに接続しない
+func (p *Policy) HTTPClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 環境変数のプロキシを経由すると接続先のIPアドレスを検証できないため利用しない
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   p.control,
+	}).DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return p.CheckURL(req.Context(), req.URL)
+		},
+	}
+}
+
+func (p *Policy) control(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBlockedURL, err)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBlockedURL, err)
+	}
+	return p.CheckAddr(addr)
+}
+
+// matchDomainはhostがdomainsのいずれかのドメインまたはそのサブドメインかを返す
+func matchDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerAll(values []string) []string {
+	var lowered []string
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			lowered = append(lowered, strings.TrimPrefix(v, "."))
+		}
+	}
+	return lowered
+}
@@ -0,0 +1,62 @@
+package cancel_task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type SummaryRepository interface {
+	GetSummary(ctx context.Context, id string, userId *string) (*entities.Summary, error)
+	RequestCancel(ctx context.Context, summary *entities.Summary) error
+	TransitionTaskStatus(ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string) error
+}
+
+// cancelRequestedReasonはユーザーがキャンセルした場合に状態の履歴に記録する理由
+const cancelRequestedReason = "user_requested"
+
+type Usecase struct {
+	SummaryRepository SummaryRepository
+}
+
+func NewUsecase(summaryRepository SummaryRepository) *Usecase {
+	return &Usecase{SummaryRepository: summaryRepository}
+}
+
+// Runはタスクにキャンセルの依頼を記録する
+// ワーカーが実行を開始していないタスクはその場でキャンセルした状態にし、
+// 実行中のタスクはワーカーが依頼を確認して取得や要約を中断する
+// 終了したタスクや失敗したタスクの場合はrepository.ErrNotCancellableを返す
+func (u *Usecase) Run(ctx context.Context, taskId string) (*entities.Summary, error) {
+	var userIdPtr *string
+	userId, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed GetUserSub: %w", err)
+	}
+	if userId != util.APIKeyUserSub {
+		userIdPtr = &userId
+	}
+	summary, err := u.SummaryRepository.GetSummary(ctx, taskId, userIdPtr)
+	if err != nil {
+		return nil, fmt.Errorf("failed get summary: %w", err)
+	}
+	// 状態を確認した後にワーカーが実行を開始しても中断できるよう、先に依頼を記録する
+	if err := u.SummaryRepository.RequestCancel(ctx, summary); err != nil {
+		return nil, fmt.Errorf("failed request cancel: %w", err)
+	}
+	if summary.TaskStatus.InProgress() {
+		return summary, nil
+	}
+	err = u.SummaryRepository.TransitionTaskStatus(ctx, summary, entities.TaskStatusCancelled, cancelRequestedReason)
+	if errors.Is(err, entities.ErrInvalidTransition) {
+		// ワーカーが実行を開始したため、ワーカーがキャンセルする
+		return summary, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed transition task status: %w", err)
+	}
+	return summary, nil
+}
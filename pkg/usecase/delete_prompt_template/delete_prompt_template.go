@@ -0,0 +1,31 @@
+package delete_prompt_template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type PromptTemplateRepository interface {
+	DeletePromptTemplate(ctx context.Context, userId string, name string) error
+}
+
+type Usecase struct {
+	PromptTemplateRepository PromptTemplateRepository
+}
+
+func NewUsecase(promptTemplateRepository PromptTemplateRepository) *Usecase {
+	return &Usecase{PromptTemplateRepository: promptTemplateRepository}
+}
+
+func (u *Usecase) Run(ctx context.Context, name string) error {
+	userSub, err := util.GetUserSub(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get user sub: %w", err)
+	}
+	if err := u.PromptTemplateRepository.DeletePromptTemplate(ctx, userSub, name); err != nil {
+		return fmt.Errorf("failed to delete prompt template: %w", err)
+	}
+	return nil
+}
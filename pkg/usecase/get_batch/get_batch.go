@@ -0,0 +1,65 @@
+package get_batch
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type BatchRepository interface {
+	GetBatch(ctx context.Context, id string, userId *string) (*entities.Batch, error)
+}
+
+type SummaryRepository interface {
+	GetSummaries(ctx context.Context, ids []string, userId string) ([]*entities.Summary, error)
+}
+
+type Usecase struct {
+	BatchRepository   BatchRepository
+	SummaryRepository SummaryRepository
+}
+
+func NewUsecase(batchRepository BatchRepository, summaryRepository SummaryRepository) *Usecase {
+	return &Usecase{
+		BatchRepository:   batchRepository,
+		SummaryRepository: summaryRepository,
+	}
+}
+
+// Runはバッチと、含まれるタスクの状態と要約を取得して進捗を集計する
+func (u *Usecase) Run(ctx context.Context, batchId string) (*entities.BatchStatus, error) {
+	var userIdPtr *string
+	userId, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed GetUserSub: %w", err)
+	}
+	if userId != util.APIKeyUserSub {
+		userIdPtr = &userId
+	}
+	batch, err := u.BatchRepository.GetBatch(ctx, batchId, userIdPtr)
+	if err != nil {
+		return nil, fmt.Errorf("failed get batch: %w", err)
+	}
+	summaries, err := u.SummaryRepository.GetSummaries(ctx, batch.TaskIds, batch.UserId)
+	if err != nil {
+		return nil, fmt.Errorf("failed get summaries: %w", err)
+	}
+	// BatchGetItemは順序を保証しないため、依頼された順に並べ直す
+	byId := make(map[string]*entities.Summary, len(summaries))
+	for _, s := range summaries {
+		byId[s.Id] = s
+	}
+	tasks := make([]*entities.Summary, 0, len(batch.TaskIds))
+	for _, id := range batch.TaskIds {
+		if s, ok := byId[id]; ok {
+			tasks = append(tasks, s)
+		}
+	}
+	return &entities.BatchStatus{
+		Batch:    batch,
+		Progress: entities.NewBatchProgress(tasks),
+		Tasks:    tasks,
+	}, nil
+}
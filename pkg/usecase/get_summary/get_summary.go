@@ -3,6 +3,7 @@ package get_summary
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
 	"github.com/shoet/webpagesummary/pkg/util"
@@ -12,12 +13,26 @@ type SummaryRepository interface {
 	GetSummary(ctx context.Context, id string, userId *string) (*entities.Summary, error)
 }
 
+// SnapshotStorageはページのスナップショットの期限付きのダウンロード用のURLを発行する
+type SnapshotStorage interface {
+	PresignGetObject(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
 type Usecase struct {
 	SummaryRepository SummaryRepository
+	// SnapshotStorageがnilの場合はスナップショットのURLを返さない
+	SnapshotStorage SnapshotStorage
+	SnapshotLinkTTL time.Duration
 }
 
-func NewUsecase(summaryRepository SummaryRepository) *Usecase {
-	return &Usecase{SummaryRepository: summaryRepository}
+func NewUsecase(
+	summaryRepository SummaryRepository, snapshotStorage SnapshotStorage, snapshotLinkTTL time.Duration,
+) *Usecase {
+	return &Usecase{
+		SummaryRepository: summaryRepository,
+		SnapshotStorage:   snapshotStorage,
+		SnapshotLinkTTL:   snapshotLinkTTL,
+	}
 }
 
 func (u *Usecase) Run(ctx context.Context, taskId string) (*entities.Summary, error) {
@@ -33,5 +48,34 @@ func (u *Usecase) Run(ctx context.Context, taskId string) (*entities.Summary, er
 	if err != nil {
 		return nil, fmt.Errorf("failed get summary: %w", err)
 	}
+	if summary.Snapshot != nil && u.SnapshotStorage != nil {
+		links, err := u.snapshotLinks(ctx, summary.Snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("failed get snapshot links: %w", err)
+		}
+		summary.Snapshot.Links = links
+	}
 	return summary, nil
 }
+
+// snapshotLinksはスナップショットの各オブジェクトのダウンロード用のURLを発行する
+func (u *Usecase) snapshotLinks(ctx context.Context, snapshot *entities.PageSnapshot) (*entities.SnapshotLinks, error) {
+	presign := func(key string) (string, error) {
+		if key == "" {
+			return "", nil
+		}
+		return u.SnapshotStorage.PresignGetObject(ctx, key, u.SnapshotLinkTTL)
+	}
+	links := &entities.SnapshotLinks{ExpiresAt: time.Now().Add(u.SnapshotLinkTTL).Unix()}
+	var err error
+	if links.Raw, err = presign(snapshot.RawKey); err != nil {
+		return nil, err
+	}
+	if links.Screenshot, err = presign(snapshot.ScreenshotKey); err != nil {
+		return nil, err
+	}
+	if links.MHTML, err = presign(snapshot.MHTMLKey); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
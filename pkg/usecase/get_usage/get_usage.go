@@ -0,0 +1,53 @@
+package get_usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+)
+
+type TaskRepository interface {
+	GetUsage(ctx context.Context, tx infrastracture.Transactor, input *repository.GetUsageInput) ([]*entities.UserUsage, error)
+}
+
+type Usecase struct {
+	DBHandler      *infrastracture.DBHandler
+	TaskRepository TaskRepository
+}
+
+func NewUsecase(dbHandler *infrastracture.DBHandler, taskRepository TaskRepository) *Usecase {
+	return &Usecase{
+		DBHandler:      dbHandler,
+		TaskRepository: taskRepository,
+	}
+}
+
+type UsecaseInput struct {
+	// From, Toは集計期間 [From, To)
+	From time.Time
+	To   time.Time
+}
+
+func (u *Usecase) Run(ctx context.Context, input UsecaseInput) ([]*entities.UserUsage, error) {
+	if !input.From.Before(input.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	repoInput := &repository.GetUsageInput{
+		From: input.From.Unix(),
+		To:   input.To.Unix(),
+	}
+	tx, err := u.DBHandler.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed GetTransaction: %w", err)
+	}
+	defer tx.Rollback()
+	usages, err := u.TaskRepository.GetUsage(ctx, tx, repoInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed GetUsage: %w", err)
+	}
+	return usages, nil
+}
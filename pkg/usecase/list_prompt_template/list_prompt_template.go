@@ -0,0 +1,33 @@
+package list_prompt_template
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type PromptTemplateRepository interface {
+	ListPromptTemplates(ctx context.Context, userId string) ([]*entities.PromptTemplate, error)
+}
+
+type Usecase struct {
+	PromptTemplateRepository PromptTemplateRepository
+}
+
+func NewUsecase(promptTemplateRepository PromptTemplateRepository) *Usecase {
+	return &Usecase{PromptTemplateRepository: promptTemplateRepository}
+}
+
+func (u *Usecase) Run(ctx context.Context) ([]*entities.PromptTemplate, error) {
+	userSub, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sub: %w", err)
+	}
+	templates, err := u.PromptTemplateRepository.ListPromptTemplates(ctx, userSub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	return templates, nil
+}
@@ -0,0 +1,66 @@
+package put_prompt_template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type PromptTemplateRepository interface {
+	GetPromptTemplate(ctx context.Context, userId string, name string) (*entities.PromptTemplate, error)
+	PutPromptTemplate(ctx context.Context, t *entities.PromptTemplate) error
+}
+
+// ErrInvalidTemplateはテンプレートの名前や本文が不正な場合のエラー
+var ErrInvalidTemplate = errors.New("invalid template")
+
+type Usecase struct {
+	PromptTemplateRepository PromptTemplateRepository
+}
+
+func NewUsecase(promptTemplateRepository PromptTemplateRepository) *Usecase {
+	return &Usecase{PromptTemplateRepository: promptTemplateRepository}
+}
+
+type UsecaseInput struct {
+	Name string
+	Body string
+}
+
+// Runはテンプレートを作成する。同じ名前のテンプレートが存在する場合は本文を上書きする
+func (u *Usecase) Run(ctx context.Context, input UsecaseInput) (*entities.PromptTemplate, error) {
+	userSub, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sub: %w", err)
+	}
+
+	now := time.Now().Unix()
+	t := &entities.PromptTemplate{
+		UserId:    userSub,
+		Name:      input.Name,
+		Body:      input.Body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := t.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTemplate, err)
+	}
+
+	current, err := u.PromptTemplateRepository.GetPromptTemplate(ctx, userSub, input.Name)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	if current != nil {
+		t.CreatedAt = current.CreatedAt
+	}
+
+	if err := u.PromptTemplateRepository.PutPromptTemplate(ctx, t); err != nil {
+		return nil, fmt.Errorf("failed to put prompt template: %w", err)
+	}
+	return t, nil
+}
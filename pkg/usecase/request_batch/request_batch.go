@@ -0,0 +1,248 @@
+package request_batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/usecase/request_task"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type SummaryRepository interface {
+	CreateSummaries(ctx context.Context, summaries []*entities.Summary) error
+	TransitionTaskStatus(ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string) error
+}
+
+type BatchRepository interface {
+	CreateBatch(ctx context.Context, batch *entities.Batch) error
+}
+
+type PromptTemplateRepository interface {
+	GetPromptTemplate(ctx context.Context, userId string, name string) (*entities.PromptTemplate, error)
+}
+
+type QueueClient interface {
+	QueueBatch(ctx context.Context, messages []string) ([]int, error)
+}
+
+type RateLimitRepository interface {
+	GetById(ctx context.Context, id string) (*entities.AuthRateLimit, error)
+	PutItem(ctx context.Context, rateLimit *entities.AuthRateLimit) error
+}
+
+// URLPolicyは要約するURLを正規化して、取得してよいURLかを検証する
+type URLPolicy interface {
+	Check(ctx context.Context, rawURL string) (*url.URL, error)
+}
+
+// urlCheckConcurrencyはURLを検証する際に同時に名前解決するURLの数
+const urlCheckConcurrency = 16
+
+type Usecase struct {
+	SummaryRepository        SummaryRepository
+	BatchRepository          BatchRepository
+	PromptTemplateRepository PromptTemplateRepository
+	QueueClient              QueueClient
+	URLPolicy                URLPolicy
+	RateLimitRepository      RateLimitRepository
+	RateLimitMax             int
+}
+
+func NewUsecase(
+	summaryRepository SummaryRepository,
+	batchRepository BatchRepository,
+	promptTemplateRepository PromptTemplateRepository,
+	queueClient QueueClient,
+	urlPolicy URLPolicy,
+	rateLimitRepository RateLimitRepository,
+	rateLimitMax int,
+) *Usecase {
+	return &Usecase{
+		SummaryRepository:        summaryRepository,
+		BatchRepository:          batchRepository,
+		PromptTemplateRepository: promptTemplateRepository,
+		QueueClient:              queueClient,
+		URLPolicy:                urlPolicy,
+		RateLimitRepository:      rateLimitRepository,
+		RateLimitMax:             rateLimitMax,
+	}
+}
+
+// UsecaseInputはまとめて要約するURLと、すべてのURLに共通する要約の設定
+type UsecaseInput struct {
+	Name       string
+	Urls       []string
+	Options    *entities.SummaryOptions
+	Style      string
+	Language   string
+	Structured bool
+}
+
+// Runはバッチを作成し、URLごとのタスクをまとめてキューに登録する
+// 検証に失敗したURLや重複したURLはタスクを作成せず、バッチのRejectedに記録する
+// キューに登録できなかったタスクは失敗した状態にし、再実行を依頼できるようにする
+// リクエスト回数制限の対象の場合は受け付けたURLの数だけリクエスト回数を加算する
+func (u *Usecase) Run(ctx context.Context, input UsecaseInput) (*entities.Batch, error) {
+	userSub, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sub: %w", err)
+	}
+
+	if len(input.Urls) == 0 {
+		return nil, entities.ErrEmptyBatch
+	}
+	if len(input.Urls) > entities.MaxBatchSize {
+		return nil, fmt.Errorf("%w: %d urls exceeds %d", entities.ErrBatchTooLarge, len(input.Urls), entities.MaxBatchSize)
+	}
+
+	if input.Structured && input.Style != "" && input.Style != entities.SummaryStyleDefault {
+		return nil, request_task.ErrStyleWithStructured
+	}
+	if input.Style != "" && !entities.IsBuiltinSummaryStyle(input.Style) {
+		_, err := u.PromptTemplateRepository.GetPromptTemplate(ctx, userSub, input.Style)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", request_task.ErrUnknownStyle, input.Style)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get prompt template: %w", err)
+		}
+	}
+
+	now := time.Now()
+	batch := &entities.Batch{
+		Id:        uuid.New().String(),
+		UserId:    userSub,
+		Name:      input.Name,
+		TaskIds:   []string{},
+		CreatedAt: now.Unix(),
+	}
+	var summaries []*entities.Summary
+	seen := map[string]bool{}
+	for i, pageUrl := range u.checkUrls(ctx, input.Urls) {
+		if pageUrl.err != nil {
+			batch.Rejected = append(batch.Rejected, entities.BatchRejectedUrl{Url: input.Urls[i], Reason: pageUrl.err.Error()})
+			continue
+		}
+		if seen[pageUrl.url] {
+			batch.Rejected = append(batch.Rejected, entities.BatchRejectedUrl{Url: input.Urls[i], Reason: "duplicate url"})
+			continue
+		}
+		seen[pageUrl.url] = true
+		summary := &entities.Summary{
+			Id:         uuid.New().String(),
+			PageUrl:    pageUrl.url,
+			CreatedAt:  now.Unix(),
+			UserId:     userSub,
+			Options:    input.Options,
+			Style:      input.Style,
+			Language:   input.Language,
+			Structured: input.Structured,
+			BatchId:    batch.Id,
+		}
+		summary.InitTaskStatus(now)
+		summaries = append(summaries, summary)
+		batch.TaskIds = append(batch.TaskIds, summary.Id)
+	}
+	if len(summaries) == 0 {
+		return nil, fmt.Errorf(
+			"%w: all urls are rejected: %s: %s", entities.ErrEmptyBatch, batch.Rejected[0].Url, batch.Rejected[0].Reason,
+		)
+	}
+
+	if err := u.consumeRateLimit(ctx, userSub, len(summaries)); err != nil {
+		return nil, err
+	}
+
+	// 一覧の取得時にタスクが存在しないことのないよう、タスクを作成してからバッチを作成する
+	if err := u.SummaryRepository.CreateSummaries(ctx, summaries); err != nil {
+		return nil, fmt.Errorf("failed to create summaries: %w", err)
+	}
+	if err := u.BatchRepository.CreateBatch(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+
+	messages := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		message, err := (&entities.TaskMessage{TaskId: s.Id, Language: s.Language}).Marshal()
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	failed, err := u.QueueClient.QueueBatch(ctx, messages)
+	if err != nil && len(failed) == 0 {
+		// 送信できたメッセージが分からない場合は、すべて送信できなかったものとして失敗させる
+		// 作成済みのタスクはメッセージがないと実行されず、失敗した状態からのみ再実行できる
+		failed = make([]int, len(summaries))
+		for i := range summaries {
+			failed[i] = i
+		}
+	}
+	for _, i := range failed {
+		if err := u.SummaryRepository.TransitionTaskStatus(
+			ctx, summaries[i], entities.TaskStatusFailed, string(entities.TaskFailureUpstreamUnavailable),
+		); err != nil {
+			return nil, fmt.Errorf("failed to update task status: %w", err)
+		}
+	}
+	return batch, nil
+}
+
+// consumeRateLimitは受け付けたURLの数だけリクエスト回数を加算する
+// リクエスト自体の1回はAuthRateLimitMiddlewareで加算済みのため、残りの回数を加算する
+// 残りの回数を超える場合はタスクを作成せずにErrRateLimitExceededを返す
+func (u *Usecase) consumeRateLimit(ctx context.Context, userSub string, accepted int) error {
+	if !util.IsRateLimited(ctx) {
+		return nil
+	}
+	rateLimit, err := u.RateLimitRepository.GetById(ctx, userSub)
+	if err != nil {
+		return fmt.Errorf("failed to get rate limit: %w", err)
+	}
+	remaining := u.RateLimitMax - int(rateLimit.Count) + 1
+	if accepted > remaining {
+		return fmt.Errorf("%w: %d urls exceeds remaining %d", entities.ErrRateLimitExceeded, accepted, remaining)
+	}
+	count := int(rateLimit.Count) + accepted - 1
+	rateLimit.Count = uint(count)
+	if err := u.RateLimitRepository.PutItem(ctx, rateLimit); err != nil {
+		return fmt.Errorf("failed to put rate limit: %w", err)
+	}
+	return nil
+}
+
+type checkedUrl struct {
+	url string
+	err error
+}
+
+// checkUrlsはURLを正規化して検証する。名前解決に時間がかかるため並行して検証する
+func (u *Usecase) checkUrls(ctx context.Context, urls []string) []checkedUrl {
+	results := make([]checkedUrl, len(urls))
+	semaphore := make(chan struct{}, urlCheckConcurrency)
+	var wg sync.WaitGroup
+	for i, rawURL := range urls {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		i, rawURL := i, rawURL
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			normalized, err := u.URLPolicy.Check(ctx, rawURL)
+			if err != nil {
+				results[i] = checkedUrl{err: err}
+				return
+			}
+			results[i] = checkedUrl{url: normalized.String()}
+		}()
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,170 @@
+package request_batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type fakeSummaryRepository struct {
+	created     []*entities.Summary
+	transitions map[string]entities.TaskStatusChange
+}
+
+func (r *fakeSummaryRepository) CreateSummaries(ctx context.Context, summaries []*entities.Summary) error {
+	r.created = append(r.created, summaries...)
+	return nil
+}
+
+func (r *fakeSummaryRepository) TransitionTaskStatus(
+	ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string,
+) error {
+	if r.transitions == nil {
+		r.transitions = map[string]entities.TaskStatusChange{}
+	}
+	r.transitions[summary.Id] = entities.TaskStatusChange{From: summary.TaskStatus, Status: next, Reason: reason}
+	summary.TaskStatus = next
+	return nil
+}
+
+type fakeBatchRepository struct {
+	created *entities.Batch
+}
+
+func (r *fakeBatchRepository) CreateBatch(ctx context.Context, batch *entities.Batch) error {
+	r.created = batch
+	return nil
+}
+
+// fakeQueueClientはfailedの位置のメッセージの送信に失敗したことを再現する
+type fakeQueueClient struct {
+	failed   []int
+	err      error
+	messages []string
+}
+
+func (q *fakeQueueClient) QueueBatch(ctx context.Context, messages []string) ([]int, error) {
+	q.messages = messages
+	return q.failed, q.err
+}
+
+type fakeURLPolicy struct{}
+
+func (p *fakeURLPolicy) Check(ctx context.Context, rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid url: %s", rawURL)
+	}
+	return u, nil
+}
+
+type fakeRateLimitRepository struct {
+	rateLimit *entities.AuthRateLimit
+}
+
+func (r *fakeRateLimitRepository) GetById(ctx context.Context, id string) (*entities.AuthRateLimit, error) {
+	return &entities.AuthRateLimit{ID: r.rateLimit.ID, Count: r.rateLimit.Count, TTL: r.rateLimit.TTL}, nil
+}
+
+func (r *fakeRateLimitRepository) PutItem(ctx context.Context, rateLimit *entities.AuthRateLimit) error {
+	r.rateLimit = rateLimit
+	return nil
+}
+
+func Test_Usecase_Run_RateLimit(t *testing.T) {
+	urls := []string{"https://example.com/1", "https://example.com/2", "not a url", "https://example.com/1"}
+	tests := []struct {
+		name        string
+		rateLimited bool
+		count       uint
+		wantErr     error
+		wantCount   uint
+	}{
+		// リクエスト自体の1回はミドルウェアで加算済み
+		{name: "within quota", rateLimited: true, count: 1, wantCount: 2},
+		{name: "exactly remaining", rateLimited: true, count: 9, wantCount: 10},
+		{name: "exceeds remaining", rateLimited: true, count: 10, wantErr: entities.ErrRateLimitExceeded, wantCount: 10},
+		{name: "not rate limited", rateLimited: false, count: 10, wantCount: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), util.TokenSubContextKey{}, "user1")
+			if tt.rateLimited {
+				ctx = context.WithValue(ctx, util.RateLimitedContextKey{}, true)
+			}
+			summaryRepo := &fakeSummaryRepository{}
+			queue := &fakeQueueClient{}
+			rateLimitRepo := &fakeRateLimitRepository{rateLimit: &entities.AuthRateLimit{ID: "user1", Count: tt.count}}
+			sut := NewUsecase(
+				summaryRepo, &fakeBatchRepository{}, nil, queue, &fakeURLPolicy{}, rateLimitRepo, 10,
+			)
+
+			batch, err := sut.Run(ctx, UsecaseInput{Urls: urls})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rateLimitRepo.rateLimit.Count != tt.wantCount {
+				t.Errorf("rate limit count = %d, want %d", rateLimitRepo.rateLimit.Count, tt.wantCount)
+			}
+			if tt.wantErr != nil {
+				if len(summaryRepo.created) != 0 || len(queue.messages) != 0 {
+					t.Errorf("tasks are created on error: %d summaries, %d messages", len(summaryRepo.created), len(queue.messages))
+				}
+				return
+			}
+			if len(batch.TaskIds) != 2 || len(batch.Rejected) != 2 {
+				t.Errorf("batch = %d tasks, %d rejected, want 2 tasks, 2 rejected", len(batch.TaskIds), len(batch.Rejected))
+			}
+		})
+	}
+}
+
+func Test_Usecase_Run_QueueBatchFailure(t *testing.T) {
+	urls := []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"}
+	tests := []struct {
+		name       string
+		failed     []int
+		queueErr   error
+		wantFailed map[int]bool
+	}{
+		{name: "all queued", wantFailed: map[int]bool{}},
+		{name: "partial failure", failed: []int{0, 2}, queueErr: errors.New("throttled"), wantFailed: map[int]bool{0: true, 2: true}},
+		// 送信できなかった位置が分からない場合は、すべてのタスクを失敗させる
+		{name: "request failure", queueErr: errors.New("unavailable"), wantFailed: map[int]bool{0: true, 1: true, 2: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), util.TokenSubContextKey{}, "user1")
+			summaryRepo := &fakeSummaryRepository{}
+			batchRepo := &fakeBatchRepository{}
+			queue := &fakeQueueClient{failed: tt.failed, err: tt.queueErr}
+			sut := NewUsecase(summaryRepo, batchRepo, nil, queue, &fakeURLPolicy{}, nil, 10)
+
+			batch, err := sut.Run(ctx, UsecaseInput{Urls: urls})
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if batchRepo.created == nil || len(batch.TaskIds) != len(urls) {
+				t.Fatalf("batch = %+v, want %d tasks", batch, len(urls))
+			}
+			for i, s := range summaryRepo.created {
+				if !tt.wantFailed[i] {
+					if s.TaskStatus != entities.TaskStatusQueued {
+						t.Errorf("task %d status = %v, want %v", i, s.TaskStatus, entities.TaskStatusQueued)
+					}
+					continue
+				}
+				change := summaryRepo.transitions[s.Id]
+				if s.TaskStatus != entities.TaskStatusFailed || change.Reason != string(entities.TaskFailureUpstreamUnavailable) {
+					t.Errorf("task %d status = %v, change = %+v, want failed by %s",
+						i, s.TaskStatus, change, entities.TaskFailureUpstreamUnavailable)
+				}
+			}
+		})
+	}
+}
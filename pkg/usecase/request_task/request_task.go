@@ -2,11 +2,15 @@ package request_task
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
 	"github.com/shoet/webpagesummary/pkg/util"
 )
 
@@ -14,36 +18,131 @@ type SummaryRepository interface {
 	CreateSummary(ctx context.Context, summary *entities.Summary) (string, error)
 }
 
+type PromptTemplateRepository interface {
+	GetPromptTemplate(ctx context.Context, userId string, name string) (*entities.PromptTemplate, error)
+}
+
 type QueueClient interface {
 	Queue(ctx context.Context, message string) error
 }
 
+// URLPolicyは要約するURLを正規化して、取得してよいURLかを検証する
+type URLPolicy interface {
+	Check(ctx context.Context, rawURL string) (*url.URL, error)
+}
+
+// UploadStorageはアップロードされた文書の内容を保存するオブジェクトストレージ
+type UploadStorage interface {
+	PutObject(ctx context.Context, key string, contentType string, body []byte) error
+}
+
+// ErrUnknownStyleは組み込みのスタイルにもユーザーのテンプレートにも存在しないスタイルが指定された場合のエラー
+var ErrUnknownStyle = errors.New("unknown style")
+
+// ErrMissingSourceはURLもアップロードされた文書も指定されていない場合のエラー
+var ErrMissingSource = errors.New("url or uploaded document is required")
+
+// ErrStyleWithStructuredは構造化出力とdefault以外のスタイルが同時に指定された場合のエラー
+var ErrStyleWithStructured = errors.New("style cannot be specified with structured output")
+
 type Usecase struct {
-	SummaryRepository SummaryRepository
-	QueueClient       QueueClient
+	SummaryRepository        SummaryRepository
+	PromptTemplateRepository PromptTemplateRepository
+	QueueClient              QueueClient
+	URLPolicy                URLPolicy
+	// UploadStorageがnilの場合は文書のアップロードを受け付けない
+	UploadStorage UploadStorage
 }
 
-func NewUsecase(summaryRepository SummaryRepository, queueClient QueueClient) *Usecase {
+func NewUsecase(
+	summaryRepository SummaryRepository,
+	promptTemplateRepository PromptTemplateRepository,
+	queueClient QueueClient,
+	urlPolicy URLPolicy,
+	uploadStorage UploadStorage,
+) *Usecase {
 	return &Usecase{
-		SummaryRepository: summaryRepository,
-		QueueClient:       queueClient,
+		SummaryRepository:        summaryRepository,
+		PromptTemplateRepository: promptTemplateRepository,
+		QueueClient:              queueClient,
+		URLPolicy:                urlPolicy,
+		UploadStorage:            uploadStorage,
 	}
 }
 
-func (u *Usecase) Run(ctx context.Context, url string) (taskID string, error error) {
+type UsecaseInput struct {
+	Url     string
+	Options *entities.SummaryOptions
+	// Styleは組み込みのスタイル名またはユーザーが定義したテンプレート名 (空の場合はdefault)
+	Style string
+	// Languageは要約を出力する言語 (空の場合はモデルに任せる)
+	Language string
+	// Structuredがtrueの場合は見出しや要点などを構造化したJSONで要約する
+	Structured bool
+	// PdfPagesはPDFの場合に要約するページ範囲 (例: "1-5,8")
+	PdfPages string
+	// Uploadはアップロードされた文書。指定された場合はUrlのページを取得せずにこの文書を要約する
+	// Urlは省略でき、指定された場合は文書の出典として記録する
+	Upload *entities.UploadedDocument
+	// Titleはアップロードされた文書のタイトル (空の場合は文書から抽出する)
+	Title string
+}
+
+func (u *Usecase) Run(ctx context.Context, input UsecaseInput) (taskID string, error error) {
 
 	userSub, err := util.GetUserSub(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get user sub: %w", err)
 	}
 
+	if input.Url == "" && input.Upload == nil {
+		return "", ErrMissingSource
+	}
+
+	pageUrl, err := u.pageUrl(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	if input.Structured && input.Style != "" && input.Style != entities.SummaryStyleDefault {
+		return "", ErrStyleWithStructured
+	}
+
+	pages, err := entities.ParsePageRanges(input.PdfPages)
+	if err != nil {
+		return "", err
+	}
+
+	if input.Style != "" && !entities.IsBuiltinSummaryStyle(input.Style) {
+		_, err := u.PromptTemplateRepository.GetPromptTemplate(ctx, userSub, input.Style)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return "", fmt.Errorf("%w: %s", ErrUnknownStyle, input.Style)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get prompt template: %w", err)
+		}
+	}
+
 	id := uuid.New().String()
+	now := time.Now()
 	newSummaryTask := &entities.Summary{
 		Id:         id,
-		PageUrl:    url,
-		TaskStatus: "request",
-		CreatedAt:  time.Now().Unix(),
+		PageUrl:    pageUrl,
+		CreatedAt:  now.Unix(),
 		UserId:     userSub,
+		Options:    input.Options,
+		Style:      input.Style,
+		Language:   input.Language,
+		Structured: input.Structured,
+		PdfPages:   pages.String(),
+		Upload:     input.Upload,
+	}
+	newSummaryTask.InitTaskStatus(now)
+	if input.Upload != nil {
+		if err := u.saveUpload(ctx, id, input.Upload); err != nil {
+			return "", err
+		}
+		newSummaryTask.Title = input.Title
 	}
 	_, err = u.SummaryRepository.CreateSummary(ctx, newSummaryTask)
 	if err != nil {
@@ -51,8 +150,46 @@ func (u *Usecase) Run(ctx context.Context, url string) (taskID string, error err
 	}
 
 	// queue taskId to sqs
-	if err := u.QueueClient.Queue(ctx, id); err != nil {
+	message, err := (&entities.TaskMessage{TaskId: id, Language: input.Language}).Marshal()
+	if err != nil {
+		return "", err
+	}
+	if err := u.QueueClient.Queue(ctx, message); err != nil {
 		return "", err
 	}
 	return id, nil
 }
+
+// saveUploadはアップロードされた文書の内容をオブジェクトストレージに保存する
+// DynamoDBの項目にはオブジェクトのキーと文書の形式のみを保存する
+func (u *Usecase) saveUpload(ctx context.Context, taskId string, upload *entities.UploadedDocument) error {
+	if u.UploadStorage == nil {
+		return fmt.Errorf("upload storage is not configured")
+	}
+	key := entities.UploadKey(taskId)
+	if err := u.UploadStorage.PutObject(ctx, key, upload.ContentType, upload.Data); err != nil {
+		return fmt.Errorf("failed to save uploaded document: %w", err)
+	}
+	upload.Key = key
+	return nil
+}
+
+// pageUrlは要約するURLを正規化して返す
+// アップロードされた文書のURLは出典として記録するのみで取得しないため、ポリシーでは検証しない
+func (u *Usecase) pageUrl(ctx context.Context, input UsecaseInput) (string, error) {
+	if input.Url == "" {
+		return "", nil
+	}
+	if input.Upload != nil {
+		normalized, err := urlpolicy.Normalize(input.Url)
+		if err != nil {
+			return "", err
+		}
+		return normalized.String(), nil
+	}
+	normalized, err := u.URLPolicy.Check(ctx, input.Url)
+	if err != nil {
+		return "", err
+	}
+	return normalized.String(), nil
+}
@@ -0,0 +1,100 @@
+package request_task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type fakeSummaryRepository struct {
+	created *entities.Summary
+}
+
+func (r *fakeSummaryRepository) CreateSummary(ctx context.Context, summary *entities.Summary) (string, error) {
+	r.created = summary
+	return summary.Id, nil
+}
+
+type fakeQueueClient struct {
+	messages []string
+}
+
+func (q *fakeQueueClient) Queue(ctx context.Context, message string) error {
+	q.messages = append(q.messages, message)
+	return nil
+}
+
+type fakeUploadStorage struct {
+	err     error
+	objects map[string][]byte
+}
+
+func (s *fakeUploadStorage) PutObject(ctx context.Context, key string, contentType string, body []byte) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.objects == nil {
+		s.objects = map[string][]byte{}
+	}
+	s.objects[key] = body
+	return nil
+}
+
+func Test_Usecase_Run_Upload(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage *fakeUploadStorage
+		wantErr bool
+	}{
+		{name: "saved", storage: &fakeUploadStorage{}},
+		// 文書を保存できない場合はタスクを登録しない
+		{name: "failed to save", storage: &fakeUploadStorage{err: errors.New("s3 is unavailable")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), util.TokenSubContextKey{}, "user1")
+			repo := &fakeSummaryRepository{}
+			queue := &fakeQueueClient{}
+			sut := NewUsecase(repo, nil, queue, nil, tt.storage)
+
+			upload, err := entities.NewUploadedDocument("memo.txt", "", []byte("社内メモ"))
+			if err != nil {
+				t.Fatalf("failed to create uploaded document: %v", err)
+			}
+			id, err := sut.Run(ctx, UsecaseInput{Upload: upload})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if repo.created != nil || len(queue.messages) != 0 {
+					t.Errorf("task should not be created")
+				}
+				return
+			}
+
+			key := entities.UploadKey(id)
+			if string(tt.storage.objects[key]) != "社内メモ" {
+				t.Errorf("document is not saved to %s: %v", key, tt.storage.objects)
+			}
+			item, err := attributevalue.MarshalMap(repo.created)
+			if err != nil {
+				t.Fatalf("failed to marshal summary: %v", err)
+			}
+			document, ok := item["uploaded_document"].(*types.AttributeValueMemberM)
+			if !ok {
+				t.Fatalf("uploaded document is not stored: %v", item)
+			}
+			if _, ok := document.Value["data"]; ok {
+				t.Errorf("document data should not be stored in the item")
+			}
+			if v, ok := document.Value["key"].(*types.AttributeValueMemberS); !ok || v.Value != key {
+				t.Errorf("key = %v, want %s", document.Value["key"], key)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package retry_task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/util"
+)
+
+type SummaryRepository interface {
+	GetSummary(ctx context.Context, id string, userId *string) (*entities.Summary, error)
+	TransitionTaskStatus(ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string) error
+}
+
+type QueueClient interface {
+	Queue(ctx context.Context, message string) error
+}
+
+// ErrNotFailedは失敗していないタスクの再実行を依頼された場合のエラー
+var ErrNotFailed = errors.New("task is not failed")
+
+// retryRequestedReasonは再実行を依頼された場合に状態の履歴に記録する理由
+const retryRequestedReason = "retry_requested"
+
+type Usecase struct {
+	SummaryRepository SummaryRepository
+	QueueClient       QueueClient
+}
+
+func NewUsecase(summaryRepository SummaryRepository, queueClient QueueClient) *Usecase {
+	return &Usecase{
+		SummaryRepository: summaryRepository,
+		QueueClient:       queueClient,
+	}
+}
+
+// Runは失敗したタスクを再実行を待つ状態にし、キューに登録し直す
+// 実行回数は数え直すため、一時的なエラーの場合は再び自動で再実行される
+func (u *Usecase) Run(ctx context.Context, taskId string) (*entities.Summary, error) {
+	var userIdPtr *string
+	userId, err := util.GetUserSub(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed GetUserSub: %w", err)
+	}
+	if userId != util.APIKeyUserSub {
+		userIdPtr = &userId
+	}
+	summary, err := u.SummaryRepository.GetSummary(ctx, taskId, userIdPtr)
+	if err != nil {
+		return nil, fmt.Errorf("failed get summary: %w", err)
+	}
+	if summary.TaskStatus != entities.TaskStatusFailed {
+		return nil, fmt.Errorf("%w: status is %s", ErrNotFailed, summary.TaskStatus)
+	}
+	failedReason := summary.TaskFailedReason
+	if err := u.SummaryRepository.TransitionTaskStatus(
+		ctx, summary, entities.TaskStatusRetrying, retryRequestedReason,
+	); err != nil {
+		return nil, fmt.Errorf("failed transition task status: %w", err)
+	}
+
+	message, err := (&entities.TaskMessage{TaskId: summary.Id, Language: summary.Language}).Marshal()
+	if err == nil {
+		err = u.QueueClient.Queue(ctx, message)
+	}
+	if err != nil {
+		// キューに登録できない場合は再び再実行を依頼できるように失敗した状態に戻す
+		if rollbackErr := u.SummaryRepository.TransitionTaskStatus(
+			ctx, summary, entities.TaskStatusFailed, failedReason,
+		); rollbackErr != nil {
+			return nil, fmt.Errorf("failed queue task: %w", errors.Join(err, rollbackErr))
+		}
+		return nil, fmt.Errorf("failed queue task: %w", err)
+	}
+	return summary, nil
+}
@@ -7,6 +7,7 @@ import (
 
 type TokenSubContextKey struct{}
 type HasAPIKeyContextKey struct{}
+type RateLimitedContextKey struct{}
 
 const APIKeyUserSub = "apikey"
 
@@ -23,3 +24,10 @@ func GetUserSub(ctx context.Context) (string, error) {
 
 	return userSub, nil
 }
+
+// IsRateLimitedはリクエスト回数制限の対象のリクエストかを返す
+// AuthRateLimitMiddlewareでリクエスト回数を加算した場合にtrueとなる
+func IsRateLimited(ctx context.Context) bool {
+	rateLimited, _ := ctx.Value(RateLimitedContextKey{}).(bool)
+	return rateLimited
+}
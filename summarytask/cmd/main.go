@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -15,14 +17,20 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/joho/godotenv"
 	cp "github.com/otiai10/copy"
-	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
 	"github.com/shoet/web-page-summarizer-task/pkg/crawler"
+	"github.com/shoet/web-page-summarizer-task/pkg/document"
+	"github.com/shoet/web-page-summarizer-task/pkg/politeness"
+	scraper "github.com/shoet/web-page-summarizer-task/pkg/scrapper"
+	"github.com/shoet/web-page-summarizer-task/pkg/snapshot"
+	"github.com/shoet/web-page-summarizer-task/pkg/source"
+	"github.com/shoet/web-page-summarizer-task/pkg/summarizer"
 	"github.com/shoet/web-page-summarizer-task/pkg/task"
 	"github.com/shoet/webpagesummary/pkg/config"
 	"github.com/shoet/webpagesummary/pkg/infrastracture/adapter"
 	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
 	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
 	"github.com/shoet/webpagesummary/pkg/logging"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
 )
 
 func FailTask(traceId string, err error) {
@@ -44,10 +52,24 @@ func CopyBrowser() (string, error) {
 }
 
 type TaskExecutor struct {
-	config            *config.Config
-	logger            *logging.Logger
-	queue             *adapter.QueueClient
-	summaryRepository *repository.SummaryRepository
+	config             *config.Config
+	logger             *logging.Logger
+	queue              *adapter.QueueClient
+	summaryRepository  *repository.SummaryRepository
+	templateRepository *repository.PromptTemplateRepository
+	// deadLetterQueueは再実行しても成功しないタスクのメッセージを移すキュー。DEAD_LETTER_QUEUE_URLが未指定の場合はnil
+	deadLetterQueue *adapter.QueueClient
+	// scraperRulesはサイトごとの抽出ルール。SCRAPER_RULES_PATHが未指定の場合はnil
+	scraperRules *scraper.RuleSet
+	// robotsとdomainLimiterはキャッシュや枠の所有者をタスクをまたいで利用する。無効な場合はnil
+	robots        *politeness.RobotsCache
+	domainLimiter *politeness.DomainLimiter
+	// urlPolicyは取得するURLと接続先のIPアドレスを検証する
+	urlPolicy *urlpolicy.Policy
+	// snapshotsは取得したページのスナップショットを保存する。SNAPSHOT_BUCKETが未指定の場合はnil
+	snapshots *snapshot.Archiver
+	// uploadsはアップロードされた文書を読み込む。SNAPSHOT_BUCKETが未指定の場合はnil
+	uploads *adapter.ObjectStorage
 }
 
 func NewTaskExecutor(ctx context.Context, cfg *config.Config) (*TaskExecutor, error) {
@@ -57,16 +79,80 @@ func NewTaskExecutor(ctx context.Context, cfg *config.Config) (*TaskExecutor, er
 		return nil, fmt.Errorf("failed to load aws config: %w", err)
 	}
 	queueClient := adapter.NewQueueClient(awsCfg, cfg.QueueUrl)
+	var deadLetterQueue *adapter.QueueClient
+	if cfg.DeadLetterQueueUrl != "" {
+		deadLetterQueue = adapter.NewQueueClient(awsCfg, cfg.DeadLetterQueueUrl)
+	}
 	db := dynamodb.NewFromConfig(awsCfg)
 	summaryRepository := repository.NewSummaryRepository(db, &cfg.Env)
+	templateRepository := repository.NewPromptTemplateRepository(db, &cfg.Env)
+	var scraperRules *scraper.RuleSet
+	if cfg.ScraperRulesPath != "" {
+		rulesSource, err := scraper.NewRulesSource(cfg.ScraperRulesPath, func(bucket string) scraper.ObjectStorage {
+			return adapter.NewObjectStorage(awsCfg, bucket, cfg.SnapshotUsePathStyle)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scraper rules: %w", err)
+		}
+		// ルールファイルの更新を反映するため、RuleSetはタスクをまたいで利用する
+		scraperRules, err = scraper.NewRuleSet(ctx, rulesSource, scraper.DefaultRegistry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scraper rules: %w", err)
+		}
+	}
+	urlPolicy := cfg.NewURLPolicy()
+	var robots *politeness.RobotsCache
+	if cfg.RobotsEnabled {
+		robots = politeness.NewRobotsCache(
+			urlPolicy.HTTPClient(time.Duration(cfg.StaticFetchTimeoutSec)*time.Second),
+			crawlerUserAgent(cfg),
+			time.Duration(cfg.RobotsCacheTTLSec)*time.Second,
+		)
+	}
+	var domainLimiter *politeness.DomainLimiter
+	if cfg.CrawlThrottleEnabled {
+		domainLimiter, err = politeness.NewDomainLimiter(
+			repository.NewCrawlThrottleRepository(db, &cfg.Env),
+			&politeness.DomainLimiterConfig{
+				MaxInFlight: cfg.CrawlMaxInFlightPerDomain,
+				MaxWait:     time.Duration(cfg.CrawlThrottleMaxWaitSec) * time.Second,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize domain limiter: %w", err)
+		}
+	}
+	var snapshots *snapshot.Archiver
+	var uploads *adapter.ObjectStorage
+	if cfg.SnapshotBucket != "" {
+		// アップロードされた文書はスナップショットと同じバケットに保存されている
+		uploads = adapter.NewObjectStorage(awsCfg, cfg.SnapshotBucket, cfg.SnapshotUsePathStyle)
+		snapshots = snapshot.NewArchiver(uploads)
+	}
 	return &TaskExecutor{
-		config:            cfg,
-		logger:            logger,
-		queue:             queueClient,
-		summaryRepository: summaryRepository,
+		config:             cfg,
+		logger:             logger,
+		queue:              queueClient,
+		deadLetterQueue:    deadLetterQueue,
+		summaryRepository:  summaryRepository,
+		templateRepository: templateRepository,
+		scraperRules:       scraperRules,
+		robots:             robots,
+		domainLimiter:      domainLimiter,
+		urlPolicy:          urlPolicy,
+		snapshots:          snapshots,
+		uploads:            uploads,
 	}, nil
 }
 
+// crawlerUserAgentはページやrobots.txtの取得に利用するUser-Agentを返す
+func crawlerUserAgent(cfg *config.Config) string {
+	if cfg.CrawlerUserAgent != "" {
+		return cfg.CrawlerUserAgent
+	}
+	return crawler.DefaultUserAgent
+}
+
 func (t *TaskExecutor) FetchTaskId(ctx context.Context, maxExecute int) ([]string, error) {
 	var tasks []string
 	for i := 0; i < maxExecute; i++ {
@@ -84,58 +170,167 @@ func (t *TaskExecutor) FetchTaskId(ctx context.Context, maxExecute int) ([]strin
 
 type RunTaskInput struct {
 	TaskId           string
+	Language         string
 	SQSReceiptHandle string
+	// SQSReceiveCountはメッセージの受信回数(ApproximateReceiveCount)。ローカルでの実行時は0
+	SQSReceiveCount int
 }
 
-func (t *TaskExecutor) RunTask(ctx context.Context, input *RunTaskInput) error {
+// NewRunTaskInputはSQSのメッセージ本文からRunTaskInputを生成する
+func NewRunTaskInput(body string, receiptHandle string, receiveCount int) (*RunTaskInput, error) {
+	message, err := entities.ParseTaskMessage(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse task message: %w", err)
+	}
+	return &RunTaskInput{
+		TaskId:           message.TaskId,
+		Language:         message.Language,
+		SQSReceiptHandle: receiptHandle,
+		SQSReceiveCount:  receiveCount,
+	}, nil
+}
+
+// launchBrowserはPlaywrightでブラウザを起動し、ブラウザでページを取得するクローラーを返す
+func (t *TaskExecutor) launchBrowser() (crawler.ContentFetcher, func() error, error) {
 	playwrightConfig := &crawler.PlaywrightClientConfig{
 		BrowserLaunchTimeoutSec: 120,
 		SkipInstallBrowsers:     false,
+		UserAgent:               crawlerUserAgent(t.config),
+		URLPolicy:               t.urlPolicy,
+		BlockedResourceTypes:    t.config.BrowserBlockedResourceTypes,
+		BlockedDomains:          t.config.BrowserBlockedDomains,
+		WaitUntil:               t.config.BrowserWaitUntil,
+		DomainWaitUntil:         t.config.BrowserDomainWaitUntil,
+		NavigationTimeout:       time.Duration(t.config.BrowserNavigationTimeoutSec) * time.Second,
+		DismissConsent:          t.config.BrowserDismissConsent,
+		CaptureScreenshot:       t.config.SnapshotScreenshot,
+		CaptureMHTML:            t.config.SnapshotMHTML,
+	}
+	if t.config.BrowserBlockAdDomains {
+		playwrightConfig.BlockedDomains = append(append([]string{}, crawler.DefaultBlockedDomains...), t.config.BrowserBlockedDomains...)
+	}
+	if t.scraperRules != nil {
+		playwrightConfig.Scrapers = t.scraperRules
 	}
 	if runtime.GOOS == "linux" {
 		// Lambdaでの実行時は/varに用意したブラウザを/tmpにコピーする
 		if _, err := CopyBrowser(); err != nil {
-			t.logger.Fatal("failed to copy browser", err)
+			return nil, nil, fmt.Errorf("failed to copy browser: %w", err)
 		}
 		// Lambdaでの実行時はブラウザのインストールをスキップする
 		playwrightConfig.SkipInstallBrowsers = true
 	} else {
 		os.Setenv("PLAYWRIGHT_BROWSERS_PATH", t.config.BrowserDownloadPath)
 	}
+	// ブラウザの接続は接続先のIPアドレスを検証するプロキシを経由させる
+	proxy, err := t.urlPolicy.StartProxy()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start proxy: %w", err)
+	}
+	playwrightConfig.ProxyServer = proxy.URL()
 	pageCrawler, browserCloser, err := crawler.NewPlaywrightClient(playwrightConfig)
-	defer browserCloser()
 	if err != nil {
-		return fmt.Errorf("failed to initialize playwright client: %w", err)
+		proxy.Close()
+		return nil, nil, fmt.Errorf("failed to initialize playwright client: %w", err)
+	}
+	closer := func() error {
+		err := browserCloser()
+		if closeErr := proxy.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	return pageCrawler, closer, nil
+}
+
+func (t *TaskExecutor) RunTask(ctx context.Context, input *RunTaskInput) error {
+	var siteRules crawler.SiteRules = scraper.DefaultRegistry
+	if t.scraperRules != nil {
+		siteRules = t.scraperRules
 	}
+	// リダイレクト先と接続先のIPアドレスもポリシーで検証する
+	fetchClient := t.urlPolicy.HTTPClient(time.Duration(t.config.StaticFetchTimeoutSec) * time.Second)
+	var staticCrawler crawler.ContentFetcher
+	if t.config.StaticFetchEnabled {
+		// サーバーで描画されたページはブラウザを起動せずに取得する
+		staticCrawler = crawler.NewHTTPCrawler(
+			fetchClient,
+			&crawler.HTTPCrawlerConfig{
+				UserAgent:        crawlerUserAgent(t.config),
+				MinContentLength: t.config.StaticFetchMinContentLength,
+				PDFMaxPages:      t.config.PDFMaxPages,
+			},
+		)
+	}
+	pageCrawler := crawler.NewFallbackCrawler(staticCrawler, siteRules, t.launchBrowser)
+	defer func() {
+		if err := pageCrawler.Close(); err != nil {
+			t.logger.Error("failed to close browser", err)
+		}
+	}()
+	// Webページはrobots.txtとドメインごとの取得の間隔、同時取得数の制限に従って取得する
+	politeCrawler := politeness.NewPoliteCrawler(pageCrawler, &politeness.PoliteCrawlerConfig{
+		Robots:        t.robots,
+		RobotsAgent:   t.config.CrawlerRobotsAgent,
+		Limiter:       t.domainLimiter,
+		MinDelay:      time.Duration(t.config.CrawlMinDelayMs) * time.Millisecond,
+		MaxCrawlDelay: time.Duration(t.config.CrawlMaxCrawlDelaySec) * time.Second,
+	})
+	// 動画や字幕ファイルのURLはWebページとしてではなく字幕を本文として取得する
+	contentsFetcher := crawler.NewPolicyCrawler(
+		source.NewRouter(
+			politeCrawler,
+			source.NewYouTubeSource(fetchClient),
+			source.NewTranscriptSource(fetchClient),
+		),
+		t.urlPolicy,
+	)
 
 	client := &http.Client{}
-	chatgptService, err := chatgpt.NewChatGPTService(t.config.OpenAIApiKey, client)
+	summarizerService, err := summarizer.NewSummarizer(t.config, client)
 	if err != nil {
-		t.logger.Fatal("failed to initialize chatgpt service", err)
+		t.logger.Fatal("failed to initialize summarizer", err)
 	}
 
-	tasker := task.NewSummaryTask(t.summaryRepository, pageCrawler, chatgptService)
+	taskConfig := &task.SummaryTaskConfig{
+		Stream:              t.config.LLMStream,
+		StreamFlushInterval: time.Duration(t.config.LLMStreamFlushIntervalMs) * time.Millisecond,
+		TokenBudgets:        t.config.LLMTokenBudgets,
+		RetryPolicy: task.RetryPolicy{
+			MaxAttempts:     t.config.TaskMaxAttempts,
+			BaseDelay:       time.Duration(t.config.TaskRetryBaseDelaySec) * time.Second,
+			MaxDelay:        time.Duration(t.config.TaskRetryMaxDelaySec) * time.Second,
+			MaxReceiveCount: t.config.TaskMaxReceiveCount,
+		},
+		CancelPollInterval: time.Duration(t.config.TaskCancelPollIntervalSec) * time.Second,
+	}
+	if t.snapshots != nil {
+		taskConfig.Snapshots = t.snapshots
+	}
+	if t.uploads != nil {
+		taskConfig.Uploads = t.uploads
+	}
+	tasker := task.NewSummaryTask(
+		t.summaryRepository,
+		t.templateRepository,
+		contentsFetcher,
+		document.NewReader(&document.ReaderConfig{PDFMaxPages: t.config.PDFMaxPages}),
+		summarizerService,
+		taskConfig,
+	)
 
 	traceIdLogger := t.logger.NewTraceIdLogger(input.TaskId)
 	ctx = logging.SetLogger(ctx, traceIdLogger)
-	if err := tasker.ExecuteSummaryTask(ctx, input.TaskId); err != nil {
-		traceIdLogger.Error("failed to execute task", err)
-		// タスク失敗時はqueueから削除する
-		input := &RunTaskInput{
-			TaskId:           input.TaskId,
-			SQSReceiptHandle: input.SQSReceiptHandle,
-		}
-		if err := t.queue.DeleteMessage(ctx, input.SQSReceiptHandle); err != nil {
-			traceIdLogger.Error("failed to delete queue", err)
-		}
-		// タスク失敗時はsummaryのstatusをfailedにする
-		if err := t.summaryRepository.UpdateSummary(context.Background(), &entities.Summary{
-			Id:               input.TaskId,
-			TaskStatus:       "failed",
-			TaskFailedReason: err.Error(),
-		}); err != nil {
-			traceIdLogger.Error("failed to update summary", err)
+	if t.scraperRules != nil {
+		if err := t.scraperRules.Err(); err != nil {
+			// 読み込みに失敗した場合は直前のルールで抽出を続ける
+			traceIdLogger.Error("failed to reload scraper rules", err)
 		}
+	}
+	message := &entities.TaskMessage{TaskId: input.TaskId, Language: input.Language}
+	if err := tasker.ExecuteSummaryTask(ctx, message); err != nil {
+		traceIdLogger.Error("failed to execute task", err)
+		t.handleFailure(tasker, traceIdLogger, input, err)
 		return fmt.Errorf("failed to execute task: %w", err)
 	}
 	traceIdLogger.Info("task is complete")
@@ -143,6 +338,47 @@ func (t *TaskExecutor) RunTask(ctx context.Context, input *RunTaskInput) error {
 
 }
 
+// handleFailureは失敗したタスクの状態を更新し、メッセージを再実行まで待機させるか、
+// デッドレターキューに移すか、削除する
+// 再実行する場合はメッセージを削除せずにHandlerがエラーを返すため、可視性タイムアウトの後に再び配信される
+func (t *TaskExecutor) handleFailure(
+	tasker *task.SummaryTask, logger *logging.Logger, input *RunTaskInput, cause error,
+) {
+	ctx := logging.SetLogger(context.Background(), logger)
+	decision, err := tasker.FailTask(ctx, input.TaskId, input.SQSReceiveCount, cause)
+	if err != nil {
+		// 状態を更新できない場合もメッセージは可視性タイムアウトの後に再び配信される
+		logger.Error("failed to update summary", err)
+		return
+	}
+	if input.SQSReceiptHandle == "" {
+		// ローカルでの実行時は受信時にメッセージを削除している
+		return
+	}
+	if decision.Retry {
+		logger.Info(fmt.Sprintf("retry task in %s: attempt=%d, failureCode=%s", decision.Delay, decision.Attempt, decision.Code))
+		if err := t.queue.ChangeMessageVisibility(ctx, input.SQSReceiptHandle, decision.Delay); err != nil {
+			logger.Error("failed to change message visibility", err)
+		}
+		return
+	}
+	if decision.DeadLetter && t.deadLetterQueue != nil {
+		message, err := (&entities.TaskMessage{TaskId: input.TaskId, Language: input.Language}).Marshal()
+		if err != nil {
+			logger.Error("failed to marshal task message", err)
+			return
+		}
+		if err := t.deadLetterQueue.Queue(ctx, message); err != nil {
+			// デッドレターキューに移せない場合はメッセージを残し、SQSのリドライブポリシーに任せる
+			logger.Error("failed to send message to dead letter queue", err)
+			return
+		}
+	}
+	if err := t.queue.DeleteMessage(ctx, input.SQSReceiptHandle); err != nil {
+		logger.Error("failed to delete queue", err)
+	}
+}
+
 var executor *TaskExecutor
 
 func init() {
@@ -161,11 +397,14 @@ func init() {
 
 func Handler(ctx context.Context, sqsEvent events.SQSEvent) error {
 	fmt.Println("start handler")
-	taskId := sqsEvent.Records[0].Body
-	sqsReceiptHandle := sqsEvent.Records[0].ReceiptHandle
-	input := &RunTaskInput{
-		TaskId:           taskId,
-		SQSReceiptHandle: sqsReceiptHandle,
+	record := sqsEvent.Records[0]
+	receiveCount, err := strconv.Atoi(record.Attributes["ApproximateReceiveCount"])
+	if err != nil {
+		return fmt.Errorf("failed to parse receive count: %w", err)
+	}
+	input, err := NewRunTaskInput(record.Body, record.ReceiptHandle, receiveCount)
+	if err != nil {
+		return err
 	}
 	if err := executor.RunTask(ctx, input); err != nil {
 		return fmt.Errorf("failed to execute task: %w", err)
@@ -186,9 +425,9 @@ func main() {
 			return
 		}
 
-		input := &RunTaskInput{
-			TaskId:           tasks[0],
-			SQSReceiptHandle: "", // TODO
+		input, err := NewRunTaskInput(tasks[0], "", 0) // TODO: SQSReceiptHandle
+		if err != nil {
+			log.Fatalf("failed to parse task: %v", err)
 		}
 		if err := executor.RunTask(ctx, input); err != nil {
 			log.Fatalf("failed to run task: %v", err)
@@ -0,0 +1,238 @@
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+)
+
+const (
+	AnthropicBaseUrl = "https://api.anthropic.com"
+	APIVersion       = "2023-06-01"
+	DefaultModel     = "claude-3-haiku-20240307"
+	DefaultMaxTokens = 1024
+	messagesPath     = "/v1/messages"
+)
+
+// AnthropicServiceはAnthropicのMessages APIを呼び出すサービス
+// SeedとOrganizationはMessages APIに対応する項目がないため無視する
+type AnthropicService struct {
+	apiKey      string
+	client      chatgpt.Client
+	options     chatgpt.ChatCompletionsOptions
+	retryPolicy chatgpt.RetryPolicy
+}
+
+func NewAnthropicService(
+	apiKey string, client *http.Client, options *chatgpt.ChatCompletionsOptions,
+) (*AnthropicService, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("api key is empty")
+	}
+	maxTokens := DefaultMaxTokens
+	defaultOptions := chatgpt.ChatCompletionsOptions{
+		Model:     DefaultModel,
+		BaseUrl:   AnthropicBaseUrl,
+		MaxTokens: &maxTokens, // Messages APIではmax_tokensが必須
+	}
+	return &AnthropicService{
+		apiKey:      apiKey,
+		client:      client,
+		options:     defaultOptions.Merge(options),
+		retryPolicy: chatgpt.DefaultRetryPolicy,
+	}, nil
+}
+
+// SetRetryPolicyは一時的なエラーが発生した場合のリトライ方法を設定する
+func (a *AnthropicService) SetRetryPolicy(policy chatgpt.RetryPolicy) {
+	a.retryPolicy = policy
+}
+
+// Modelはリクエストで利用するデフォルトのモデル名を返す
+func (a *AnthropicService) Model() string {
+	return a.options.Model
+}
+
+type MessagesRequest struct {
+	Model       string                   `json:"model"`
+	MaxTokens   int                      `json:"max_tokens"`
+	Messages    []MessagesRequestMessage `json:"messages"`
+	Stream      bool                     `json:"stream,omitempty"`
+	Temperature *float64                 `json:"temperature,omitempty"`
+	TopP        *float64                 `json:"top_p,omitempty"`
+	Tools       []MessagesTool           `json:"tools,omitempty"`
+	ToolChoice  *MessagesToolChoice      `json:"tool_choice,omitempty"`
+}
+
+type MessagesTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// MessagesToolChoiceはモデルに呼び出させるツールを指定する
+type MessagesToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type MessagesRequestMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type MessagesResponse struct {
+	ID            string                 `json:"id"`
+	Type          string                 `json:"type"`
+	Role          string                 `json:"role"`
+	Model         string                 `json:"model"`
+	Content       []MessagesContentBlock `json:"content"`
+	StopReason    string                 `json:"stop_reason"`
+	Usage         *MessagesUsage         `json:"usage"`
+	ErrorResponse MessagesErrorResponse  `json:"error"`
+}
+
+type MessagesUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ToUsageはMessages APIの使用量をChat Completionsの形式に変換する
+func (u *MessagesUsage) ToUsage() *chatgpt.Usage {
+	if u == nil {
+		return nil
+	}
+	return &chatgpt.Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
+type MessagesContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	// NameとInputはtool_useのブロックで返却される
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type MessagesErrorResponse struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (a *AnthropicService) ChatCompletions(
+	ctx context.Context, input *chatgpt.ChatCompletionsInput,
+) (*chatgpt.ChatCompletionsOutput, error) {
+	resp, err := a.do(ctx, input, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var responseBody MessagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	if responseBody.ErrorResponse != (MessagesErrorResponse{}) {
+		e := responseBody.ErrorResponse
+		return nil, chatgpt.NewAPIError(resp.StatusCode, resp.Header, "", e.Type, e.Message)
+	}
+	textBuilder := strings.Builder{}
+	for _, c := range responseBody.Content {
+		if c.Type == "text" {
+			textBuilder.WriteString(c.Text)
+		}
+	}
+	if input.Tool != nil {
+		for _, c := range responseBody.Content {
+			if c.Type == "tool_use" && c.Name == input.Tool.Name {
+				textBuilder.Reset()
+				textBuilder.Write(c.Input)
+				break
+			}
+		}
+	}
+	if textBuilder.Len() == 0 {
+		return nil, fmt.Errorf("failed to get response")
+	}
+	options := a.options.Merge(input.Options)
+	return chatgpt.NewChatCompletionsOutput(
+		options.Model, input.Text, responseBody.Model, textBuilder.String(), responseBody.Usage.ToUsage()), nil
+}
+
+// doはリクエストを送信し、ステータスが200以外の場合はAPIErrorを返す
+// レート制限や過負荷など一時的なエラーの場合はリトライする
+func (a *AnthropicService) do(
+	ctx context.Context, input *chatgpt.ChatCompletionsInput, stream bool,
+) (*http.Response, error) {
+	return chatgpt.Retry(ctx, a.retryPolicy, func() (*http.Response, error) {
+		req, err := a.newRequest(ctx, input, stream)
+		if err != nil {
+			return nil, err
+		}
+		if stream {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		resp, err := a.client.Do(req)
+		if err != nil {
+			return nil, chatgpt.WrapRequestError(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			var responseBody MessagesResponse
+			_ = json.NewDecoder(resp.Body).Decode(&responseBody)
+			e := responseBody.ErrorResponse
+			return nil, chatgpt.NewAPIError(resp.StatusCode, resp.Header, "", e.Type, e.Message)
+		}
+		return resp, nil
+	})
+}
+
+func (a *AnthropicService) newRequest(
+	ctx context.Context, input *chatgpt.ChatCompletionsInput, stream bool,
+) (*http.Request, error) {
+	if input.Text == "" {
+		return nil, fmt.Errorf("input text is empty")
+	}
+	options := a.options.Merge(input.Options)
+	requestBody := MessagesRequest{
+		Model:     options.Model,
+		MaxTokens: *options.MaxTokens,
+		Messages: []MessagesRequestMessage{
+			{Role: "user", Content: input.Text},
+		},
+		Stream:      stream,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+	}
+	if input.Tool != nil {
+		requestBody.Tools = []MessagesTool{
+			{
+				Name:        input.Tool.Name,
+				Description: input.Tool.Description,
+				InputSchema: input.Tool.Parameters,
+			},
+		}
+		requestBody.ToolChoice = &MessagesToolChoice{Type: "tool", Name: input.Tool.Name}
+	}
+	b, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, strings.TrimRight(options.BaseUrl, "/")+messagesPath, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", APIVersion)
+	return req, nil
+}
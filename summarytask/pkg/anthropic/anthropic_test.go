@@ -0,0 +1,91 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+)
+
+func Test_AnthropicService_ChatCompletions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test_key" {
+			t.Errorf("x-api-key is not expected: %v", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") != APIVersion {
+			t.Errorf("anthropic-version is not expected: %v", r.Header.Get("anthropic-version"))
+		}
+		if r.URL.Path != messagesPath {
+			t.Errorf("path is not expected: %v", r.URL.Path)
+		}
+		var body MessagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		if body.Model != DefaultModel {
+			t.Errorf("model is not expected: %v", body.Model)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-haiku-20240307","content":[{"type":"text","text":"要約"}],"usage":{"input_tokens":10,"output_tokens":3}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	sut, err := NewAnthropicService(
+		"test_key", server.Client(), &chatgpt.ChatCompletionsOptions{BaseUrl: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create anthropic service: %v", err)
+	}
+
+	got, err := sut.ChatCompletions(context.Background(), &chatgpt.ChatCompletionsInput{Text: "こんにちは"})
+	if err != nil {
+		t.Fatalf("failed to get completion: %v", err)
+	}
+	want := &chatgpt.ChatCompletionsOutput{
+		Text:  "要約",
+		Model: DefaultModel,
+		Usage: chatgpt.Usage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_AnthropicService_ChatCompletions_Tool(t *testing.T) {
+	var gotRequest MessagesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotRequest); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-haiku-20240307","content":[{"type":"tool_use","id":"toolu_1","name":"save_summary","input":{"headline":"見出し"}}],"usage":{"input_tokens":10,"output_tokens":3}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	sut, err := NewAnthropicService(
+		"test_key", server.Client(), &chatgpt.ChatCompletionsOptions{BaseUrl: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create anthropic service: %v", err)
+	}
+
+	got, err := sut.ChatCompletions(context.Background(), &chatgpt.ChatCompletionsInput{
+		Text: "こんにちは",
+		Tool: chatgpt.StructuredSummaryTool,
+	})
+	if err != nil {
+		t.Fatalf("failed to get completion: %v", err)
+	}
+	if got.Text != `{"headline":"見出し"}` {
+		t.Errorf("text is not expected: %v", got.Text)
+	}
+	if len(gotRequest.Tools) != 1 || gotRequest.Tools[0].Name != chatgpt.StructuredSummaryTool.Name {
+		t.Errorf("tools is not expected: %v", gotRequest.Tools)
+	}
+	want := &MessagesToolChoice{Type: "tool", Name: chatgpt.StructuredSummaryTool.Name}
+	if diff := cmp.Diff(want, gotRequest.ToolChoice); diff != "" {
+		t.Errorf("tool_choice mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,86 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+)
+
+// MessagesStreamEventはMessages APIのストリーミングで受信するイベント
+type MessagesStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	// Messageはmessage_startで返却されるメッセージ (入力トークン数を含む)
+	Message *MessagesResponse `json:"message"`
+	// Usageはmessage_deltaで返却される累計の出力トークン数
+	Usage         *MessagesUsage        `json:"usage"`
+	ErrorResponse MessagesErrorResponse `json:"error"`
+}
+
+// ChatCompletionsStreamはストリーミングモードでMessages APIを呼び出す
+// 差分を受信するたびにonDeltaを呼び出し、最終的に結合したテキストを返す
+func (a *AnthropicService) ChatCompletionsStream(
+	ctx context.Context, input *chatgpt.ChatCompletionsInput, onDelta chatgpt.StreamDeltaFunc,
+) (*chatgpt.ChatCompletionsOutput, error) {
+	if input.Tool != nil {
+		return nil, fmt.Errorf("tool is not supported in streaming")
+	}
+	// ストリームの受信開始後は差分を通知済みのためリトライしない
+	resp, err := a.do(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	contentBuilder := strings.Builder{}
+	var model string
+	var usage *MessagesUsage
+	err = chatgpt.ReadServerSentEvents(resp.Body, func(event *chatgpt.ServerSentEvent) error {
+		var e MessagesStreamEvent
+		if err := json.Unmarshal([]byte(event.Data), &e); err != nil {
+			return fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		switch e.Type {
+		case "message_start":
+			if e.Message != nil {
+				model = e.Message.Model
+				usage = e.Message.Usage
+			}
+		case "message_delta":
+			if e.Usage != nil && usage != nil {
+				usage.OutputTokens = e.Usage.OutputTokens
+			}
+		case "message_stop":
+			return io.EOF
+		case "error":
+			return chatgpt.NewAPIError(resp.StatusCode, nil, "", e.ErrorResponse.Type, e.ErrorResponse.Message)
+		case "content_block_delta":
+			if e.Delta.Text == "" {
+				return nil
+			}
+			contentBuilder.WriteString(e.Delta.Text)
+			if onDelta != nil {
+				if err := onDelta(e.Delta.Text); err != nil {
+					return fmt.Errorf("failed to handle delta: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if contentBuilder.Len() == 0 {
+		return nil, fmt.Errorf("failed to get response")
+	}
+	options := a.options.Merge(input.Options)
+	return chatgpt.NewChatCompletionsOutput(
+		options.Model, input.Text, model, contentBuilder.String(), usage.ToUsage()), nil
+}
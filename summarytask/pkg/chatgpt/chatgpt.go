@@ -2,34 +2,190 @@ package chatgpt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	OpenAIBaseUrl  = "https://api.openai.com/v1"
+	DefaultModel   = "gpt-4"
+	completionPath = "/chat/completions"
 )
 
 type Client interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// AuthHeaderFuncはプロバイダーごとの認証ヘッダーをリクエストに設定する関数
+type AuthHeaderFunc func(req *http.Request, apiKey string)
+
+func BearerAuthHeader(req *http.Request, apiKey string) {
+	if apiKey == "" {
+		return
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+}
+
+func AzureAuthHeader(req *http.Request, apiKey string) {
+	req.Header.Set("api-key", apiKey)
+}
+
+// ChatGPTServiceはOpenAIのChat Completions APIと互換のあるAPIを呼び出すサービス
+// OpenAI, Azure OpenAI, OpenAI互換API(Ollama/vLLMなど)で共通して利用する
 type ChatGPTService struct {
-	apiKey string
-	client Client
+	apiKey       string
+	client       Client
+	endpointPath string
+	options      ChatCompletionsOptions
+	authHeader   AuthHeaderFunc
+	retryPolicy  RetryPolicy
+	// streamUsageはストリーミング時にstream_optionsで使用量の返却を要求するか
+	// Azure OpenAIやOpenAI互換APIでは未対応のバージョンがあるため、OpenAIのみ有効にする
+	streamUsage bool
 }
 
-func NewChatGPTService(apiKey string, client *http.Client) (*ChatGPTService, error) {
+// NewChatGPTServiceはOpenAIのAPIを呼び出すChatGPTServiceを生成する
+// optionsで指定されていない項目はOpenAIのデフォルト値を利用する
+func NewChatGPTService(
+	apiKey string, client *http.Client, options *ChatCompletionsOptions,
+) (*ChatGPTService, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("api key is empty")
 	}
+	defaultOptions := ChatCompletionsOptions{
+		Model:   DefaultModel,
+		BaseUrl: OpenAIBaseUrl,
+	}
 	return &ChatGPTService{
-		apiKey: apiKey,
-		client: client,
+		apiKey:       apiKey,
+		client:       client,
+		endpointPath: completionPath,
+		options:      defaultOptions.Merge(options),
+		authHeader:   BearerAuthHeader,
+		retryPolicy:  DefaultRetryPolicy,
+		streamUsage:  true,
 	}, nil
 }
 
+// NewAzureOpenAIServiceはAzure OpenAIのデプロイメントを呼び出すChatGPTServiceを生成する
+// endpointには https://{resource}.openai.azure.com を指定する
+func NewAzureOpenAIService(
+	apiKey, endpoint, deployment, apiVersion string,
+	client *http.Client, options *ChatCompletionsOptions,
+) (*ChatGPTService, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("api key is empty")
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint is empty")
+	}
+	if deployment == "" {
+		return nil, fmt.Errorf("deployment is empty")
+	}
+	if apiVersion == "" {
+		return nil, fmt.Errorf("api version is empty")
+	}
+	endpointPath := fmt.Sprintf(
+		"/openai/deployments/%s%s?api-version=%s",
+		url.PathEscape(deployment),
+		completionPath,
+		url.QueryEscape(apiVersion),
+	)
+	defaultOptions := ChatCompletionsOptions{
+		Model:   deployment, // Azureではデプロイメントがモデルを決定する
+		BaseUrl: endpoint,
+	}
+	return &ChatGPTService{
+		apiKey:       apiKey,
+		client:       client,
+		endpointPath: endpointPath,
+		options:      defaultOptions.Merge(options),
+		authHeader:   AzureAuthHeader,
+		retryPolicy:  DefaultRetryPolicy,
+	}, nil
+}
+
+// NewOpenAICompatibleServiceはOllamaやvLLMなどOpenAI互換APIを呼び出すChatGPTServiceを生成する
+// baseUrlには http://localhost:11434/v1 のように /chat/completions の手前までを指定する
+// ローカルのサーバーではAPIキーが不要なことが多いため、apiKeyは空を許容する
+func NewOpenAICompatibleService(
+	baseUrl, apiKey string, client *http.Client, options *ChatCompletionsOptions,
+) (*ChatGPTService, error) {
+	defaultOptions := ChatCompletionsOptions{
+		BaseUrl: baseUrl,
+	}
+	merged := defaultOptions.Merge(options)
+	if merged.BaseUrl == "" {
+		return nil, fmt.Errorf("base url is empty")
+	}
+	if merged.Model == "" {
+		return nil, fmt.Errorf("model is empty")
+	}
+	return &ChatGPTService{
+		apiKey:       apiKey,
+		client:       client,
+		endpointPath: completionPath,
+		options:      merged,
+		authHeader:   BearerAuthHeader,
+		retryPolicy:  DefaultRetryPolicy,
+	}, nil
+}
+
+// Modelはリクエストで利用するデフォルトのモデル名を返す
+func (c *ChatGPTService) Model() string {
+	return c.options.Model
+}
+
+// SetRetryPolicyは一時的なエラーが発生した場合のリトライ方法を設定する
+func (c *ChatGPTService) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// Endpointはオプションのベースurlに対するChat CompletionsのエンドポイントURLを返す
+func (c *ChatGPTService) Endpoint(options ChatCompletionsOptions) string {
+	return strings.TrimRight(options.BaseUrl, "/") + c.endpointPath
+}
+
 type ChatGPTRequest struct {
-	Model    string                  `json:"model"`
-	Messages []ChatGPTRequestMessage `json:"messages"`
-	Stream   bool                    `json:"stream"`
+	Model         string                  `json:"model"`
+	Messages      []ChatGPTRequestMessage `json:"messages"`
+	Stream        bool                    `json:"stream"`
+	Temperature   *float64                `json:"temperature,omitempty"`
+	TopP          *float64                `json:"top_p,omitempty"`
+	MaxTokens     *int                    `json:"max_tokens,omitempty"`
+	Seed          *int                    `json:"seed,omitempty"`
+	StreamOptions *ChatGPTStreamOptions   `json:"stream_options,omitempty"`
+	Tools         []ChatGPTTool           `json:"tools,omitempty"`
+	ToolChoice    *ChatGPTToolChoice      `json:"tool_choice,omitempty"`
+}
+
+type ChatGPTTool struct {
+	Type     string          `json:"type"`
+	Function ChatGPTFunction `json:"function"`
+}
+
+type ChatGPTFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ChatGPTToolChoiceはモデルに呼び出させる関数を指定する
+type ChatGPTToolChoice struct {
+	Type     string                    `json:"type"`
+	Function ChatGPTToolChoiceFunction `json:"function"`
+}
+
+type ChatGPTToolChoiceFunction struct {
+	Name string `json:"name"`
+}
+
+type ChatGPTStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type ChatGPTRequestMessage struct {
@@ -43,6 +199,7 @@ type ChatGPTResponse struct {
 	Created       int64                  `json:"created"`
 	Model         string                 `json:"model"`
 	Choices       []ChatGPTRequestChoice `json:"choices"`
+	Usage         *Usage                 `json:"usage"`
 	ErrorResponse ChatGPTErrorResponse   `json:"error"`
 }
 
@@ -59,12 +216,28 @@ type ChatGPTRequestChoice struct {
 }
 
 type ChatGPTResponseMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string            `json:"role"`
+	Content   string            `json:"content"`
+	ToolCalls []ChatGPTToolCall `json:"tool_calls"`
+}
+
+type ChatGPTToolCall struct {
+	ID       string              `json:"id"`
+	Type     string              `json:"type"`
+	Function ChatGPTFunctionCall `json:"function"`
+}
+
+type ChatGPTFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 type ChatCompletionsInput struct {
 	Text string `json:"text"`
+	// Optionsはタスク単位でサービスのオプションを上書きする場合に指定する
+	Options *ChatCompletionsOptions `json:"-"`
+	// Toolを指定した場合はモデルに関数を呼び出させ、その引数(JSON)を応答のテキストとする
+	Tool *Tool `json:"-"`
 }
 
 type ChatGPTErrorResponse struct {
@@ -74,9 +247,81 @@ type ChatGPTErrorResponse struct {
 	Code    string `json:"code"`
 }
 
-func (c *ChatGPTService) ChatCompletions(input *ChatCompletionsInput) (string, error) {
+func (c *ChatGPTService) ChatCompletions(
+	ctx context.Context, input *ChatCompletionsInput,
+) (*ChatCompletionsOutput, error) {
+	resp, err := c.do(ctx, input, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var responseBody ChatGPTResponse
+	if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	if responseBody.ErrorResponse != (ChatGPTErrorResponse{}) {
+		e := responseBody.ErrorResponse
+		return nil, NewAPIError(resp.StatusCode, resp.Header, e.Code, e.Type, e.Message)
+	}
+	if len(responseBody.Choices) == 0 {
+		return nil, fmt.Errorf("failed to get response")
+	}
+	text := responseBody.Choices[0].Message.Content
+	if input.Tool != nil {
+		// 関数を呼び出さずにテキストで応答するOpenAI互換APIもあるため、その場合はテキストを返す
+		for _, call := range responseBody.Choices[0].Message.ToolCalls {
+			if call.Function.Name == input.Tool.Name {
+				text = call.Function.Arguments
+				break
+			}
+		}
+	}
+	return c.newOutput(input, responseBody.Model, text, responseBody.Usage), nil
+}
+
+// newOutputはレスポンスからChatCompletionsOutputを生成する
+// 使用量が返却されない場合はプロンプトと応答のテキストからトークン数を見積もる
+func (c *ChatGPTService) newOutput(
+	input *ChatCompletionsInput, model string, text string, usage *Usage,
+) *ChatCompletionsOutput {
+	return NewChatCompletionsOutput(c.options.Merge(input.Options).Model, input.Text, model, text, usage)
+}
+
+// doはリクエストを送信し、ステータスが200以外の場合はAPIErrorを返す
+// レート制限やサーバーエラーなど一時的なエラーの場合はリトライする
+func (c *ChatGPTService) do(
+	ctx context.Context, input *ChatCompletionsInput, stream bool,
+) (*http.Response, error) {
+	return Retry(ctx, c.retryPolicy, func() (*http.Response, error) {
+		req, err := c.newRequest(ctx, input, stream)
+		if err != nil {
+			return nil, err
+		}
+		if stream {
+			req.Header.Set("Accept", "text/event-stream")
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, WrapRequestError(ctx, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			// エラー時はストリームではなく通常のJSONが返却される
+			// プロキシなどがJSON以外を返す場合もあるため、デコードに失敗してもステータスで分類する
+			var responseBody ChatGPTResponse
+			_ = json.NewDecoder(resp.Body).Decode(&responseBody)
+			e := responseBody.ErrorResponse
+			return nil, NewAPIError(resp.StatusCode, resp.Header, e.Code, e.Type, e.Message)
+		}
+		return resp, nil
+	})
+}
+
+func (c *ChatGPTService) newRequest(
+	ctx context.Context, input *ChatCompletionsInput, stream bool,
+) (*http.Request, error) {
 	if input.Text == "" {
-		return "", fmt.Errorf("input text is empty")
+		return nil, fmt.Errorf("input text is empty")
 	}
 	payload := struct {
 		User string `json:"user"`
@@ -85,47 +330,58 @@ func (c *ChatGPTService) ChatCompletions(input *ChatCompletionsInput) (string, e
 	}
 	b, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 	messages := []ChatGPTRequestMessage{
 		{Role: "user", Content: string(b)},
 	}
+	options := c.options.Merge(input.Options)
 	requestBody := ChatGPTRequest{
-		Model:    "gpt-4",
-		Messages: messages,
+		Model:       options.Model,
+		Messages:    messages,
+		Stream:      stream,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		MaxTokens:   options.MaxTokens,
+		Seed:        options.Seed,
+	}
+	if input.Tool != nil {
+		requestBody.Tools = []ChatGPTTool{
+			{
+				Type: "function",
+				Function: ChatGPTFunction{
+					Name:        input.Tool.Name,
+					Description: input.Tool.Description,
+					Parameters:  input.Tool.Parameters,
+				},
+			},
+		}
+		requestBody.ToolChoice = &ChatGPTToolChoice{
+			Type:     "function",
+			Function: ChatGPTToolChoiceFunction{Name: input.Tool.Name},
+		}
+	}
+	if stream && c.streamUsage {
+		requestBody.StreamOptions = &ChatGPTStreamOptions{IncludeUsage: true}
 	}
 	b, err = json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		http.MethodPost,
-		"https://api.openai.com/v1/chat/completions",
+		c.Endpoint(options),
 		bytes.NewBuffer([]byte(b)),
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to build request: %w", err)
+		return nil, fmt.Errorf("failed to build request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-	if err != nil {
-		return "", fmt.Errorf("failed to build request: %w", err)
-	}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-	var responseBody ChatGPTResponse
-	if err := json.NewDecoder(resp.Body).Decode(&responseBody); err != nil {
-		return "", fmt.Errorf("failed to decode response body: %w", err)
-	}
-	if responseBody.ErrorResponse != (ChatGPTErrorResponse{}) {
-		return "", fmt.Errorf("failed to get response: %s", responseBody.ErrorResponse.Message)
-	}
-	if len(responseBody.Choices) == 0 {
-		return "", fmt.Errorf("failed to get response")
+	c.authHeader(req, c.apiKey)
+	if options.Organization != "" {
+		req.Header.Set("OpenAI-Organization", options.Organization)
 	}
-	return responseBody.Choices[0].Message.Content, nil
+	return req, nil
 }
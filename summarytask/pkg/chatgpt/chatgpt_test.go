@@ -1,6 +1,7 @@
 package chatgpt
 
 import (
+	"context"
 	"net/http"
 	"os"
 	"testing"
@@ -26,17 +27,17 @@ func Test_ChatGPTService_ChatCompletions(t *testing.T) {
 	}
 
 	client := &http.Client{}
-	sut, err := NewChatGPTService(apiKey, client)
+	sut, err := NewChatGPTService(apiKey, client, nil)
 	if err != nil {
 		t.Fatalf("failed to create chatgpt service: %v", err)
 	}
 
-	got, err := sut.ChatCompletions(input)
+	got, err := sut.ChatCompletions(context.Background(), input)
 	if err != nil {
 		t.Fatalf("failed to get completion: %v", err)
 	}
 
-	if got == "" {
+	if got.Text == "" {
 		t.Fatalf("got is empty")
 	}
 }
@@ -0,0 +1,184 @@
+/*
+chatgpttestはChat Completions APIと互換のあるフェイクサーバーを提供する
+ネットワークやAPIキーなしでChatGPTServiceを利用する処理をテストするために利用する
+*/
+package chatgpttest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+)
+
+//go:embed testdata
+var fixtures embed.FS
+
+// Responseはフェイクサーバーが1件のリクエストに対して返すレスポンス
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Header      http.Header
+	Body        []byte
+}
+
+// Fixtureはtestdataのファイルをステータス200のレスポンスとして読み込む
+// 拡張子が.txtのファイルはストリーミング(text/event-stream)のレスポンスとして返す
+func Fixture(t testing.TB, name string) Response {
+	t.Helper()
+	b, err := fixtures.ReadFile(path.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+	contentType := "application/json"
+	if strings.HasSuffix(name, ".txt") {
+		contentType = "text/event-stream"
+	}
+	return Response{StatusCode: http.StatusOK, ContentType: contentType, Body: b}
+}
+
+// ErrorFixtureはtestdataのエラーレスポンスを指定したステータスで返すレスポンスとして読み込む
+func ErrorFixture(t testing.TB, status int, name string) Response {
+	t.Helper()
+	r := Fixture(t, name)
+	r.StatusCode = status
+	return r
+}
+
+// Completionはtextを応答とする通常のレスポンスを返す
+// 使用量は返却しないため、ChatGPTServiceはテキストからトークン数を見積もる
+func Completion(text string) Response {
+	b, _ := json.Marshal(chatgpt.ChatGPTResponse{
+		ID:     "chatcmpl-test",
+		Object: "chat.completion",
+		Choices: []chatgpt.ChatGPTRequestChoice{
+			{
+				Message:      chatgpt.ChatGPTResponseMessage{Role: "assistant", Content: text},
+				FinishReason: "stop",
+			},
+		},
+	})
+	return Response{StatusCode: http.StatusOK, ContentType: "application/json", Body: b}
+}
+
+// StreamCompletionはdeltasを順に差分として返すストリーミングのレスポンスを返す
+func StreamCompletion(deltas ...string) Response {
+	b := strings.Builder{}
+	for _, delta := range deltas {
+		chunk, _ := json.Marshal(chatgpt.ChatGPTResponse{
+			ID:      "chatcmpl-test",
+			Object:  "chat.completion.chunk",
+			Choices: []chatgpt.ChatGPTRequestChoice{{Delta: chatgpt.ChatGPTResponseDelta{Content: delta}}},
+		})
+		fmt.Fprintf(&b, "data: %s\n\n", chunk)
+	}
+	b.WriteString("data: [DONE]\n\n")
+	return Response{StatusCode: http.StatusOK, ContentType: "text/event-stream", Body: []byte(b.String())}
+}
+
+// ServerはChat Completions APIのフェイクサーバー
+// 登録したレスポンスをリクエストの順に返し、最後のレスポンスは以降のリクエストでも繰り返し返す
+type Server struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []Response
+	requests  []chatgpt.ChatGPTRequest
+}
+
+// NewServerはresponsesを返すフェイクサーバーを起動する
+// サーバーはテストの終了時に停止する
+func NewServer(t testing.TB, responses ...Response) *Server {
+	t.Helper()
+	s := &Server{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Enqueueはレスポンスを末尾に追加する
+func (s *Server) Enqueue(responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// Requestsはサーバーが受信したリクエストを受信した順に返す
+func (s *Server) Requests() []chatgpt.ChatGPTRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]chatgpt.ChatGPTRequest(nil), s.requests...)
+}
+
+// Optionsはフェイクサーバーにリクエストするためのオプションを返す
+func (s *Server) Options() *chatgpt.ChatCompletionsOptions {
+	return &chatgpt.ChatCompletionsOptions{BaseUrl: s.URL}
+}
+
+// RetryPolicyはテストが待たされないよう待機時間を短くしたリトライ方法
+var RetryPolicy = chatgpt.RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  time.Millisecond,
+	MaxDelay:   time.Millisecond,
+}
+
+// NewChatGPTServiceはフェイクサーバーにリクエストするChatGPTServiceを生成する
+func (s *Server) NewChatGPTService(t testing.TB) *chatgpt.ChatGPTService {
+	t.Helper()
+	service, err := chatgpt.NewChatGPTService("test_key", s.Client(), s.Options())
+	if err != nil {
+		t.Fatalf("failed to create chatgpt service: %v", err)
+	}
+	service.SetRetryPolicy(RetryPolicy)
+	return service
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	var request chatgpt.ChatGPTRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to decode request body: %v", err))
+		return
+	}
+	if r.Header.Get("Authorization") == "" && r.Header.Get("api-key") == "" {
+		writeError(w, http.StatusUnauthorized, "api key is not set")
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	if len(s.responses) == 0 {
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, "no response is registered")
+		return
+	}
+	response := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	s.mu.Unlock()
+
+	for k, values := range response.Header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.Header().Set("Content-Type", response.ContentType)
+	w.WriteHeader(response.StatusCode)
+	w.Write(response.Body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	b, _ := json.Marshal(map[string]any{
+		"error": chatgpt.ChatGPTErrorResponse{Message: message, Type: "invalid_request_error"},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,69 @@
+package chatgpttest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+)
+
+func Test_Server(t *testing.T) {
+	ctx := context.Background()
+	input := &chatgpt.ChatCompletionsInput{Text: "こんにちは"}
+	want := &chatgpt.ChatCompletionsOutput{
+		Text:  "これはテスト用の要約です。",
+		Model: "gpt-4-0613",
+		Usage: chatgpt.Usage{PromptTokens: 120, CompletionTokens: 15, TotalTokens: 135},
+	}
+
+	t.Run("completion", func(t *testing.T) {
+		server := NewServer(t, Fixture(t, "completion.json"))
+		got, err := server.NewChatGPTService(t).ChatCompletions(ctx, input)
+		if err != nil {
+			t.Fatalf("failed to get completion: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+		if requests := server.Requests(); len(requests) != 1 || requests[0].Model != chatgpt.DefaultModel {
+			t.Errorf("requests is not expected: %v", requests)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		server := NewServer(t, Fixture(t, "completion_stream.txt"))
+		got, err := server.NewChatGPTService(t).ChatCompletionsStream(ctx, input, nil)
+		if err != nil {
+			t.Fatalf("failed to get completion: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("output mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("recover from rate limit", func(t *testing.T) {
+		server := NewServer(t,
+			ErrorFixture(t, http.StatusTooManyRequests, "rate_limit.json"),
+			Completion("要約"),
+		)
+		got, err := server.NewChatGPTService(t).ChatCompletions(ctx, input)
+		if err != nil {
+			t.Fatalf("failed to get completion: %v", err)
+		}
+		if got.Text != "要約" || len(server.Requests()) != 2 {
+			t.Errorf("output is not expected: %v, requests: %d", got.Text, len(server.Requests()))
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		server := NewServer(t,
+			ErrorFixture(t, http.StatusBadRequest, "context_length_exceeded.json"))
+		_, err := server.NewChatGPTService(t).ChatCompletions(ctx, input)
+		if !errors.Is(err, chatgpt.ErrContextLengthExceeded) {
+			t.Errorf("error is not expected: %v", err)
+		}
+	})
+}
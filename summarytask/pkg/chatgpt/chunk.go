@@ -0,0 +1,103 @@
+package chatgpt
+
+import (
+	"strings"
+)
+
+// SplitByTokensはテキストを1チャンクあたりmaxTokens以内に収まるように分割する
+// 段落、文、文字の順に区切りを細かくしながら、なるべく意味のまとまりを保って分割する
+func SplitByTokens(text string, maxTokens int) []string {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+	var chunks []string
+	current := strings.Builder{}
+	currentTokens := 0
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+	add := func(segment string) {
+		tokens := EstimateTokens(segment)
+		if currentTokens+tokens > maxTokens {
+			flush()
+		}
+		current.WriteString(segment)
+		currentTokens += tokens
+	}
+
+	for _, paragraph := range splitKeepSeparator(text, "\n") {
+		if EstimateTokens(paragraph) <= maxTokens {
+			add(paragraph)
+			continue
+		}
+		for _, sentence := range splitSentences(paragraph) {
+			if EstimateTokens(sentence) <= maxTokens {
+				add(sentence)
+				continue
+			}
+			for _, part := range splitRunes(sentence, maxTokens) {
+				add(part)
+			}
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitKeepSeparatorはsepで分割し、各要素の末尾にsepを残す
+func splitKeepSeparator(text string, sep string) []string {
+	parts := strings.SplitAfter(text, sep)
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// splitSentencesは日本語と英語の文末記号で文に分割する
+func splitSentences(text string) []string {
+	var sentences []string
+	current := strings.Builder{}
+	for _, r := range text {
+		current.WriteRune(r)
+		switch r {
+		case '。', '！', '？', '.', '!', '?':
+			sentences = append(sentences, current.String())
+			current.Reset()
+		}
+	}
+	if current.Len() > 0 {
+		sentences = append(sentences, current.String())
+	}
+	return sentences
+}
+
+// splitRunesは文の区切りがない長いテキストを文字単位でmaxTokens以内に分割する
+func splitRunes(text string, maxTokens int) []string {
+	var parts []string
+	var ascii, nonASCII int
+	current := strings.Builder{}
+	for _, r := range text {
+		current.WriteRune(r)
+		if isASCIIToken(r) {
+			ascii++
+		} else {
+			nonASCII++
+		}
+		if estimateTokens(ascii, nonASCII) >= maxTokens {
+			parts = append(parts, current.String())
+			current.Reset()
+			ascii, nonASCII = 0, 0
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
@@ -0,0 +1,64 @@
+package chatgpt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_SplitByTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		want      []string
+	}{
+		{
+			name:      "fits in one chunk",
+			text:      "一段落目。\n二段落目。",
+			maxTokens: 100,
+			want:      []string{"一段落目。\n二段落目。"},
+		},
+		{
+			name:      "split by paragraph",
+			text:      "一段落目。\n二段落目。\n",
+			maxTokens: 6,
+			want:      []string{"一段落目。", "二段落目。"},
+		},
+		{
+			name:      "split by sentence",
+			text:      "一文目。二文目。三文目。",
+			maxTokens: 8,
+			want:      []string{"一文目。二文目。", "三文目。"},
+		},
+		{
+			name:      "split by rune",
+			text:      "あいうえおかきくけこ",
+			maxTokens: 4,
+			want:      []string{"あいうえ", "おかきく", "けこ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitByTokens(tt.text, tt.maxTokens)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("SplitByTokens() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_SplitByTokens_WithinBudget(t *testing.T) {
+	text := strings.Repeat("これはテストの文章です。This is a test sentence.\n", 200)
+	maxTokens := 100
+	chunks := SplitByTokens(text, maxTokens)
+	if len(chunks) < 2 {
+		t.Fatalf("chunks is not split: %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if got := EstimateTokens(c); got > maxTokens {
+			t.Errorf("chunk %d exceeds max tokens: %d", i, got)
+		}
+	}
+}
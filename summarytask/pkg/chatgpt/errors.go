@@ -0,0 +1,128 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRateLimited           = errors.New("rate limited")
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+	ErrInvalidAPIKey         = errors.New("invalid api key")
+	ErrServerError           = errors.New("server error")
+	ErrTimeout               = errors.New("timeout")
+)
+
+// APIErrorはLLMのAPIがエラーを返した場合のエラー
+// errors.Isで分類(ErrRateLimitedなど)を判定できる
+type APIError struct {
+	StatusCode int
+	Code       string
+	Type       string
+	Message    string
+	// RetryAfterはレスポンスのRetry-Afterヘッダーで指定された待機時間
+	RetryAfter time.Duration
+	kind       error
+}
+
+// NewAPIErrorはHTTPステータスとエラーレスポンスのcode/typeからエラーを分類したAPIErrorを生成する
+func NewAPIError(statusCode int, header http.Header, code, errorType, message string) *APIError {
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Code:       code,
+		Type:       errorType,
+		Message:    message,
+		RetryAfter: parseRetryAfter(header, time.Now()),
+		kind:       classifyAPIError(statusCode, code, errorType, message),
+	}
+}
+
+func (e *APIError) Error() string {
+	s := fmt.Sprintf("failed to get response: status=%d", e.StatusCode)
+	if e.Code != "" {
+		s += fmt.Sprintf(" code=%s", e.Code)
+	}
+	return s + ": " + e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+func classifyAPIError(statusCode int, code, errorType, message string) error {
+	switch {
+	case code == "context_length_exceeded",
+		strings.Contains(message, "prompt is too long"):
+		return ErrContextLengthExceeded
+	case code == "invalid_api_key",
+		errorType == "authentication_error",
+		statusCode == http.StatusUnauthorized:
+		return ErrInvalidAPIKey
+	case code == "insufficient_quota":
+		// クォータ不足は429で返却されるが、待っても回復しないためリトライ対象にしない
+		return nil
+	case code == "rate_limit_exceeded",
+		errorType == "rate_limit_error",
+		statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusRequestTimeout,
+		statusCode == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case errorType == "server_error",
+		errorType == "overloaded_error",
+		statusCode >= http.StatusInternalServerError:
+		return ErrServerError
+	}
+	return nil
+}
+
+// parseRetryAfterはretry-after-ms、Retry-After(秒またはHTTP日付)ヘッダーから待機時間を返す
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if v := header.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.ParseFloat(v, 64); err == nil && sec > 0 {
+		return time.Duration(sec * float64(time.Second))
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// WrapRequestErrorはリクエスト送信時のエラーをラップし、タイムアウトの場合はErrTimeoutとして分類する
+// 呼び出し元のcontextがキャンセルされた場合はリトライしないため分類しない
+func WrapRequestError(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return fmt.Errorf("failed to execute request: %w: %w", ErrTimeout, err)
+		}
+	}
+	return fmt.Errorf("failed to execute request: %w", err)
+}
+
+// IsRetryableは時間をおいて再実行すれば成功する可能性のあるエラーかを判定する
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) ||
+		errors.Is(err, ErrServerError) ||
+		errors.Is(err, ErrTimeout)
+}
@@ -0,0 +1,68 @@
+package chatgpt
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_NewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		code       string
+		errorType  string
+		want       error
+		retryable  bool
+	}{
+		{name: "rate limited", statusCode: 429, code: "rate_limit_exceeded", want: ErrRateLimited, retryable: true},
+		{name: "insufficient quota", statusCode: 429, code: "insufficient_quota", want: nil, retryable: false},
+		{name: "context length", statusCode: 400, code: "context_length_exceeded", want: ErrContextLengthExceeded, retryable: false},
+		{name: "invalid api key", statusCode: 401, code: "invalid_api_key", want: ErrInvalidAPIKey, retryable: false},
+		{name: "server error", statusCode: 500, errorType: "server_error", want: ErrServerError, retryable: true},
+		{name: "anthropic overloaded", statusCode: 529, errorType: "overloaded_error", want: ErrServerError, retryable: true},
+		{name: "gateway timeout", statusCode: 504, want: ErrTimeout, retryable: true},
+		{name: "bad request", statusCode: 400, errorType: "invalid_request_error", want: nil, retryable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewAPIError(tt.statusCode, nil, tt.code, tt.errorType, "")
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("error is not %v: %v", tt.want, err)
+			}
+			if tt.want == nil && err.Unwrap() != nil {
+				t.Errorf("error should not be classified: %v", err.Unwrap())
+			}
+			if got := IsRetryable(err); got != tt.retryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{name: "empty", header: http.Header{}, want: 0},
+		{name: "seconds", header: http.Header{"Retry-After": []string{"2"}}, want: 2 * time.Second},
+		{name: "milliseconds", header: http.Header{"Retry-After-Ms": []string{"500"}}, want: 500 * time.Millisecond},
+		{
+			name:   "http date",
+			header: http.Header{"Retry-After": []string{now.Add(3 * time.Second).Format(http.TimeFormat)}},
+			want:   3 * time.Second,
+		},
+		{name: "invalid", header: http.Header{"Retry-After": []string{"soon"}}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.header, now); got != tt.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package chatgpt
+
+// ChatCompletionsOptionsはChat Completions APIのリクエストパラメータ
+// ポインタのフィールドはnilの場合リクエストに含めずAPIのデフォルト値を利用する
+type ChatCompletionsOptions struct {
+	Model        string
+	BaseUrl      string
+	Temperature  *float64
+	TopP         *float64
+	MaxTokens    *int
+	Seed         *int
+	Organization string
+}
+
+// Mergeはoverrideで指定された値でoを上書きしたオプションを返す
+// overrideで値が指定されていないフィールドはoの値を引き継ぐ
+func (o ChatCompletionsOptions) Merge(override *ChatCompletionsOptions) ChatCompletionsOptions {
+	if override == nil {
+		return o
+	}
+	merged := o
+	if override.Model != "" {
+		merged.Model = override.Model
+	}
+	if override.BaseUrl != "" {
+		merged.BaseUrl = override.BaseUrl
+	}
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.MaxTokens != nil {
+		merged.MaxTokens = override.MaxTokens
+	}
+	if override.Seed != nil {
+		merged.Seed = override.Seed
+	}
+	if override.Organization != "" {
+		merged.Organization = override.Organization
+	}
+	return merged
+}
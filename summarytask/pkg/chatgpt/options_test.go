@@ -0,0 +1,122 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func intPtr(v int) *int { return &v }
+
+func Test_ChatCompletionsOptions_Merge(t *testing.T) {
+	base := ChatCompletionsOptions{
+		Model:       "gpt-4",
+		BaseUrl:     OpenAIBaseUrl,
+		Temperature: floatPtr(1),
+	}
+	tests := []struct {
+		name     string
+		override *ChatCompletionsOptions
+		want     ChatCompletionsOptions
+	}{
+		{
+			name:     "nil override",
+			override: nil,
+			want:     base,
+		},
+		{
+			name: "override model and seed",
+			override: &ChatCompletionsOptions{
+				Model: "gpt-3.5-turbo",
+				Seed:  intPtr(1),
+			},
+			want: ChatCompletionsOptions{
+				Model:       "gpt-3.5-turbo",
+				BaseUrl:     OpenAIBaseUrl,
+				Temperature: floatPtr(1),
+				Seed:        intPtr(1),
+			},
+		},
+		{
+			name: "override temperature with zero",
+			override: &ChatCompletionsOptions{
+				Temperature: floatPtr(0),
+			},
+			want: ChatCompletionsOptions{
+				Model:       "gpt-4",
+				BaseUrl:     OpenAIBaseUrl,
+				Temperature: floatPtr(0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := base.Merge(tt.override)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Merge() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ChatGPTService_ChatCompletions_Options(t *testing.T) {
+	var gotRequest ChatGPTRequest
+	var gotOrganization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != completionPath {
+			t.Errorf("path is not expected: %v", r.URL.Path)
+		}
+		gotOrganization = r.Header.Get("OpenAI-Organization")
+		if err := json.NewDecoder(r.Body).Decode(&gotRequest); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"要約"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	sut, err := NewChatGPTService("test_key", server.Client(), &ChatCompletionsOptions{
+		BaseUrl:      server.URL,
+		Temperature:  floatPtr(0.2),
+		Organization: "org-test",
+	})
+	if err != nil {
+		t.Fatalf("failed to create chatgpt service: %v", err)
+	}
+
+	got, err := sut.ChatCompletions(context.Background(), &ChatCompletionsInput{
+		Text: "こんにちは",
+		Options: &ChatCompletionsOptions{
+			Model:     "gpt-3.5-turbo",
+			MaxTokens: intPtr(100),
+			Seed:      intPtr(42),
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to get completion: %v", err)
+	}
+	if got.Text != "要約" {
+		t.Fatalf("got is not expected: %v", got.Text)
+	}
+
+	want := ChatGPTRequest{
+		Model:       "gpt-3.5-turbo",
+		Temperature: floatPtr(0.2),
+		MaxTokens:   intPtr(100),
+		Seed:        intPtr(42),
+	}
+	if diff := cmp.Diff(want, gotRequest, cmp.FilterPath(func(p cmp.Path) bool {
+		return p.String() == "Messages"
+	}, cmp.Ignore())); diff != "" {
+		t.Errorf("request mismatch (-want +got):\n%s", diff)
+	}
+	if gotOrganization != "org-test" {
+		t.Errorf("organization header is not expected: %v", gotOrganization)
+	}
+}
@@ -0,0 +1,62 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicyは一時的なエラーが発生した場合のリトライ方法
+type RetryPolicy struct {
+	// MaxRetriesは初回を除いたリトライの最大回数 (0の場合はリトライしない)
+	MaxRetries int
+	// BaseDelayは1回目のリトライまでの待機時間 (以降は指数関数的に増加する)
+	BaseDelay time.Duration
+	// MaxDelayは1回あたりの待機時間の上限
+	MaxDelay time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+// Delayはattempt回目(0始まり)のリトライまでの待機時間を返す
+// Retry-Afterが指定されている場合はその値を優先し、MaxDelayを上限とする
+func (p RetryPolicy) Delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return min(apiErr.RetryAfter, p.MaxDelay)
+	}
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	// 複数のタスクが同時にリトライしないように待機時間を半分から全体の間でばらつかせる
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+// Retryはfnを実行し、IsRetryableなエラーの場合はポリシーに従って待機してから再実行する
+func Retry[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	var result T
+	var err error
+	for attempt := 0; ; attempt++ {
+		result, err = fn()
+		if err == nil || !IsRetryable(err) || attempt >= policy.MaxRetries {
+			return result, err
+		}
+		timer := time.NewTimer(policy.Delay(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package chatgpt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_RetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		got := policy.Delay(attempt, ErrServerError)
+		if got < max/2 || got > max {
+			t.Errorf("Delay(%d) = %v, want between %v and %v", attempt, got, max/2, max)
+		}
+	}
+	retryAfter := &APIError{RetryAfter: 3 * time.Second, kind: ErrRateLimited}
+	if got := policy.Delay(0, retryAfter); got != 3*time.Second {
+		t.Errorf("Delay() with Retry-After = %v, want %v", got, 3*time.Second)
+	}
+	retryAfter.RetryAfter = time.Minute
+	if got := policy.Delay(0, retryAfter); got != policy.MaxDelay {
+		t.Errorf("Delay() with long Retry-After = %v, want %v", got, policy.MaxDelay)
+	}
+}
+
+func Test_ChatGPTService_ChatCompletions_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		status    int
+		body      string
+		wantCalls int32
+		wantErr   error
+	}{
+		{
+			name:      "recover from rate limit",
+			failures:  2,
+			status:    http.StatusTooManyRequests,
+			body:      `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantCalls: 3,
+		},
+		{
+			name:      "recover from bad gateway",
+			failures:  1,
+			status:    http.StatusBadGateway,
+			body:      `<html>Bad Gateway</html>`,
+			wantCalls: 2,
+		},
+		{
+			name:      "give up after max retries",
+			failures:  10,
+			status:    http.StatusServiceUnavailable,
+			body:      `{"error":{"message":"The server is overloaded","type":"server_error"}}`,
+			wantCalls: 3,
+			wantErr:   ErrServerError,
+		},
+		{
+			name:      "do not retry invalid api key",
+			failures:  10,
+			status:    http.StatusUnauthorized,
+			body:      `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			wantCalls: 1,
+			wantErr:   ErrInvalidAPIKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				if int(n) <= tt.failures {
+					w.Header().Set("Retry-After", "0.01")
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.body))
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"要約"}}]}`))
+			}))
+			t.Cleanup(server.Close)
+
+			sut, err := NewChatGPTService("test_key", server.Client(), &ChatCompletionsOptions{BaseUrl: server.URL})
+			if err != nil {
+				t.Fatalf("failed to create chatgpt service: %v", err)
+			}
+			sut.SetRetryPolicy(RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+			got, err := sut.ChatCompletions(context.Background(), &ChatCompletionsInput{Text: "こんにちは"})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error is not expected: %v", err)
+				}
+			} else if err != nil || got.Text != "要約" {
+				t.Errorf("failed to get completion: %v, %v", got, err)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
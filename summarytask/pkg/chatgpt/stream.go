@@ -0,0 +1,120 @@
+package chatgpt
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamDeltaFuncはストリーミングで受信したテキストの差分ごとに呼び出される関数
+// エラーを返した場合はストリーミングを中断する
+type StreamDeltaFunc func(delta string) error
+
+const streamDone = "[DONE]"
+
+// ServerSentEventはtext/event-streamで受信した1件のイベント
+type ServerSentEvent struct {
+	Event string
+	Data  string
+}
+
+// ReadServerSentEventsはtext/event-streamのレスポンスを読み込み、イベントごとにfnを呼び出す
+func ReadServerSentEvents(r io.Reader, fn func(event *ServerSentEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	// 1イベントのサイズが大きい場合に備えてバッファを拡張する
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var event ServerSentEvent
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ServerSentEvent{}
+			return nil
+		}
+		event.Data = strings.Join(data, "\n")
+		err := fn(&event)
+		event = ServerSentEvent{}
+		data = nil
+		return err
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// コメント行は無視する
+		case strings.HasPrefix(line, "event:"):
+			event.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return dispatch()
+}
+
+// ChatCompletionsStreamはストリーミングモードでChat Completions APIを呼び出す
+// 差分を受信するたびにonDeltaを呼び出し、最終的に結合したテキストを返す
+func (c *ChatGPTService) ChatCompletionsStream(
+	ctx context.Context, input *ChatCompletionsInput, onDelta StreamDeltaFunc,
+) (*ChatCompletionsOutput, error) {
+	if input.Tool != nil {
+		return nil, fmt.Errorf("tool is not supported in streaming")
+	}
+	// ストリームの受信開始後は差分を通知済みのためリトライしない
+	resp, err := c.do(ctx, input, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	contentBuilder := strings.Builder{}
+	var model string
+	var usage *Usage
+	err = ReadServerSentEvents(resp.Body, func(event *ServerSentEvent) error {
+		if event.Data == streamDone {
+			return io.EOF
+		}
+		var chunk ChatGPTResponse
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal chunk: %w", err)
+		}
+		if chunk.ErrorResponse != (ChatGPTErrorResponse{}) {
+			e := chunk.ErrorResponse
+			return NewAPIError(resp.StatusCode, nil, e.Code, e.Type, e.Message)
+		}
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			// include_usageを指定した場合は最後のチャンクで使用量が返却される
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+		delta := chunk.Choices[0].Delta.Content
+		contentBuilder.WriteString(delta)
+		if onDelta != nil {
+			if err := onDelta(delta); err != nil {
+				return fmt.Errorf("failed to handle delta: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if contentBuilder.Len() == 0 {
+		return nil, fmt.Errorf("failed to get response")
+	}
+	return c.newOutput(input, model, contentBuilder.String(), usage), nil
+}
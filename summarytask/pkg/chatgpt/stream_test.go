@@ -0,0 +1,96 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ReadServerSentEvents(t *testing.T) {
+	stream := strings.Join([]string{
+		": comment",
+		"event: message_start",
+		"data: {\"a\":1}",
+		"",
+		"data: line1",
+		"data: line2",
+		"",
+		"data: [DONE]",
+		"",
+	}, "\n")
+
+	var got []ServerSentEvent
+	err := ReadServerSentEvents(strings.NewReader(stream), func(event *ServerSentEvent) error {
+		got = append(got, *event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read events: %v", err)
+	}
+	want := []ServerSentEvent{
+		{Event: "message_start", Data: "{\"a\":1}"},
+		{Data: "line1\nline2"},
+		{Data: "[DONE]"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ReadServerSentEvents() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_ChatGPTService_ChatCompletionsStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ChatGPTRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		if body.StreamOptions == nil || !body.StreamOptions.IncludeUsage {
+			t.Errorf("stream_options is not expected: %v", body.StreamOptions)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+			`{"choices":[{"index":0,"delta":{"content":"これは"}}]}`,
+			`{"choices":[{"index":0,"delta":{"content":"要約"}}]}`,
+			`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			`{"model":"gpt-4-0613","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":3,"total_tokens":13}}`,
+			streamDone,
+		} {
+			w.Write([]byte("data: " + chunk + "\n\n"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sut, err := NewChatGPTService("test_key", server.Client(), &ChatCompletionsOptions{BaseUrl: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create chatgpt service: %v", err)
+	}
+
+	var deltas []string
+	got, err := sut.ChatCompletionsStream(
+		context.Background(),
+		&ChatCompletionsInput{Text: "こんにちは"},
+		func(delta string) error {
+			deltas = append(deltas, delta)
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to get completion: %v", err)
+	}
+	want := &ChatCompletionsOutput{
+		Text:  "これは要約",
+		Model: "gpt-4-0613",
+		Usage: Usage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"これは", "要約"}, deltas); diff != "" {
+		t.Errorf("deltas mismatch (-want +got):\n%s", diff)
+	}
+}
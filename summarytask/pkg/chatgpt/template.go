@@ -4,13 +4,50 @@ import (
 	"bytes"
 	_ "embed"
 	"fmt"
-	"text/template"
+	"strings"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
 )
 
 func SummaryTemplateBuilder(input *SummaryTemplateInput) (string, error) {
-	tmpl, err := template.New("summary").Parse(gptRequestSummaryTemplate)
+	return buildTemplate("summary", gptRequestSummaryTemplate, input)
+}
+
+// StyleTemplateBuilderは組み込みのスタイル(箇条書き、TL;DRなど)のテンプレートで要約のプロンプトを生成する
+func StyleTemplateBuilder(style string, input *SummaryTemplateInput) (string, error) {
+	text, ok := styleTemplates[style]
+	if !ok {
+		return "", fmt.Errorf("unknown style: %s", style)
+	}
+	return buildTemplate(style, text, input)
+}
+
+// CustomTemplateBuilderはユーザーが定義したテンプレートで要約のプロンプトを生成する
+// テンプレートが出力言語(.Language)を参照しない場合は末尾に出力言語の指示を追加する
+func CustomTemplateBuilder(text string, input *SummaryTemplateInput) (string, error) {
+	if !strings.Contains(text, ".Language") {
+		text += `{{template "language" .}}`
+	}
+	return buildTemplate("custom", text, input)
+}
+
+// StructuredSummaryTemplateBuilderは構造化出力(StructuredSummaryTool)で要約するプロンプトを生成する
+func StructuredSummaryTemplateBuilder(input *SummaryTemplateInput) (string, error) {
+	return buildTemplate("structured_summary", gptRequestStructuredSummaryTemplate, input)
+}
+
+func ChunkSummaryTemplateBuilder(input *ChunkSummaryTemplateInput) (string, error) {
+	return buildTemplate("chunk_summary", gptRequestChunkSummaryTemplate, input)
+}
+
+func ReduceSummaryTemplateBuilder(input *ReduceSummaryTemplateInput) (string, error) {
+	return buildTemplate("reduce_summary", gptRequestReduceSummaryTemplate, input)
+}
+
+func buildTemplate(name string, text string, input any) (string, error) {
+	tmpl, err := entities.ParsePromptTemplate(name, text)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return "", err
 	}
 	var buffer bytes.Buffer
 	if err := tmpl.Execute(&buffer, input); err != nil {
@@ -25,4 +62,54 @@ var gptRequestSummaryTemplate string
 type SummaryTemplateInput struct {
 	Title   string
 	Content string
+	// Languageは出力言語の名前 (例: 英語 (English))。空の場合は言語を指示しない
+	Language string
+}
+
+var (
+	//go:embed bullet_points_template.txt
+	gptRequestBulletPointsTemplate string
+	//go:embed tldr_template.txt
+	gptRequestTLDRTemplate string
+	//go:embed executive_brief_template.txt
+	gptRequestExecutiveBriefTemplate string
+	//go:embed qa_template.txt
+	gptRequestQATemplate string
+	//go:embed key_quotes_template.txt
+	gptRequestKeyQuotesTemplate string
+)
+
+// styleTemplatesは組み込みのスタイル名ごとのテンプレート
+var styleTemplates = map[string]string{
+	"default":         gptRequestSummaryTemplate,
+	"bullet_points":   gptRequestBulletPointsTemplate,
+	"tldr":            gptRequestTLDRTemplate,
+	"executive_brief": gptRequestExecutiveBriefTemplate,
+	"qa":              gptRequestQATemplate,
+	"key_quotes":      gptRequestKeyQuotesTemplate,
+}
+
+//go:embed chunk_summary_template.txt
+var gptRequestChunkSummaryTemplate string
+
+// ChunkSummaryTemplateInputは分割した本文の一部分を要約するためのテンプレート入力
+type ChunkSummaryTemplateInput struct {
+	Title    string
+	Content  string
+	Index    int
+	Total    int
+	Language string
+}
+
+//go:embed structured_summary_template.txt
+var gptRequestStructuredSummaryTemplate string
+
+//go:embed reduce_summary_template.txt
+var gptRequestReduceSummaryTemplate string
+
+// ReduceSummaryTemplateInputは部分要約を統合するためのテンプレート入力
+type ReduceSummaryTemplateInput struct {
+	Title     string
+	Summaries []string
+	Language  string
 }
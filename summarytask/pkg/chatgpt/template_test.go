@@ -0,0 +1,65 @@
+package chatgpt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+func Test_StyleTemplateBuilder(t *testing.T) {
+	input := &SummaryTemplateInput{Title: "タイトル", Content: "本文のテキスト"}
+	for _, style := range entities.BuiltinSummaryStyles {
+		t.Run(style, func(t *testing.T) {
+			got, err := StyleTemplateBuilder(style, input)
+			if err != nil {
+				t.Fatalf("failed to build template: %v", err)
+			}
+			if !strings.Contains(got, input.Title) || !strings.Contains(got, input.Content) {
+				t.Errorf("template does not contain title or content: %v", got)
+			}
+		})
+	}
+	if _, err := StyleTemplateBuilder("unknown", input); err == nil {
+		t.Errorf("unknown style should be error")
+	}
+}
+
+func Test_ReduceSummaryTemplateBuilder(t *testing.T) {
+	got, err := ReduceSummaryTemplateBuilder(&ReduceSummaryTemplateInput{
+		Title:     "タイトル",
+		Summaries: []string{"要約1", "要約2"},
+	})
+	if err != nil {
+		t.Fatalf("failed to build template: %v", err)
+	}
+	if !strings.Contains(got, "[1]\n要約1") || !strings.Contains(got, "[2]\n要約2") {
+		t.Errorf("template does not contain summaries: %v", got)
+	}
+}
+
+func Test_TemplateBuilder_Language(t *testing.T) {
+	instruction := "要約は必ず英語 (English)で出力してください。"
+	got, err := SummaryTemplateBuilder(&SummaryTemplateInput{Title: "タイトル", Content: "本文"})
+	if err != nil {
+		t.Fatalf("failed to build template: %v", err)
+	}
+	if strings.Contains(got, "で出力してください") {
+		t.Errorf("template should not contain language instruction: %v", got)
+	}
+	input := &SummaryTemplateInput{Title: "タイトル", Content: "本文", Language: "英語 (English)"}
+	got, err = SummaryTemplateBuilder(input)
+	if err != nil {
+		t.Fatalf("failed to build template: %v", err)
+	}
+	if !strings.Contains(got, instruction) {
+		t.Errorf("template does not contain language instruction: %v", got)
+	}
+	got, err = CustomTemplateBuilder("{{.Content}}を要約してください。", input)
+	if err != nil {
+		t.Fatalf("failed to build template: %v", err)
+	}
+	if !strings.HasSuffix(got, instruction) {
+		t.Errorf("custom template does not end with language instruction: %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package chatgpt
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// contextWindowsはモデル名(プレフィックス)ごとのコンテキストウィンドウのトークン数
+var contextWindows = map[string]int{
+	"gpt-4":              8192,
+	"gpt-4-32k":          32768,
+	"gpt-4-turbo":        128000,
+	"gpt-4-1106-preview": 128000,
+	"gpt-4-0125-preview": 128000,
+	"gpt-4o":             128000,
+	"gpt-3.5-turbo":      16385,
+	"claude-":            200000,
+	"claude-2.0":         100000,
+	"llama3":             8192,
+	"mistral":            32768,
+	"mixtral":            32768,
+}
+
+const DefaultContextWindow = 8192
+
+// ContextWindowはモデルのコンテキストウィンドウのトークン数を返す
+func ContextWindow(model string) int {
+	if v, ok := lookupByPrefix(contextWindows, model); ok {
+		return v
+	}
+	return DefaultContextWindow
+}
+
+// lookupByPrefixはモデル名に完全一致する値、なければ最も長く一致するプレフィックスの値を返す
+func lookupByPrefix[T any](table map[string]T, model string) (T, bool) {
+	if v, ok := table[model]; ok {
+		return v, true
+	}
+	prefixes := make([]string, 0, len(table))
+	for k := range table {
+		prefixes = append(prefixes, k)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	for _, p := range prefixes {
+		if strings.HasPrefix(model, p) {
+			return table[p], true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// TokenBudgetsはモデルごとに1リクエストのプロンプトへ割り当てるトークン数
+type TokenBudgets map[string]int
+
+// ForModelはモデルのトークン予算を返す
+// 設定されていない場合はコンテキストウィンドウの半分とし、残りを出力に充てる
+func (b TokenBudgets) ForModel(model string) int {
+	if v, ok := b[model]; ok && v > 0 {
+		return v
+	}
+	return ContextWindow(model) / 2
+}
+
+// EstimateTokensはテキストのおおよそのトークン数を見積もる
+// ASCIIはおよそ4文字で1トークン、日本語などの非ASCII文字は1文字1トークンとして多めに見積もる
+func EstimateTokens(text string) int {
+	var ascii, nonASCII int
+	for _, r := range text {
+		if isASCIIToken(r) {
+			ascii++
+		} else {
+			nonASCII++
+		}
+	}
+	return estimateTokens(ascii, nonASCII)
+}
+
+func estimateTokens(ascii, nonASCII int) int {
+	return (ascii+3)/4 + nonASCII
+}
+
+func isASCIIToken(r rune) bool {
+	return r < utf8.RuneSelf || unicode.IsSpace(r)
+}
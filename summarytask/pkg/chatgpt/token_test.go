@@ -0,0 +1,59 @@
+package chatgpt
+
+import "testing"
+
+func Test_ContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{model: "gpt-4", want: 8192},
+		{model: "gpt-4-0613", want: 8192},
+		{model: "gpt-4-32k-0613", want: 32768},
+		{model: "gpt-4o-mini", want: 128000},
+		{model: "claude-3-haiku-20240307", want: 200000},
+		{model: "claude-2.0", want: 100000},
+		{model: "unknown-model", want: DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := ContextWindow(tt.model); got != tt.want {
+				t.Errorf("ContextWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_TokenBudgets_ForModel(t *testing.T) {
+	budgets := TokenBudgets{"gpt-4": 1000}
+	if got := budgets.ForModel("gpt-4"); got != 1000 {
+		t.Errorf("ForModel() = %v, want %v", got, 1000)
+	}
+	if got := budgets.ForModel("gpt-3.5-turbo"); got != 16385/2 {
+		t.Errorf("ForModel() = %v, want %v", got, 16385/2)
+	}
+	var empty TokenBudgets
+	if got := empty.ForModel("gpt-4"); got != 4096 {
+		t.Errorf("ForModel() = %v, want %v", got, 4096)
+	}
+}
+
+func Test_EstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "ascii", text: "hello world!", want: 3},
+		{name: "japanese", text: "こんにちは", want: 5},
+		{name: "mixed", text: "Go言語", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package chatgpt
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+// Toolは構造化された出力を得るためにモデルに呼び出させる関数の定義
+// ChatCompletionsInputに指定した場合はモデルに必ずこの関数を呼び出させ、
+// 関数の引数(JSON)を応答のテキストとして返す
+type Tool struct {
+	Name        string
+	Description string
+	// Parametersは関数の引数をJSON Schemaで記述したもの
+	Parameters json.RawMessage
+}
+
+//go:embed structured_summary_schema.json
+var structuredSummarySchema []byte
+
+// StructuredSummaryToolは見出し、要点、固有表現、感情、タグを構造化して出力させるためのTool
+// 引数はentities.StructuredSummaryのJSONと対応する
+var StructuredSummaryTool = &Tool{
+	Name:        "save_summary",
+	Description: "Webページの要約を構造化して保存する",
+	Parameters:  structuredSummarySchema,
+}
@@ -0,0 +1,76 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+func Test_ChatGPTService_ChatCompletions_Tool(t *testing.T) {
+	var gotRequest ChatGPTRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&gotRequest); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"save_summary","arguments":"{\"headline\":\"見出し\",\"keyPoints\":[\"要点\"],\"entities\":[],\"sentiment\":\"neutral\",\"tags\":[\"go\"]}"}}]}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	sut, err := NewChatGPTService("test_key", server.Client(), &ChatCompletionsOptions{BaseUrl: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create chatgpt service: %v", err)
+	}
+	got, err := sut.ChatCompletions(context.Background(), &ChatCompletionsInput{
+		Text: "こんにちは",
+		Tool: StructuredSummaryTool,
+	})
+	if err != nil {
+		t.Fatalf("failed to get completion: %v", err)
+	}
+
+	if len(gotRequest.Tools) != 1 || gotRequest.Tools[0].Function.Name != StructuredSummaryTool.Name {
+		t.Errorf("tools is not expected: %v", gotRequest.Tools)
+	}
+	if gotRequest.ToolChoice == nil || gotRequest.ToolChoice.Function.Name != StructuredSummaryTool.Name {
+		t.Errorf("tool_choice is not expected: %v", gotRequest.ToolChoice)
+	}
+	structured, err := entities.ParseStructuredSummary(got.Text)
+	if err != nil {
+		t.Fatalf("failed to parse structured summary: %v", err)
+	}
+	if structured.Headline != "見出し" {
+		t.Errorf("headline is not expected: %v", structured.Headline)
+	}
+}
+
+func Test_StructuredSummaryTool_Parameters(t *testing.T) {
+	var schema struct {
+		Properties map[string]any `json:"properties"`
+		Required   []string       `json:"required"`
+	}
+	if err := json.Unmarshal(StructuredSummaryTool.Parameters, &schema); err != nil {
+		t.Fatalf("failed to unmarshal schema: %v", err)
+	}
+	// スキーマの項目はentities.StructuredSummaryのJSONと一致している必要がある
+	b, err := json.Marshal(entities.StructuredSummary{})
+	if err != nil {
+		t.Fatalf("failed to marshal structured summary: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatalf("failed to unmarshal structured summary: %v", err)
+	}
+	for field := range fields {
+		if _, ok := schema.Properties[field]; !ok {
+			t.Errorf("schema does not contain %s", field)
+		}
+	}
+	if len(schema.Required) != len(fields) {
+		t.Errorf("required is not expected: %v", schema.Required)
+	}
+}
@@ -0,0 +1,83 @@
+package chatgpt
+
+// ChatCompletionsOutputはChat Completions APIのレスポンスから取り出した結果
+type ChatCompletionsOutput struct {
+	Text string
+	// Modelはレスポンスで返却された実際のモデル名 (返却されない場合はリクエストしたモデル名)
+	Model string
+	Usage Usage
+}
+
+// NewChatCompletionsOutputはレスポンスの内容からChatCompletionsOutputを生成する
+// レスポンスにモデル名や使用量が含まれない場合は、リクエストのモデル名とテキストから見積もった値を利用する
+func NewChatCompletionsOutput(
+	requestModel string, prompt string, responseModel string, text string, usage *Usage,
+) *ChatCompletionsOutput {
+	output := &ChatCompletionsOutput{
+		Text:  text,
+		Model: responseModel,
+	}
+	if output.Model == "" {
+		output.Model = requestModel
+	}
+	if usage != nil && usage.TotalTokens > 0 {
+		output.Usage = *usage
+	} else {
+		promptTokens, completionTokens := EstimateTokens(prompt), EstimateTokens(text)
+		output.Usage = Usage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		}
+	}
+	return output
+}
+
+// Usageはリクエストで消費したトークン数
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Addはuとotherのトークン数を合計したUsageを返す
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		TotalTokens:      u.TotalTokens + other.TotalTokens,
+	}
+}
+
+// ModelPriceはモデルの100万トークンあたりの料金(USD)
+type ModelPrice struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+// modelPricesはモデル名(プレフィックス)ごとの料金
+// ローカルのモデルなど表にないモデルの料金は0とする
+var modelPrices = map[string]ModelPrice{
+	"gpt-4":              {PromptPerMillion: 30, CompletionPerMillion: 60},
+	"gpt-4-32k":          {PromptPerMillion: 60, CompletionPerMillion: 120},
+	"gpt-4-turbo":        {PromptPerMillion: 10, CompletionPerMillion: 30},
+	"gpt-4-1106-preview": {PromptPerMillion: 10, CompletionPerMillion: 30},
+	"gpt-4-0125-preview": {PromptPerMillion: 10, CompletionPerMillion: 30},
+	"gpt-4o":             {PromptPerMillion: 5, CompletionPerMillion: 15},
+	"gpt-4o-mini":        {PromptPerMillion: 0.15, CompletionPerMillion: 0.6},
+	"gpt-3.5-turbo":      {PromptPerMillion: 0.5, CompletionPerMillion: 1.5},
+	"claude-3-haiku":     {PromptPerMillion: 0.25, CompletionPerMillion: 1.25},
+	"claude-3-sonnet":    {PromptPerMillion: 3, CompletionPerMillion: 15},
+	"claude-3-5-sonnet":  {PromptPerMillion: 3, CompletionPerMillion: 15},
+	"claude-3-opus":      {PromptPerMillion: 15, CompletionPerMillion: 75},
+}
+
+// Costはモデルとトークン数から料金(USD)を計算する
+func Cost(model string, usage Usage) float64 {
+	price, ok := lookupByPrefix(modelPrices, model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*price.PromptPerMillion +
+		float64(usage.CompletionTokens)*price.CompletionPerMillion) / 1_000_000
+}
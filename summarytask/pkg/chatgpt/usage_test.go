@@ -0,0 +1,40 @@
+package chatgpt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Cost(t *testing.T) {
+	usage := Usage{PromptTokens: 1000, CompletionTokens: 500, TotalTokens: 1500}
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{model: "gpt-4-0613", want: 0.06},
+		{model: "gpt-4o-mini-2024-07-18", want: 0.00045},
+		{model: "claude-3-haiku-20240307", want: 0.000875},
+		{model: "llama3", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := Cost(tt.model, usage); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Cost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_NewChatCompletionsOutput(t *testing.T) {
+	got := NewChatCompletionsOutput("gpt-4", "hello world!", "", "こんにちは", nil)
+	want := &ChatCompletionsOutput{
+		Text:  "こんにちは",
+		Model: "gpt-4",
+		Usage: Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("NewChatCompletionsOutput() mismatch (-want +got):\n%s", diff)
+	}
+}
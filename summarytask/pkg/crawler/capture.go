@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"fmt"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// screenshotTimeoutはページ全体のスクリーンショットを撮影する最大の時間(ミリ秒)
+const screenshotTimeout = 30000
+
+// capturePageは表示したページの描画後のHTMLと、設定に応じてスクリーンショットとMHTMLを取得する
+// スクリーンショットとMHTMLは取得できなくてもHTMLは記録する
+func (p *PlaywrightClient) capturePage(page playwright.Page) (*entities.PageCapture, error) {
+	content, err := page.Content()
+	if err != nil {
+		return nil, fmt.Errorf("could not get page content: %v", err)
+	}
+	capture := &entities.PageCapture{
+		Url:         page.URL(),
+		ContentType: "text/html; charset=utf-8",
+		Raw:         []byte(content),
+	}
+	if p.captureScreenshot {
+		if screenshot, err := page.Screenshot(playwright.PageScreenshotOptions{
+			FullPage: playwright.Bool(true),
+			Type:     playwright.ScreenshotTypePng,
+			Timeout:  playwright.Float(screenshotTimeout),
+		}); err == nil {
+			capture.Screenshot = screenshot
+		}
+	}
+	if p.captureMHTML {
+		if mhtml, err := captureMHTML(page); err == nil {
+			capture.MHTML = mhtml
+		}
+	}
+	return capture, nil
+}
+
+// captureMHTMLはChromeDevTools Protocolでサブリソースを含むページのMHTMLを取得する
+func captureMHTML(page playwright.Page) ([]byte, error) {
+	session, err := page.Context().NewCDPSession(page)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cdp session: %v", err)
+	}
+	defer session.Detach()
+	result, err := session.Send("Page.captureSnapshot", map[string]interface{}{"format": "mhtml"})
+	if err != nil {
+		return nil, fmt.Errorf("could not capture snapshot: %v", err)
+	}
+	fields, _ := result.(map[string]interface{})
+	data, _ := fields["data"].(string)
+	if data == "" {
+		return nil, fmt.Errorf("snapshot is empty")
+	}
+	return []byte(data), nil
+}
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"fmt"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// dismissConsentScriptはCookieの同意ダイアログの同意ボタンを押し、残った同意のバナーを取り除くスクリプト
+// 主要な同意管理ツールのボタンを優先し、見つからない場合は同意のダイアログの中の「同意」などのボタンを探す
+// 押したボタンまたは取り除いた要素があればtrueを返す
+const dismissConsentScript = `() => {
+  const acceptSelectors = [
+    '#onetrust-accept-btn-handler',
+    '#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll',
+    '#CybotCookiebotDialogBodyButtonAccept',
+    '#didomi-notice-agree-button',
+    '.qc-cmp2-summary-buttons button[mode="primary"]',
+    '#truste-consent-button',
+    '.fc-cta-consent',
+    '.cc-allow',
+    '.cc-dismiss',
+  ];
+  const containerSelector = [
+    '[id*="cookie" i]', '[class*="cookie" i]',
+    '[id*="consent" i]', '[class*="consent" i]',
+    '[id*="gdpr" i]', '[class*="gdpr" i]',
+    '[aria-label*="cookie" i]', '[aria-label*="consent" i]',
+    '[role="dialog"]', '[aria-modal="true"]',
+  ].join(',');
+  const acceptText = /^(accept|accept all|accept all cookies|accept cookies|allow all|allow cookies|agree|i agree|i accept|got it|ok|okay|同意する|同意します|すべて同意|全て同意|同意して閉じる|許可する|すべて許可|閉じる)$/i;
+
+  const visible = (el) => {
+    const rect = el.getBoundingClientRect();
+    const style = window.getComputedStyle(el);
+    return rect.width > 0 && rect.height > 0 && style.visibility !== 'hidden' && style.display !== 'none';
+  };
+
+  let dismissed = false;
+  for (const selector of acceptSelectors) {
+    const button = document.querySelector(selector);
+    if (button && visible(button)) {
+      button.click();
+      dismissed = true;
+      break;
+    }
+  }
+  if (!dismissed) {
+    for (const container of document.querySelectorAll(containerSelector)) {
+      const text = (container.textContent || '').toLowerCase();
+      if (!/cookie|consent|gdpr|クッキー|同意/.test(text)) {
+        continue;
+      }
+      const button = Array.from(container.querySelectorAll('button, a[role="button"], [role="button"], input[type="button"], input[type="submit"]'))
+        .find((el) => visible(el) && acceptText.test((el.innerText || el.value || '').trim()));
+      if (button) {
+        button.click();
+        dismissed = true;
+        break;
+      }
+    }
+  }
+
+  // 同意した後も残るバナーや、ボタンが見つからないバナーは本文の抽出の対象にならないよう取り除く
+  for (const el of document.querySelectorAll(containerSelector)) {
+    if (el === document.body || el === document.documentElement || el.querySelector('article, main')) {
+      continue;
+    }
+    const position = window.getComputedStyle(el).position;
+    const text = (el.textContent || '').toLowerCase();
+    if ((position === 'fixed' || position === 'sticky') && /cookie|consent|gdpr|クッキー/.test(text)) {
+      el.remove();
+      dismissed = true;
+    }
+  }
+  return dismissed;
+}`
+
+// dismissConsentはCookieの同意ダイアログを閉じる
+// 同意によりページが再読み込みされる場合があるため、閉じた場合は読み込みを待つ
+func dismissConsent(page playwright.Page) error {
+	dismissed, err := page.Evaluate(dismissConsentScript)
+	if err != nil {
+		return fmt.Errorf("could not dismiss consent dialog: %v", err)
+	}
+	if ok, _ := dismissed.(bool); ok {
+		_ = page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+			State:   playwright.LoadStateDomcontentloaded,
+			Timeout: playwright.Float(float64(consentReloadTimeout.Milliseconds())),
+		})
+	}
+	return nil
+}
@@ -1,13 +1,15 @@
 package crawler
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
-	"strings"
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
+	"github.com/shoet/web-page-summarizer-task/pkg/extractor"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
 )
 
 type PageCrawler struct {
@@ -29,7 +31,7 @@ func NewPageCrawler(input *PageCrawlerInput) (*PageCrawler, error) {
 	return &PageCrawler{browser: browser}, nil
 }
 
-func (f *PageCrawler) FetchContents(url string) (string, string, error) {
+func (f *PageCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
 	page, err := f.FetchPage(url)
 	if err != nil {
 		return "", "", fmt.Errorf("Failed to fetch page: %w", err)
@@ -59,26 +61,15 @@ func BuildBrowser(browserPath string) (*rod.Browser, error) {
 	return browser, nil
 }
 
+// ScrapBodyはページのHTMLから本文を抽出し、タイトルとMarkdownの本文を返す
 func ScrapBody(page *rod.Page) (string, string, error) {
-	titles, err := page.Elements("h1")
+	html, err := page.HTML()
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get h1: %w", err)
+		return "", "", fmt.Errorf("failed to get html: %w", err)
 	}
-
-	titleBuilder := strings.Builder{}
-	for _, t := range titles {
-		titleBuilder.WriteString(t.MustText())
-	}
-
-	paragraphs, err := page.Elements("p")
+	article, err := extractor.ExtractString(html)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get p: %w", err)
+		return "", "", fmt.Errorf("failed to extract content: %w", err)
 	}
-
-	contentBuilder := strings.Builder{}
-	for _, p := range paragraphs {
-		contentBuilder.WriteString(p.MustText() + "\n")
-	}
-
-	return titleBuilder.String(), contentBuilder.String(), nil
+	return article.Title, article.Content, nil
 }
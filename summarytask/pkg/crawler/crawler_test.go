@@ -35,7 +35,7 @@ func Test_FetchPage(t *testing.T) {
 		t.Fatalf("failed to create PageCrawler: %v", err)
 	}
 
-	title, content, err := sut.FetchContents(url)
+	title, content, err := sut.FetchContents(context.Background(), url, nil)
 	if err != nil {
 		t.Fatalf("failed to fetch contents: %v", err)
 	}
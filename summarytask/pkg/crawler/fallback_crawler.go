@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
+)
+
+// ContentFetcherはURLのページのタイトルと本文を取得する
+type ContentFetcher interface {
+	FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error)
+}
+
+// BrowserFactoryはブラウザで取得するContentFetcherと、ブラウザを終了する関数を生成する
+type BrowserFactory func() (ContentFetcher, func() error, error)
+
+// SiteRulesはサイト固有の抽出ルールがあるかを返す
+type SiteRules interface {
+	HasRule(url string) bool
+}
+
+// FallbackCrawlerはHTTPでの取得を優先し、本文を抽出できない場合のみブラウザで取得する
+// ブラウザは必要になるまで起動しない
+type FallbackCrawler struct {
+	static     ContentFetcher
+	siteRules  SiteRules
+	newBrowser BrowserFactory
+
+	mu      sync.Mutex
+	browser ContentFetcher
+	closer  func() error
+}
+
+// NewFallbackCrawlerはFallbackCrawlerを生成する
+// siteRulesにルールがあるサイトは、セレクターで抽出するため最初からブラウザで取得する
+func NewFallbackCrawler(static ContentFetcher, siteRules SiteRules, newBrowser BrowserFactory) *FallbackCrawler {
+	return &FallbackCrawler{static: static, siteRules: siteRules, newBrowser: newBrowser}
+}
+
+func (c *FallbackCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	if c.static != nil && (c.siteRules == nil || !c.siteRules.HasRule(url)) {
+		title, content, err := c.static.FetchContents(ctx, url, options)
+		if err == nil {
+			return title, content, nil
+		}
+		if !browserRetryable(err) {
+			return "", "", err
+		}
+		// ボット対策でHTTPでの取得が拒否される場合もあるため、HTTPや本文の抽出のエラーはブラウザで取得し直す
+	}
+	if ctx.Err() != nil {
+		// キャンセルされたタスクのためにブラウザを起動しない
+		return "", "", fmt.Errorf("failed to fetch contents: %w", context.Cause(ctx))
+	}
+	browser, err := c.browserFetcher()
+	if err != nil {
+		return "", "", err
+	}
+	return browser.FetchContents(ctx, url, options)
+}
+
+// browserRetryableはHTTPでの取得のエラーをブラウザで取得し直すかを返す
+// キャンセルやタイムアウト、ポリシーで許可されていないURLは、ブラウザで取得しても同じ結果になる
+// PDFはブラウザで取得しても本文を抽出できない
+func browserRetryable(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, urlpolicy.ErrBlockedURL),
+		errors.Is(err, urlpolicy.ErrInvalidURL),
+		errors.Is(err, ErrUnreadablePDF):
+		return false
+	}
+	return true
+}
+
+func (c *FallbackCrawler) browserFetcher() (ContentFetcher, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.browser != nil {
+		return c.browser, nil
+	}
+	browser, closer, err := c.newBrowser()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+	c.browser, c.closer = browser, closer
+	return browser, nil
+}
+
+// Closeは起動したブラウザを終了する
+func (c *FallbackCrawler) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closer == nil {
+		return nil
+	}
+	err := c.closer()
+	c.browser, c.closer = nil, nil
+	return err
+}
@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
+)
+
+type fakeFetcher struct {
+	title   string
+	content string
+	err     error
+	calls   int
+}
+
+func (f *fakeFetcher) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	f.calls++
+	return f.title, f.content, f.err
+}
+
+type fakeSiteRules map[string]bool
+
+func (r fakeSiteRules) HasRule(url string) bool {
+	return r[url]
+}
+
+func Test_FallbackCrawler_FetchContents(t *testing.T) {
+	tests := []struct {
+		name         string
+		static       *fakeFetcher
+		url          string
+		wantTitle    string
+		wantStatic   int
+		wantBrowser  int
+		wantLaunched bool
+	}{
+		{
+			name:       "static",
+			static:     &fakeFetcher{title: "static"},
+			url:        "https://example.com/",
+			wantTitle:  "static",
+			wantStatic: 1,
+		},
+		{
+			name:         "browser required",
+			static:       &fakeFetcher{err: fmt.Errorf("%w: content is too short", ErrBrowserRequired)},
+			url:          "https://example.com/",
+			wantTitle:    "browser",
+			wantStatic:   1,
+			wantBrowser:  1,
+			wantLaunched: true,
+		},
+		{
+			name:         "site rule",
+			static:       &fakeFetcher{title: "static"},
+			url:          "https://news.example.com/",
+			wantTitle:    "browser",
+			wantBrowser:  1,
+			wantLaunched: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browser := &fakeFetcher{title: "browser"}
+			launched, closed := 0, 0
+			sut := NewFallbackCrawler(tt.static, fakeSiteRules{"https://news.example.com/": true},
+				func() (ContentFetcher, func() error, error) {
+					launched++
+					return browser, func() error { closed++; return nil }, nil
+				},
+			)
+			title, _, err := sut.FetchContents(context.Background(), tt.url, nil)
+			if err != nil {
+				t.Fatalf("FetchContents() error = %v", err)
+			}
+			if title != tt.wantTitle {
+				t.Errorf("title = %v, want %v", title, tt.wantTitle)
+			}
+			if tt.static.calls != tt.wantStatic || browser.calls != tt.wantBrowser {
+				t.Errorf("calls: static = %d, browser = %d", tt.static.calls, browser.calls)
+			}
+			if err := sut.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if (launched == 1) != tt.wantLaunched || launched != closed {
+				t.Errorf("browser is launched %d times and closed %d times", launched, closed)
+			}
+		})
+	}
+}
+
+func Test_FallbackCrawler_NoFallback(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		cancel bool
+		want   error
+	}{
+		{name: "unreadable pdf", err: fmt.Errorf("%w: pdf has no extractable text", ErrUnreadablePDF), want: ErrUnreadablePDF},
+		{name: "canceled", err: fmt.Errorf("failed to request: %w", context.Canceled), want: context.Canceled},
+		{name: "deadline exceeded", err: fmt.Errorf("failed to request: %w", context.DeadlineExceeded), want: context.DeadlineExceeded},
+		{name: "blocked url", err: fmt.Errorf("failed to request: %w", urlpolicy.ErrBlockedURL), want: urlpolicy.ErrBlockedURL},
+		{name: "invalid url", err: fmt.Errorf("failed to request: %w", urlpolicy.ErrInvalidURL), want: urlpolicy.ErrInvalidURL},
+		// HTTPのクライアントがキャンセルをラップしないエラーを返しても、ctxがキャンセルされていればブラウザで取得しない
+		{name: "ctx cancelled", err: errors.New("failed to request: connection reset"), cancel: true, want: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			static := &fakeFetcher{err: tt.err}
+			if tt.cancel {
+				cancel()
+			}
+			launched := 0
+			sut := NewFallbackCrawler(static, nil, func() (ContentFetcher, func() error, error) {
+				launched++
+				return &fakeFetcher{}, func() error { return nil }, nil
+			})
+			if _, _, err := sut.FetchContents(ctx, "https://example.com/", nil); !errors.Is(err, tt.want) {
+				t.Errorf("FetchContents() error = %v, want %v", err, tt.want)
+			}
+			if launched != 0 {
+				t.Errorf("browser should not be launched")
+			}
+		})
+	}
+}
+
+func Test_FallbackCrawler_LaunchOnce(t *testing.T) {
+	launched := 0
+	sut := NewFallbackCrawler(nil, nil, func() (ContentFetcher, func() error, error) {
+		launched++
+		return &fakeFetcher{}, func() error { return nil }, nil
+	})
+	for i := 0; i < 3; i++ {
+		if _, _, err := sut.FetchContents(context.Background(), "https://example.com/", nil); err != nil {
+			t.Fatalf("FetchContents() error = %v", err)
+		}
+	}
+	if launched != 1 {
+		t.Errorf("browser should be launched once: %d", launched)
+	}
+}
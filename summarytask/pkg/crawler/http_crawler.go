@@ -0,0 +1,244 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/document"
+	"github.com/shoet/web-page-summarizer-task/pkg/extractor"
+	"github.com/shoet/web-page-summarizer-task/pkg/pdftext"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// DefaultUserAgentはHTTPでページを取得する際のUser-Agent
+	DefaultUserAgent = "Mozilla/5.0 (compatible; WebPageSummarizer/1.0)"
+	// DefaultMinContentLengthはブラウザを使わずに取得した本文として扱う最小の文字数
+	DefaultMinContentLength = 500
+	// maxBodyBytesは取得するHTMLの最大のサイズ
+	maxBodyBytes = 10 << 20
+	// maxPDFBytesは取得するPDFの最大のサイズ
+	maxPDFBytes = 50 << 20
+)
+
+// ErrBrowserRequiredはHTTPの取得では本文を抽出できず、ブラウザでの取得が必要な場合のエラー
+var ErrBrowserRequired = errors.New("browser is required")
+
+// ErrUnreadablePDFはPDFからテキストを抽出できない場合のエラー
+// ブラウザで取得しても抽出できないため、ブラウザでの取得は行わない
+var ErrUnreadablePDF = errors.New("unreadable pdf")
+
+type HTTPCrawlerConfig struct {
+	UserAgent string
+	// MinContentLengthは本文の最小の文字数。これより短い場合はJavaScriptでの描画が必要とみなす
+	MinContentLength int
+	// PDFMaxPagesはPDFから抽出する最大のページ数
+	PDFMaxPages int
+}
+
+// HTTPCrawlerはブラウザを使わずにHTTPで取得したHTMLやPDFから本文を抽出する
+// サーバーで描画されたページはブラウザを起動するより高速に取得できる
+type HTTPCrawler struct {
+	client           *http.Client
+	userAgent        string
+	minContentLength int
+	pdfMaxPages      int
+}
+
+func NewHTTPCrawler(client *http.Client, config *HTTPCrawlerConfig) *HTTPCrawler {
+	c := &HTTPCrawler{
+		client:           client,
+		userAgent:        DefaultUserAgent,
+		minContentLength: DefaultMinContentLength,
+		pdfMaxPages:      pdftext.DefaultMaxPages,
+	}
+	if config != nil {
+		if config.UserAgent != "" {
+			c.userAgent = config.UserAgent
+		}
+		if config.MinContentLength > 0 {
+			c.minContentLength = config.MinContentLength
+		}
+		if config.PDFMaxPages > 0 {
+			c.pdfMaxPages = config.PDFMaxPages
+		}
+	}
+	return c
+}
+
+func (c *HTTPCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "ja,en;q=0.8")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	body := bufio.NewReader(resp.Body)
+	// スナップショットのために、文字コードを変換する前の読み込んだ内容をそのまま記録する
+	var raw bytes.Buffer
+	var r io.Reader = body
+	if options != nil && options.Capture != nil {
+		r = io.TeeReader(body, &raw)
+	}
+	var title, content string
+	switch {
+	case isPDF(contentType, body):
+		title, content, err = c.fetchPDF(r, options)
+	case isHTML(contentType):
+		title, content, err = c.fetchHTML(r, contentType)
+	default:
+		return "", "", fmt.Errorf("%w: unsupported content type: %s", ErrBrowserRequired, contentType)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if options != nil && options.Capture != nil {
+		*options.Capture = entities.PageCapture{
+			Url:         resp.Request.URL.String(),
+			ContentType: contentType,
+			Raw:         raw.Bytes(),
+		}
+	}
+	return title, content, nil
+}
+
+func (c *HTTPCrawler) fetchHTML(r io.Reader, contentType string) (string, string, error) {
+	// Shift_JISなどUTF-8以外のページはContent-Typeやmetaの指定に従ってUTF-8に変換する
+	reader, err := charset.NewReader(io.LimitReader(r, maxBodyBytes), contentType)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode charset: %w", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read body: %w", err)
+	}
+
+	if requiresJavaScript(body) {
+		return "", "", fmt.Errorf("%w: page requires javascript", ErrBrowserRequired)
+	}
+	article, err := extractor.Extract(bytes.NewReader(body))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to extract content: %w", err)
+	}
+	if n := utf8.RuneCountInString(article.Content); n < c.minContentLength {
+		return "", "", fmt.Errorf("%w: content is too short (%d characters)", ErrBrowserRequired, n)
+	}
+	return article.Title, article.Content, nil
+}
+
+// fetchPDFはPDFのページ範囲のテキストを本文として返す
+func (c *HTTPCrawler) fetchPDF(r io.Reader, options *entities.FetchOptions) (string, string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPDFBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(data) > maxPDFBytes {
+		return "", "", fmt.Errorf("%w: pdf is larger than %d bytes", ErrUnreadablePDF, maxPDFBytes)
+	}
+	var pages entities.PageRanges
+	if options != nil {
+		pages = options.Pages
+	}
+	title, content, err := document.ReadPDF(data, pages, c.pdfMaxPages)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrUnreadablePDF, err)
+	}
+	return title, content, nil
+}
+
+// isPDFはContent-Typeまたは本文の先頭からPDFかを判定する
+// application/octet-streamで配信されるPDFもあるため本文の先頭も確認する
+func isPDF(contentType string, body *bufio.Reader) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "application/pdf" {
+		return true
+	}
+	head, _ := body.Peek(5)
+	return bytes.Equal(head, []byte("%PDF-"))
+}
+
+// isHTMLはContent-TypeがHTMLかを返す。Content-Typeがない場合はHTMLとみなす
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// SPAのフレームワークがJavaScriptで描画する要素のid
+var appRootIds = map[string]bool{
+	"root":   true,
+	"app":    true,
+	"__next": true,
+	"__nuxt": true,
+}
+
+// requiresJavaScriptはページの描画にJavaScriptが必要かを返す
+// noscriptでJavaScriptの有効化を求めている場合や、SPAの描画先の要素が空の場合に必要とみなす
+func requiresJavaScript(body []byte) bool {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	noscript := 0
+	emptyRoot := ""
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.DataAtom == atom.Noscript && tt == html.StartTagToken {
+				noscript++
+			}
+			emptyRoot = ""
+			if token.DataAtom == atom.Div && tt == html.StartTagToken {
+				for _, a := range token.Attr {
+					if a.Key == "id" && appRootIds[a.Val] {
+						emptyRoot = a.Val
+					}
+				}
+			}
+		case html.EndTagToken:
+			token := z.Token()
+			if token.DataAtom == atom.Noscript && noscript > 0 {
+				noscript--
+			}
+			if token.DataAtom == atom.Div && emptyRoot != "" {
+				return true
+			}
+			emptyRoot = ""
+		case html.TextToken:
+			text := z.Text()
+			if noscript > 0 && strings.Contains(strings.ToLower(string(text)), "javascript") {
+				return true
+			}
+			if len(bytes.TrimSpace(text)) > 0 {
+				emptyRoot = ""
+			}
+		}
+	}
+}
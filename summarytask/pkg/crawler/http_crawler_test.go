@@ -0,0 +1,185 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func Test_HTTPCrawler_FetchContents(t *testing.T) {
+	paragraph := strings.Repeat("サーバーで描画されたページの本文です。", 10)
+	article := `<html><head><title>テストページ</title></head><body>
+<nav><a href="/">ホーム</a></nav>
+<article><h1>テストページ</h1><p>` + paragraph + `</p><p>` + paragraph + `</p></article>
+</body></html>`
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().String(
+		strings.Replace(article, "<head>", `<head><meta charset="Shift_JIS">`, 1),
+	)
+	if err != nil {
+		t.Fatalf("failed to encode shift_jis: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		wantTitle   string
+		wantErr     error
+	}{
+		{name: "server rendered", contentType: "text/html; charset=utf-8", body: article, wantTitle: "テストページ"},
+		{name: "shift_jis", contentType: "text/html", body: shiftJIS, wantTitle: "テストページ"},
+		{
+			name:        "too short",
+			contentType: "text/html",
+			body:        `<html><body><article><p>短い本文</p></article></body></html>`,
+			wantErr:     ErrBrowserRequired,
+		},
+		{
+			name:        "spa",
+			contentType: "text/html",
+			body:        `<html><body><div id="root"></div><script src="/app.js"></script></body></html>`,
+			wantErr:     ErrBrowserRequired,
+		},
+		{name: "not html", contentType: "application/json", body: `{}`, wantErr: ErrBrowserRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("User-Agent") != DefaultUserAgent {
+					t.Errorf("unexpected user agent: %s", r.Header.Get("User-Agent"))
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			t.Cleanup(server.Close)
+
+			sut := NewHTTPCrawler(server.Client(), &HTTPCrawlerConfig{MinContentLength: 100})
+			capture := &entities.PageCapture{}
+			title, content, err := sut.FetchContents(context.Background(), server.URL, &entities.FetchOptions{Capture: capture})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FetchContents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if title != tt.wantTitle {
+				t.Errorf("title = %v, want %v", title, tt.wantTitle)
+			}
+			// スナップショットには文字コードを変換する前の内容を記録する
+			if string(capture.Raw) != tt.body || capture.ContentType != tt.contentType || capture.Url != server.URL {
+				t.Errorf("unexpected capture: %s %s", capture.Url, capture.ContentType)
+			}
+			if !strings.Contains(content, paragraph) || strings.Contains(content, "ホーム") {
+				t.Errorf("unexpected content: %v", content)
+			}
+		})
+	}
+}
+
+func Test_HTTPCrawler_FetchContents_PDF(t *testing.T) {
+	data, err := os.ReadFile("../pdftext/testdata/whitepaper.pdf")
+	if err != nil {
+		t.Fatalf("failed to read pdf: %v", err)
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		options     *entities.FetchOptions
+		maxPages    int
+		wantContent string
+	}{
+		{
+			name:        "content type",
+			contentType: "application/pdf",
+			options:     &entities.FetchOptions{Pages: entities.PageRanges{{First: 3, Last: 3}}},
+			wantContent: "Conclusion page.",
+		},
+		{
+			name:        "octet stream",
+			contentType: "application/octet-stream",
+			maxPages:    1,
+			wantContent: "Introduction\n\nThis whitepaper describes the summarization pipeline.\n\n" +
+				"※ このPDFは全3ページのうち1ページのみを掲載しています。",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(data)
+			}))
+			t.Cleanup(server.Close)
+
+			sut := NewHTTPCrawler(server.Client(), &HTTPCrawlerConfig{PDFMaxPages: tt.maxPages})
+			title, content, err := sut.FetchContents(context.Background(), server.URL+"/whitepaper.pdf", tt.options)
+			if err != nil {
+				t.Fatalf("FetchContents() error = %v", err)
+			}
+			if title != "Test Whitepaper" {
+				t.Errorf("title = %v", title)
+			}
+			if content != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4\nbroken"))
+	}))
+	t.Cleanup(server.Close)
+	_, _, err = NewHTTPCrawler(server.Client(), nil).FetchContents(context.Background(), server.URL, nil)
+	if !errors.Is(err, ErrUnreadablePDF) {
+		t.Errorf("FetchContents() error = %v, want ErrUnreadablePDF", err)
+	}
+}
+
+func Test_HTTPCrawler_FetchContents_Status(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	sut := NewHTTPCrawler(server.Client(), nil)
+	if _, _, err := sut.FetchContents(context.Background(), server.URL, nil); err == nil {
+		t.Errorf("FetchContents() should fail with status 403")
+	}
+}
+
+func Test_requiresJavaScript(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{
+			name: "noscript message",
+			html: `<html><body><noscript>このサイトを利用するにはJavaScriptを有効にしてください</noscript><p>本文</p></body></html>`,
+			want: true,
+		},
+		{
+			name: "tag manager noscript",
+			html: `<html><body><noscript><iframe src="https://www.googletagmanager.com/ns.html"></iframe></noscript><p>本文</p></body></html>`,
+			want: false,
+		},
+		{name: "empty root", html: `<html><body><div id="__next">  </div></body></html>`, want: true},
+		{name: "rendered root", html: `<html><body><div id="__next"><p>本文</p></div></body></html>`, want: false},
+		{name: "plain", html: `<html><body><p>本文</p></body></html>`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requiresJavaScript([]byte(tt.html)); got != tt.want {
+				t.Errorf("requiresJavaScript() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
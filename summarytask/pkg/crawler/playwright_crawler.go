@@ -1,28 +1,88 @@
 package crawler
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	cp "github.com/otiai10/copy"
 	"github.com/playwright-community/playwright-go"
 	scraper "github.com/shoet/web-page-summarizer-task/pkg/scrapper"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// ScraperResolverはURLに対応するスクレイパーを選択する
+type ScraperResolver interface {
+	Lookup(url string) (scraper.PlaywrightScraper, error)
+}
+
+const (
+	// defaultNavigationTimeoutはページ表示までの既定のタイムアウト
+	defaultNavigationTimeout = 2 * time.Minute
+	// loadSettleTimeoutはdomcontentloadedなどで表示した後に、loadイベントを待つ最大の時間
+	// スクリプトで描画するページの本文を取得できるよう、短い時間だけ待つ
+	loadSettleTimeout = 3 * time.Second
+	// consentReloadTimeoutはCookieの同意ダイアログを閉じた後に再読み込みを待つ最大の時間
+	consentReloadTimeout = 5 * time.Second
 )
 
 type PlaywrightClient struct {
-	browser playwright.Browser
+	browser           playwright.Browser
+	scrapers          ScraperResolver
+	userAgent         string
+	urlPolicy         URLPolicy
+	blocker           *resourceBlocker
+	waitUntil         string
+	domainWaitUntil   map[string]string
+	navigationTimeout time.Duration
+	dismissConsent    bool
+	captureScreenshot bool
+	captureMHTML      bool
 }
 
 type PlaywrightClientConfig struct {
 	SkipInstallBrowsers     bool
 	BrowserLaunchTimeoutSec int
+	// Scrapersは未指定の場合scraper.DefaultRegistryを利用する
+	Scrapers ScraperResolver
+	// UserAgentはページを表示する際のUser-Agent。空の場合はDefaultUserAgentを利用する
+	UserAgent string
+	// URLPolicyが指定された場合は、ページの遷移やリダイレクト、サブリソースのリクエストを検証して許可されていないものを中断する
+	URLPolicy URLPolicy
+	// ProxyServerはブラウザのすべての接続を中継するプロキシのURL
+	// ブラウザ自身の名前解決では検証後に接続先を変えられるため、接続先を検証するプロキシ(urlpolicy.Proxy)を指定する
+	ProxyServer string
+	// BlockedResourceTypesはページの表示の際に取得しないリソースの種類(image、media、fontなど)
+	BlockedResourceTypes []string
+	// BlockedDomainsはページの表示の際に取得しない広告やトラッキングのドメイン。サブドメインも対象とする
+	BlockedDomains []string
+	// WaitUntilはページの表示の完了とみなすイベント(load、domcontentloaded、networkidle、commit)。空の場合はload
+	WaitUntil string
+	// DomainWaitUntilはドメインごとのWaitUntil。サブドメインも対象とし、最も長く一致するドメインの設定を利用する
+	DomainWaitUntil map[string]string
+	// NavigationTimeoutはページ表示までのタイムアウト。0の場合は2分
+	NavigationTimeout time.Duration
+	// DismissConsentがtrueの場合は本文の抽出の前にCookieの同意ダイアログを閉じる
+	DismissConsent bool
+	// CaptureScreenshotとCaptureMHTMLは、FetchOptions.Captureが指定された場合にスクリーンショットとMHTMLも記録するか
+	// BlockedResourceTypesで中断した画像などはスクリーンショットとMHTMLにも含まれない
+	CaptureScreenshot bool
+	CaptureMHTML      bool
 }
 
 func NewPlaywrightClient(
 	config *PlaywrightClientConfig,
 ) (client *PlaywrightClient, closerFunc func() error, err error) {
+	// 設定の誤りはブラウザを起動する前に検出する
+	waitUntil, domainWaitUntil, err := parseWaitUntil(config.WaitUntil, config.DomainWaitUntil)
+	if err != nil {
+		return nil, nil, err
+	}
 	browserBaseDir := "/tmp/playwright/browser"
 	runOption := &playwright.RunOptions{
 		SkipInstallBrowsers: config.SkipInstallBrowsers,
@@ -67,6 +127,13 @@ func NewPlaywrightClient(
 	if runtime.GOOS != "linux" {
 		chromiumOptions = playwright.BrowserTypeLaunchOptions{}
 	}
+	if config.ProxyServer != "" {
+		chromiumOptions.Proxy = &playwright.Proxy{
+			Server: config.ProxyServer,
+			// ループバックアドレスへのリクエストもプロキシを経由させ、直接接続させない
+			Bypass: playwright.String("<-loopback>"),
+		}
+	}
 	browser, err := pw.Chromium.Launch(chromiumOptions)
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not launch browser: %v", err)
@@ -80,8 +147,30 @@ func NewPlaywrightClient(
 		}
 		return nil
 	}
+	var scrapers ScraperResolver = scraper.DefaultRegistry
+	if config.Scrapers != nil {
+		scrapers = config.Scrapers
+	}
+	userAgent := DefaultUserAgent
+	if config.UserAgent != "" {
+		userAgent = config.UserAgent
+	}
+	navigationTimeout := defaultNavigationTimeout
+	if config.NavigationTimeout > 0 {
+		navigationTimeout = config.NavigationTimeout
+	}
 	return &PlaywrightClient{
-		browser: browser,
+		browser:           browser,
+		scrapers:          scrapers,
+		userAgent:         userAgent,
+		urlPolicy:         config.URLPolicy,
+		blocker:           newResourceBlocker(config.BlockedResourceTypes, config.BlockedDomains),
+		waitUntil:         waitUntil,
+		domainWaitUntil:   domainWaitUntil,
+		navigationTimeout: navigationTimeout,
+		dismissConsent:    config.DismissConsent,
+		captureScreenshot: config.CaptureScreenshot,
+		captureMHTML:      config.CaptureMHTML,
 	}, closer, nil
 }
 
@@ -98,32 +187,144 @@ func CopyBrowser() (string, error) {
 }
 
 func (p *PlaywrightClient) FetchPage(url string) (playwright.Page, error) {
-	page, err := p.browser.NewPage()
+	return p.FetchPageContext(context.Background(), url)
+}
+
+// FetchPageContextはctxがキャンセルされた場合にページを閉じ、表示中の遷移を中断する
+func (p *PlaywrightClient) FetchPageContext(ctx context.Context, url string) (playwright.Page, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("could not create page: %w", context.Cause(ctx))
+	}
+	page, err := p.browser.NewPage(playwright.BrowserNewPageOptions{
+		UserAgent: playwright.String(p.userAgent),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("could not create page: %v", err)
 	}
+	var guard *requestGuard
+	if p.urlPolicy != nil {
+		guard = newRequestGuard(ctx, p.urlPolicy)
+		if err := guard.install(page); err != nil {
+			page.Close()
+			return nil, err
+		}
+	}
+	// requestGuardより後に登録し、中断しないリクエストのみrequestGuardで検証する
+	if err := p.blocker.install(page); err != nil {
+		page.Close()
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		page.Close()
+	})
+	defer stop()
+	waitUntil := p.lookupWaitUntil(url)
+	waitUntilState := playwright.WaitUntilState(waitUntil)
 	pageGotoOptions := playwright.PageGotoOptions{
-		Timeout: playwright.Float(120000), // ページ表示までのタイムアウト: 2分
+		Timeout:   playwright.Float(float64(p.navigationTimeout.Milliseconds())),
+		WaitUntil: &waitUntilState,
 	}
 	_, err = page.Goto(url, pageGotoOptions)
+	if ctx.Err() != nil {
+		// ページを閉じたことによるGotoのエラーではなく、キャンセルの理由を返す
+		page.Close()
+		return nil, fmt.Errorf("could not goto page: %w", context.Cause(ctx))
+	}
+	if guard != nil {
+		// 遷移の中断によるGotoのエラーよりも、ポリシーのエラーを優先して返す
+		if blockedErr := guard.err(); blockedErr != nil {
+			page.Close()
+			return nil, fmt.Errorf("could not goto page: %w", blockedErr)
+		}
+		if err == nil {
+			if checkErr := guard.check(page.URL()); checkErr != nil {
+				page.Close()
+				return nil, fmt.Errorf("could not goto page: %w", checkErr)
+			}
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("could not goto page: %v", err)
+		page.Close()
+		return nil, fmt.Errorf("could not goto page: %w", err)
+	}
+	if waitUntil == "domcontentloaded" || waitUntil == "commit" {
+		// loadイベントまで待たずに抽出できるよう、待てなかった場合もそのまま抽出する
+		_ = page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+			State:   playwright.LoadStateLoad,
+			Timeout: playwright.Float(float64(loadSettleTimeout.Milliseconds())),
+		})
+	}
+	if p.dismissConsent {
+		// 同意ダイアログを閉じられなくても本文は抽出できるため、エラーにしない
+		_ = dismissConsent(page)
 	}
 	return page, nil
 }
 
-func (p *PlaywrightClient) FetchContents(url string) (string, string, error) {
-	page, err := p.FetchPage(url)
+// lookupWaitUntilはurlのドメインに対応するWaitUntilを返す
+func (p *PlaywrightClient) lookupWaitUntil(rawURL string) string {
+	u, err := url.Parse(rawURL)
 	if err != nil {
-		return "", "", fmt.Errorf("could not fetch page: %v", err)
+		return p.waitUntil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	waitUntil, matched := p.waitUntil, ""
+	for domain, w := range p.domainWaitUntil {
+		if len(domain) > len(matched) && matchDomain([]string{domain}, host) {
+			waitUntil, matched = w, domain
+		}
+	}
+	return waitUntil
+}
+
+// parseWaitUntilはWaitUntilの設定を検証し、ドメインを小文字にそろえる
+func parseWaitUntil(waitUntil string, domainWaitUntil map[string]string) (string, map[string]string, error) {
+	validate := func(w string) (string, error) {
+		w = strings.ToLower(strings.TrimSpace(w))
+		switch w {
+		case "":
+			return "load", nil
+		case "load", "domcontentloaded", "networkidle", "commit":
+			return w, nil
+		}
+		return "", fmt.Errorf("invalid wait until: %q", w)
+	}
+	defaultWaitUntil, err := validate(waitUntil)
+	if err != nil {
+		return "", nil, err
+	}
+	domains := map[string]string{}
+	for domain, w := range domainWaitUntil {
+		if w, err = validate(w); err != nil {
+			return "", nil, fmt.Errorf("%s: %w", domain, err)
+		}
+		domains[strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), ".")] = w
+	}
+	return defaultWaitUntil, domains, nil
+}
+
+// FetchContentsはブラウザで表示したページから本文を抽出する
+// optionsのPDFのページ範囲はHTTPでの取得のための設定のため利用しない
+func (p *PlaywrightClient) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	page, err := p.FetchPageContext(ctx, url)
+	if err != nil {
+		return "", "", fmt.Errorf("could not fetch page: %w", err)
+	}
+	// ブラウザはタスクをまたいで利用するため、抽出が終わったページは閉じる
+	defer page.Close()
+	if options != nil && options.Capture != nil {
+		// スナップショットを保存できなくても要約はできるため、取得できない場合もそのまま抽出する
+		if capture, err := p.capturePage(page); err == nil {
+			*options.Capture = *capture
+		}
 	}
-	scraper, err := scraper.NewPlaywrightScraper(url)
+	scraper, err := p.scrapers.Lookup(url)
 	if err != nil {
 		return "", "", fmt.Errorf("could not create scraper: %v", err)
 	}
 	title, body, err := scraper.Scrape(page)
 	if err != nil {
-		return "", "", fmt.Errorf("could not close page: %v", err)
+		return "", "", fmt.Errorf("could not scrape page: %w", err)
 	}
 	return title, body, nil
 }
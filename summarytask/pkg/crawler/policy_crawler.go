@@ -0,0 +1,31 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// PolicyCrawlerは取得する前にURLをURLPolicyで検証する
+// APIで受け付けた後にポリシーが変更された場合や、名前解決の結果が変わった場合も取得しない
+type PolicyCrawler struct {
+	next   ContentFetcher
+	policy URLPolicy
+}
+
+func NewPolicyCrawler(next ContentFetcher, policy URLPolicy) *PolicyCrawler {
+	return &PolicyCrawler{next: next, policy: policy}
+}
+
+func (c *PolicyCrawler) FetchContents(ctx context.Context, rawURL string, options *entities.FetchOptions) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	if err := c.policy.CheckURL(ctx, u); err != nil {
+		return "", "", err
+	}
+	return c.next.FetchContents(ctx, rawURL, options)
+}
@@ -0,0 +1,69 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+var errBlocked = errors.New("blocked")
+
+// hostPolicyは指定したホストのみを拒否するURLPolicy
+type hostPolicy struct {
+	blocked string
+}
+
+func (p *hostPolicy) CheckURL(ctx context.Context, u *url.URL) error {
+	if u.Hostname() == p.blocked {
+		return errBlocked
+	}
+	return nil
+}
+
+type stubFetcher struct {
+	calls int
+}
+
+func (f *stubFetcher) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	f.calls++
+	return "title", "content", nil
+}
+
+func Test_PolicyCrawler_FetchContents(t *testing.T) {
+	next := &stubFetcher{}
+	sut := NewPolicyCrawler(next, &hostPolicy{blocked: "169.254.169.254"})
+
+	if _, _, err := sut.FetchContents(context.Background(), "http://169.254.169.254/latest/meta-data/", nil); !errors.Is(err, errBlocked) {
+		t.Errorf("FetchContents() error = %v, want errBlocked", err)
+	}
+	if next.calls != 0 {
+		t.Errorf("blocked url should not be fetched")
+	}
+	if _, _, err := sut.FetchContents(context.Background(), "https://example.com/", nil); err != nil {
+		t.Errorf("FetchContents() error = %v", err)
+	}
+	if next.calls != 1 {
+		t.Errorf("calls = %d, want 1", next.calls)
+	}
+}
+
+func Test_requestGuard_check(t *testing.T) {
+	sut := newRequestGuard(context.Background(), &hostPolicy{blocked: "internal.example.com"})
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://example.com/image.png"},
+		{url: "data:image/png;base64,AAAA"},
+		{url: "blob:https://example.com/0f0e"},
+		{url: "http://internal.example.com/admin", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := sut.check(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("check(%s) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
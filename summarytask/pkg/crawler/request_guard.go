@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// URLPolicyはブラウザが取得するURLと接続先を検証する
+type URLPolicy interface {
+	CheckURL(ctx context.Context, u *url.URL) error
+}
+
+// requestGuardはブラウザのリクエストをURLPolicyで検証し、許可されていないリクエストを中断する
+// ページの遷移が中断された場合はそのエラーを記録し、ページの取得を失敗させる
+type requestGuard struct {
+	// ctxはページを取得するタスクのcontext。キャンセルされた場合は名前解決を中断する
+	ctx    context.Context
+	policy URLPolicy
+
+	mu      sync.Mutex
+	blocked error
+}
+
+func newRequestGuard(ctx context.Context, policy URLPolicy) *requestGuard {
+	return &requestGuard{ctx: ctx, policy: policy}
+}
+
+// installはページのすべてのリクエストを検証するように設定する
+func (g *requestGuard) install(page playwright.Page) error {
+	if err := page.Route("**/*", g.route); err != nil {
+		return fmt.Errorf("could not route requests: %v", err)
+	}
+	// リダイレクト先のリクエストはRouteを経由しない場合があるため、発行されたリクエストも検証する
+	page.OnRequest(func(req playwright.Request) {
+		if req.RedirectedFrom() == nil {
+			return
+		}
+		if err := g.check(req.URL()); err != nil && req.IsNavigationRequest() {
+			g.block(err)
+		}
+	})
+	return nil
+}
+
+func (g *requestGuard) route(route playwright.Route) {
+	req := route.Request()
+	if err := g.check(req.URL()); err != nil {
+		if req.IsNavigationRequest() {
+			g.block(err)
+		}
+		_ = route.Abort("blockedbyclient")
+		return
+	}
+	_ = route.Continue()
+}
+
+// checkはURLを検証する。ネットワークに接続しないdataやblobのURLは検証しない
+func (g *requestGuard) check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("could not parse url: %v", err)
+	}
+	switch u.Scheme {
+	case "data", "blob", "about":
+		return nil
+	}
+	return g.policy.CheckURL(g.ctx, u)
+}
+
+func (g *requestGuard) block(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.blocked == nil {
+		g.blocked = err
+	}
+}
+
+func (g *requestGuard) err() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.blocked
+}
@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// DefaultBlockedDomainsは本文の抽出に不要な広告やトラッキングのドメイン。サブドメインも対象とする
+var DefaultBlockedDomains = []string{
+	"doubleclick.net",
+	"googlesyndication.com",
+	"googleadservices.com",
+	"google-analytics.com",
+	"googletagmanager.com",
+	"googletagservices.com",
+	"adservice.google.com",
+	"amazon-adsystem.com",
+	"adnxs.com",
+	"criteo.com",
+	"criteo.net",
+	"taboola.com",
+	"outbrain.com",
+	"scorecardresearch.com",
+	"quantserve.com",
+	"hotjar.com",
+	"connect.facebook.net",
+	"ads-twitter.com",
+	"analytics.tiktok.com",
+	"clarity.ms",
+	"microad.jp",
+	"i-mobile.co.jp",
+	"adingo.jp",
+}
+
+// resourceBlockerは指定した種類のリソースと広告やトラッキングのドメインへのリクエストを中断する
+// ページ自体の遷移は中断しない
+type resourceBlocker struct {
+	resourceTypes map[string]bool
+	domains       []string
+}
+
+func newResourceBlocker(resourceTypes []string, domains []string) *resourceBlocker {
+	b := &resourceBlocker{resourceTypes: map[string]bool{}}
+	for _, t := range resourceTypes {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			b.resourceTypes[t] = true
+		}
+	}
+	for _, d := range domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			b.domains = append(b.domains, strings.TrimPrefix(d, "."))
+		}
+	}
+	return b
+}
+
+// installはページのリクエストを中断するように設定する
+// Routeは後に登録したハンドラーから呼ばれるため、requestGuardより後に登録すると中断しないリクエストはrequestGuardで検証される
+func (b *resourceBlocker) install(page playwright.Page) error {
+	if len(b.resourceTypes) == 0 && len(b.domains) == 0 {
+		return nil
+	}
+	if err := page.Route("**/*", b.route); err != nil {
+		return fmt.Errorf("could not route requests: %v", err)
+	}
+	return nil
+}
+
+func (b *resourceBlocker) route(route playwright.Route) {
+	req := route.Request()
+	if !req.IsNavigationRequest() && b.blocked(req.ResourceType(), req.URL()) {
+		_ = route.Abort("blockedbyclient")
+		return
+	}
+	_ = route.Fallback()
+}
+
+// blockedはリソースの種類とURLから取得しないリクエストかを返す
+func (b *resourceBlocker) blocked(resourceType string, rawURL string) bool {
+	if resourceType == "document" {
+		return false
+	}
+	if b.resourceTypes[resourceType] {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	return matchDomain(b.domains, host)
+}
+
+// matchDomainはhostがdomainsのいずれかのドメインまたはそのサブドメインかを返す
+func matchDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/playwright-community/playwright-go"
+	scraper "github.com/shoet/web-page-summarizer-task/pkg/scrapper"
+)
+
+func Test_resourceBlocker_blocked(t *testing.T) {
+	sut := newResourceBlocker([]string{"image", " Font "}, append([]string{".tracker.example"}, DefaultBlockedDomains...))
+	tests := []struct {
+		resourceType string
+		url          string
+		want         bool
+	}{
+		{resourceType: "document", url: "https://example.com/article", want: false},
+		{resourceType: "script", url: "https://example.com/app.js", want: false},
+		{resourceType: "image", url: "https://example.com/photo.jpg", want: true},
+		{resourceType: "font", url: "https://example.com/font.woff2", want: true},
+		{resourceType: "stylesheet", url: "https://example.com/style.css", want: false},
+		{resourceType: "script", url: "https://www.googletagmanager.com/gtm.js", want: true},
+		{resourceType: "xhr", url: "https://securepubads.g.doubleclick.net/gampad/ads", want: true},
+		{resourceType: "script", url: "https://cdn.tracker.example/t.js", want: true},
+		{resourceType: "script", url: "https://nottracker.example/t.js", want: false},
+		// ドキュメントはドメインに関わらず中断しない
+		{resourceType: "document", url: "https://ad.doubleclick.net/frame", want: false},
+	}
+	for _, tt := range tests {
+		if got := sut.blocked(tt.resourceType, tt.url); got != tt.want {
+			t.Errorf("blocked(%s, %s) = %v, want %v", tt.resourceType, tt.url, got, tt.want)
+		}
+	}
+}
+
+func Test_PlaywrightClient_lookupWaitUntil(t *testing.T) {
+	waitUntil, domains, err := parseWaitUntil("domcontentloaded", map[string]string{
+		"Example.com":      "networkidle",
+		"news.example.com": "load",
+	})
+	if err != nil {
+		t.Fatalf("parseWaitUntil() error = %v", err)
+	}
+	sut := &PlaywrightClient{waitUntil: waitUntil, domainWaitUntil: domains}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://example.org/", want: "domcontentloaded"},
+		{url: "https://example.com/", want: "networkidle"},
+		{url: "https://www.example.com/", want: "networkidle"},
+		{url: "https://news.example.com/article", want: "load"},
+	}
+	for _, tt := range tests {
+		if got := sut.lookupWaitUntil(tt.url); got != tt.want {
+			t.Errorf("lookupWaitUntil(%s) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+
+	if w, _, err := parseWaitUntil("", nil); err != nil || w != "load" {
+		t.Errorf("parseWaitUntil() = %s, %v, want load", w, err)
+	}
+	if _, _, err := parseWaitUntil("idle", nil); err == nil {
+		t.Errorf("parseWaitUntil() should fail for unknown state")
+	}
+	if _, _, err := parseWaitUntil("load", map[string]string{"example.com": "ready"}); err == nil {
+		t.Errorf("parseWaitUntil() should fail for unknown domain state")
+	}
+}
+
+// fakeBrowserは開いたページを記録する。未実装のメソッドを呼び出した場合はpanicする
+type fakeBrowser struct {
+	playwright.Browser
+	mu    sync.Mutex
+	pages []*fakePage
+}
+
+func (b *fakeBrowser) NewPage(options ...playwright.BrowserNewPageOptions) (playwright.Page, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	page := &fakePage{}
+	b.pages = append(b.pages, page)
+	return page, nil
+}
+
+// openPagesは閉じられていないページの数を返す
+func (b *fakeBrowser) openPages() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	open := 0
+	for _, page := range b.pages {
+		if !page.isClosed() {
+			open++
+		}
+	}
+	return open
+}
+
+type fakePage struct {
+	playwright.Page
+	mu     sync.Mutex
+	url    string
+	closed bool
+}
+
+func (p *fakePage) Goto(url string, options ...playwright.PageGotoOptions) (playwright.Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.url = url
+	return nil, nil
+}
+
+func (p *fakePage) URL() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.url
+}
+
+func (p *fakePage) Close(options ...playwright.PageCloseOptions) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *fakePage) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+type fakeScraper struct {
+	err error
+}
+
+func (s *fakeScraper) Scrape(page playwright.Page) (string, string, error) {
+	return "title", "body", s.err
+}
+
+type fakeScraperResolver struct {
+	scraper *fakeScraper
+}
+
+func (r *fakeScraperResolver) Lookup(url string) (scraper.PlaywrightScraper, error) {
+	return r.scraper, nil
+}
+
+func Test_PlaywrightClient_FetchContents_ClosesPage(t *testing.T) {
+	tests := []struct {
+		name    string
+		scraper *fakeScraper
+		wantErr bool
+	}{
+		{name: "scraped", scraper: &fakeScraper{}},
+		{name: "failed to scrape", scraper: &fakeScraper{err: errors.New("no body")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			browser := &fakeBrowser{}
+			sut := &PlaywrightClient{
+				browser:   browser,
+				scrapers:  &fakeScraperResolver{scraper: tt.scraper},
+				blocker:   newResourceBlocker(nil, nil),
+				waitUntil: "load",
+			}
+			for i := 0; i < 3; i++ {
+				_, _, err := sut.FetchContents(context.Background(), "https://example.com/", nil)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("FetchContents() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+			if len(browser.pages) != 3 {
+				t.Fatalf("pages = %d, want 3", len(browser.pages))
+			}
+			if open := browser.openPages(); open != 0 {
+				t.Errorf("open pages = %d, want 0", open)
+			}
+		})
+	}
+}
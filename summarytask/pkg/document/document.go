@@ -0,0 +1,114 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/extractor"
+	"github.com/shoet/web-page-summarizer-task/pkg/pdftext"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"golang.org/x/net/html/charset"
+)
+
+// pdfTruncatedNoticeは一部のページのみを要約することをLLMに伝えるため本文の末尾に追加するテキスト
+const pdfTruncatedNotice = "※ このPDFは全%dページのうち%dページのみを掲載しています。"
+
+// ErrEmptyDocumentは文書から本文を抽出できない場合のエラー
+var ErrEmptyDocument = errors.New("document has no content")
+
+type ReaderConfig struct {
+	// PDFMaxPagesはPDFから抽出する最大のページ数
+	PDFMaxPages int
+}
+
+// Readerはアップロードされた文書からタイトルと本文を抽出する
+type Reader struct {
+	pdfMaxPages int
+}
+
+func NewReader(config *ReaderConfig) *Reader {
+	r := &Reader{pdfMaxPages: pdftext.DefaultMaxPages}
+	if config != nil && config.PDFMaxPages > 0 {
+		r.pdfMaxPages = config.PDFMaxPages
+	}
+	return r
+}
+
+// ReadDocumentは文書の形式に応じて本文を抽出する
+// HTMLは本文をMarkdownに変換し、テキストとMarkdownはそのまま本文とする
+// タイトルを抽出できない場合はファイル名をタイトルとする
+func (r *Reader) ReadDocument(document *entities.UploadedDocument, options *entities.FetchOptions) (string, string, error) {
+	var title, content string
+	var err error
+	switch document.ContentType {
+	case entities.UploadContentTypeText:
+		content = strings.TrimPrefix(string(document.Data), "\ufeff")
+	case entities.UploadContentTypeMarkdown:
+		content = strings.TrimPrefix(string(document.Data), "\ufeff")
+		title = markdownTitle(content)
+	case entities.UploadContentTypeHTML:
+		title, content, err = readHTML(document.Data)
+	case entities.UploadContentTypePDF:
+		var pages entities.PageRanges
+		if options != nil {
+			pages = options.Pages
+		}
+		title, content, err = ReadPDF(document.Data, pages, r.pdfMaxPages)
+	default:
+		return "", "", fmt.Errorf("unsupported content type: %s", document.ContentType)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", "", ErrEmptyDocument
+	}
+	if title == "" && document.FileName != "" {
+		title = strings.TrimSuffix(document.FileName, path.Ext(document.FileName))
+	}
+	return title, content, nil
+}
+
+// ReadPDFはPDFのページ範囲のテキストを本文として返す
+// maxPagesを超えるため一部のページのみを抽出した場合は、その旨を本文の末尾に追加する
+func ReadPDF(data []byte, pages entities.PageRanges, maxPages int) (string, string, error) {
+	doc, err := pdftext.Extract(bytes.NewReader(data), int64(len(data)), &pdftext.Options{
+		Pages:    pages,
+		MaxPages: maxPages,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	content := doc.Content
+	if doc.Truncated {
+		content += "\n\n" + fmt.Sprintf(pdfTruncatedNotice, doc.NumPages, len(doc.Pages))
+	}
+	return doc.Title, content, nil
+}
+
+func readHTML(data []byte) (string, string, error) {
+	// Shift_JISなどUTF-8以外の文書はmetaの指定に従ってUTF-8に変換する
+	reader, err := charset.NewReader(bytes.NewReader(data), "text/html")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to decode charset: %w", err)
+	}
+	article, err := extractor.Extract(reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to extract content: %w", err)
+	}
+	return article.Title, article.Content, nil
+}
+
+// markdownTitleは最初の見出しをタイトルとして返す
+func markdownTitle(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
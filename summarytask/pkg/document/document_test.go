@@ -0,0 +1,82 @@
+package document
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func Test_Reader_ReadDocument(t *testing.T) {
+	pdf, err := os.ReadFile("../pdftext/testdata/whitepaper.pdf")
+	if err != nil {
+		t.Fatalf("failed to read pdf: %v", err)
+	}
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().String(
+		`<html><head><meta charset="Shift_JIS"><title>社内ポータル</title></head>` +
+			`<body><article><h1>社内ポータル</h1><p>社内向けのお知らせです。</p></article></body></html>`,
+	)
+	if err != nil {
+		t.Fatalf("failed to encode shift_jis: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		document    *entities.UploadedDocument
+		options     *entities.FetchOptions
+		wantTitle   string
+		wantContent string
+		wantErr     error
+	}{
+		{
+			name:        "text",
+			document:    &entities.UploadedDocument{FileName: "memo.txt", ContentType: entities.UploadContentTypeText, Data: []byte("\ufeff社内メモ\n")},
+			wantTitle:   "memo",
+			wantContent: "社内メモ",
+		},
+		{
+			name:        "markdown",
+			document:    &entities.UploadedDocument{ContentType: entities.UploadContentTypeMarkdown, Data: []byte("# 設計書\n\n本文")},
+			wantTitle:   "設計書",
+			wantContent: "# 設計書\n\n本文",
+		},
+		{
+			name:        "html",
+			document:    &entities.UploadedDocument{ContentType: entities.UploadContentTypeHTML, Data: []byte(shiftJIS)},
+			wantTitle:   "社内ポータル",
+			wantContent: "社内向けのお知らせです。",
+		},
+		{
+			name:        "pdf",
+			document:    &entities.UploadedDocument{ContentType: entities.UploadContentTypePDF, Data: pdf},
+			options:     &entities.FetchOptions{Pages: entities.PageRanges{{First: 3, Last: 3}}},
+			wantTitle:   "Test Whitepaper",
+			wantContent: "Conclusion page.",
+		},
+		{
+			name:     "empty",
+			document: &entities.UploadedDocument{ContentType: entities.UploadContentTypeText, Data: []byte(" \n")},
+			wantErr:  ErrEmptyDocument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			title, content, err := NewReader(nil).ReadDocument(tt.document, tt.options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if title != tt.wantTitle {
+				t.Errorf("title = %v, want %v", title, tt.wantTitle)
+			}
+			if !strings.Contains(content, tt.wantContent) {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+/*
+extractorはWebページのHTMLから本文を抽出してMarkdownに変換する
+
+Readabilityと同様に段落ごとのテキスト量と句読点の数をスコアとして親要素に加算し、
+スコアが最も高い要素を本文とする。ナビゲーションやフッター、Cookieのバナー、
+コメント欄などは事前に取り除き、見出し、リスト、表、コードブロックはMarkdownとして残す
+*/
+package extractor
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Articleはページから抽出した記事
+type Article struct {
+	Title string
+	// ContentはMarkdownに変換した本文
+	Content string
+}
+
+// ExtractはHTMLを解析して記事のタイトルとMarkdownの本文を抽出する
+func Extract(r io.Reader) (*Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+	root := findFirst(doc, atom.Body)
+	if root == nil {
+		root = doc
+	}
+	// タイトルを探す前にヘッダーやナビゲーションを取り除き、サイト名のh1を除外する
+	removeUnlikely(root)
+	title := extractTitle(doc)
+
+	nodes := selectContent(root)
+	for _, n := range nodes {
+		removeLinkLists(n)
+	}
+	return &Article{
+		Title:   title,
+		Content: renderMarkdown(nodes, title),
+	}, nil
+}
+
+// ExtractStringは文字列のHTMLから記事を抽出する
+func ExtractString(s string) (*Article, error) {
+	return Extract(strings.NewReader(s))
+}
+
+// ToMarkdownはHTMLの断片全体をMarkdownに変換する
+// サイト固有のセレクターで本文の要素を特定済みの場合に利用し、スコアによる本文の選択は行わない
+func ToMarkdown(s string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+	root := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	removeUnlikely(root)
+	return renderMarkdown([]*html.Node{root}, ""), nil
+}
+
+// 本文ではない可能性が高い要素
+var unlikelyAtoms = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Svg:      true,
+	atom.Button:   true,
+	atom.Input:    true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Template: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Canvas:   true,
+	atom.Dialog:   true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+var unlikelyRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+	"dialog":        true,
+	"alertdialog":   true,
+	"menu":          true,
+	"menubar":       true,
+}
+
+var (
+	unlikelyPattern = regexp.MustCompile(`(?i)-ad-|ad-break|agegate|banner|breadcrumb|combx|comment|community|consent|cookie|disqus|extra|footer|gdpr|header|legends|menu|newsletter|pager|pagination|popup|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental`)
+	likelyPattern   = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positivePattern = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativePattern = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+	hiddenPattern   = regexp.MustCompile(`(?i)display\s*:\s*none|visibility\s*:\s*hidden`)
+)
+
+// removeUnlikelyはスクリプトやナビゲーション、非表示の要素など本文ではない要素を取り除く
+func removeUnlikely(root *html.Node) {
+	var remove []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.CommentNode {
+				remove = append(remove, c)
+				continue
+			}
+			if c.Type != html.ElementNode {
+				continue
+			}
+			if isUnlikely(c) {
+				remove = append(remove, c)
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(root)
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	if unlikelyAtoms[n.DataAtom] {
+		return true
+	}
+	// 記事内のheaderはタイトルを含むため残す
+	if n.DataAtom == atom.Header && !hasAncestor(n, atom.Article, atom.Main) {
+		return true
+	}
+	if _, ok := attr(n, "hidden"); ok {
+		return true
+	}
+	if v, _ := attr(n, "aria-hidden"); v == "true" {
+		return true
+	}
+	if v, _ := attr(n, "style"); hiddenPattern.MatchString(v) {
+		return true
+	}
+	if v, _ := attr(n, "role"); unlikelyRoles[v] {
+		return true
+	}
+	switch n.DataAtom {
+	case atom.Body, atom.Article, atom.Main, atom.A, atom.Pre, atom.Code, atom.Table:
+		return false
+	}
+	matchString := classAndId(n)
+	return unlikelyPattern.MatchString(matchString) && !likelyPattern.MatchString(matchString)
+}
+
+// extractTitleは記事のタイトルを返す
+// 本文のh1が1つの場合はh1、それ以外はog:title、titleの順に利用する
+func extractTitle(doc *html.Node) string {
+	h1s := findAll(doc, atom.H1)
+	if len(h1s) == 1 {
+		if t := inlineText(h1s[0]); t != "" {
+			return t
+		}
+	}
+	for _, meta := range findAll(doc, atom.Meta) {
+		property, _ := attr(meta, "property")
+		if property == "og:title" {
+			if content, _ := attr(meta, "content"); strings.TrimSpace(content) != "" {
+				return strings.TrimSpace(content)
+			}
+		}
+	}
+	if title := findFirst(doc, atom.Title); title != nil {
+		if t := inlineText(title); t != "" {
+			return t
+		}
+	}
+	if len(h1s) > 0 {
+		return inlineText(h1s[0])
+	}
+	return ""
+}
+
+// 段落としてスコアを計算する要素の最小文字数
+const minParagraphLength = 25
+
+// selectContentは本文の候補をスコアリングし、本文とみなす要素を文書の順に返す
+// 候補が見つからない場合はroot全体を本文とする
+func selectContent(root *html.Node) []*html.Node {
+	scores := map[*html.Node]float64{}
+	initialize := func(n *html.Node) {
+		if _, ok := scores[n]; !ok {
+			scores[n] = tagWeight(n) + classWeight(n)
+		}
+	}
+
+	walkElements(root, func(n *html.Node) {
+		if !isParagraph(n) {
+			return
+		}
+		text := inlineText(n)
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return
+		}
+		score := 1 + float64(countCommas(text)) + math.Min(float64(length/100), 3)
+		level := 0
+		for p := n.Parent; p != nil && p != root.Parent && level < 5; p = p.Parent {
+			if p.Type != html.ElementNode {
+				break
+			}
+			initialize(p)
+			switch level {
+			case 0:
+				scores[p] += score
+			case 1:
+				scores[p] += score / 2
+			default:
+				scores[p] += score / float64(level*3)
+			}
+			level++
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		scores[n] = score
+		if top == nil || score > topScore || (score == topScore && isBefore(n, top)) {
+			top, topScore = n, score
+		}
+	}
+	if top == nil || top.Parent == nil {
+		return []*html.Node{root}
+	}
+
+	// 本文が複数の兄弟要素に分かれている場合に備えて、スコアの高い兄弟要素や段落も含める
+	threshold := math.Max(10, topScore*0.2)
+	var nodes []*html.Node
+	for s := top.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+		if s == top {
+			nodes = append(nodes, s)
+			continue
+		}
+		if score, ok := scores[s]; ok && score >= threshold {
+			nodes = append(nodes, s)
+			continue
+		}
+		if s.DataAtom == atom.P {
+			text := inlineText(s)
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(s)
+			if (length > 80 && density < 0.25) ||
+				(length > 0 && density == 0 && strings.ContainsAny(text, ".。")) {
+				nodes = append(nodes, s)
+			}
+		}
+	}
+	return nodes
+}
+
+// removeLinkListsは本文中のシェアボタンや関連記事などリンクが大半を占める要素を取り除く
+func removeLinkLists(root *html.Node) {
+	var remove []*html.Node
+	walkElements(root, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.Ul, atom.Ol, atom.Div, atom.Section, atom.Table:
+			if n != root && linkDensity(n) > 0.5 && !hasAncestorIn(n, remove) {
+				remove = append(remove, n)
+			}
+		}
+	})
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// isParagraphは段落としてスコアを計算する要素かを返す
+// ブロック要素を含まないdivやsectionも段落として扱う
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Li:
+		return true
+	case atom.Div, atom.Section:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockAtoms[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func tagWeight(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		return 10
+	case atom.Div:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+// classWeightはclassとidが本文らしい名前であれば加点し、本文らしくない名前であれば減点する
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{"class", "id"} {
+		v, ok := attr(n, name)
+		if !ok || v == "" {
+			continue
+		}
+		if negativePattern.MatchString(v) {
+			weight -= 25
+		}
+		if positivePattern.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensityは要素のテキストのうちリンクのテキストが占める割合を返す
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(inlineText(n))
+	if length == 0 {
+		return 0
+	}
+	linkLength := 0
+	for _, a := range findAll(n, atom.A) {
+		linkLength += utf8.RuneCountInString(inlineText(a))
+	}
+	return float64(linkLength) / float64(length)
+}
+
+func countCommas(text string) int {
+	count := 0
+	for _, r := range text {
+		switch r {
+		case ',', '、', '，':
+			count++
+		}
+	}
+	return count
+}
+
+func classAndId(n *html.Node) string {
+	class, _ := attr(n, "class")
+	id, _ := attr(n, "id")
+	return class + " " + id
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func hasAncestor(n *html.Node, atoms ...atom.Atom) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		for _, a := range atoms {
+			if p.DataAtom == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasAncestorIn(n *html.Node, nodes []*html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		for _, node := range nodes {
+			if p == node {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBeforeは文書の順でaがbより前にあるかを返す
+func isBefore(a, b *html.Node) bool {
+	found := false
+	var walk func(n *html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n == a {
+			found = true
+			return true
+		}
+		if n == b {
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	root := a
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	walk(root)
+	return found
+}
+
+func walkElements(root *html.Node, fn func(n *html.Node)) {
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			fn(c)
+			walkElements(c, fn)
+		}
+	}
+}
+
+func findAll(root *html.Node, a atom.Atom) []*html.Node {
+	var nodes []*html.Node
+	walkElements(root, func(n *html.Node) {
+		if n.DataAtom == a {
+			nodes = append(nodes, n)
+		}
+	})
+	return nodes
+}
+
+func findFirst(root *html.Node, a atom.Atom) *html.Node {
+	nodes := findAll(root, a)
+	if len(nodes) == 0 {
+		return nil
+	}
+	return nodes[0]
+}
@@ -0,0 +1,85 @@
+package extractor
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Extract(t *testing.T) {
+	f, err := os.Open("testdata/blog.html")
+	if err != nil {
+		t.Fatalf("failed to open testdata: %v", err)
+	}
+	defer f.Close()
+	want, err := os.ReadFile("testdata/blog.md")
+	if err != nil {
+		t.Fatalf("failed to read testdata: %v", err)
+	}
+
+	got, err := Extract(f)
+	if err != nil {
+		t.Fatalf("failed to extract: %v", err)
+	}
+	if got.Title != "GoでWebページを要約する" {
+		t.Errorf("title is not expected: %v", got.Title)
+	}
+	if diff := cmp.Diff(strings.TrimSpace(string(want)), got.Content); diff != "" {
+		t.Errorf("content mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_ExtractString(t *testing.T) {
+	tests := []struct {
+		name        string
+		html        string
+		wantTitle   string
+		wantContent string
+	}{
+		{
+			name:        "short page",
+			html:        `<html><head><title>TestPage</title></head><body><h1>TestPage h1</h1><p>TestPage p</p></body></html>`,
+			wantTitle:   "TestPage h1",
+			wantContent: "TestPage p",
+		},
+		{
+			name:        "title from og:title",
+			html:        `<html><head><meta property="og:title" content="OG Title"><title>Title</title></head><body><p>本文です。</p></body></html>`,
+			wantTitle:   "OG Title",
+			wantContent: "本文です。",
+		},
+		{
+			name: "hidden and nested list",
+			html: `<html><body><div style="display: none">非表示のテキスト</div>
+<ol><li>1つ目<br>改行</li><li></li><li>2つ目<ol><li>入れ子</li></ol></li></ol></body></html>`,
+			wantContent: "1. 1つ目\n   改行\n2. 2つ目\n   1. 入れ子",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractString(tt.html)
+			if err != nil {
+				t.Fatalf("failed to extract: %v", err)
+			}
+			if got.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got.Title, tt.wantTitle)
+			}
+			if diff := cmp.Diff(tt.wantContent, got.Content); diff != "" {
+				t.Errorf("content mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ToMarkdown(t *testing.T) {
+	got, err := ToMarkdown(`<h2>見出し</h2><p>段落1</p><script>alert(1)</script><ul><li>項目</li></ul>`)
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+	want := "## 見出し\n\n段落1\n\n- 項目"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("markdown mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,356 @@
+package extractor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ブロックとして扱う要素
+var blockAtoms = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Blockquote: true,
+	atom.Dd:         true,
+	atom.Details:    true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Fieldset:   true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.Form:       true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Header:     true,
+	atom.Hr:         true,
+	atom.Li:         true,
+	atom.Main:       true,
+	atom.Nav:        true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Summary:    true,
+	atom.Table:      true,
+	atom.Ul:         true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1,
+	atom.H2: 2,
+	atom.H3: 3,
+	atom.H4: 4,
+	atom.H5: 5,
+	atom.H6: 6,
+}
+
+// renderMarkdownは本文の要素をMarkdownに変換する
+// タイトルと同じ見出しは本文から除く
+func renderMarkdown(nodes []*html.Node, title string) string {
+	r := &markdownRenderer{title: title}
+	var blocks []string
+	for _, n := range nodes {
+		blocks = append(blocks, r.blocks(n)...)
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+type markdownRenderer struct {
+	title        string
+	titleSkipped bool
+}
+
+// blocksはnをMarkdownのブロックに変換する
+func (r *markdownRenderer) blocks(n *html.Node) []string {
+	if n.Type == html.ElementNode && blockAtoms[n.DataAtom] {
+		if block := r.block(n); block != nil {
+			return block
+		}
+	}
+	return r.childBlocks(n)
+}
+
+// childBlocksはnの子要素をMarkdownのブロックに変換する
+// ブロック要素の間にあるテキストやインライン要素は1つの段落にまとめる
+func (r *markdownRenderer) childBlocks(n *html.Node) []string {
+	var blocks []string
+	inline := strings.Builder{}
+	flush := func() {
+		if text := normalizeSpace(inline.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockAtoms[c.DataAtom] {
+			flush()
+			blocks = append(blocks, r.blocks(c)...)
+			continue
+		}
+		inline.WriteString(r.inline(c))
+	}
+	flush()
+	return blocks
+}
+
+// blockはMarkdownの構文に対応するブロック要素を変換する
+// 対応する構文がないdivなどはnilを返し、子要素をブロックとして変換する
+func (r *markdownRenderer) block(n *html.Node) []string {
+	if level, ok := headingLevels[n.DataAtom]; ok {
+		text := normalizeSpace(r.inline(n))
+		if text == "" {
+			return []string{}
+		}
+		if !r.titleSkipped && level <= 2 && text == r.title {
+			r.titleSkipped = true
+			return []string{}
+		}
+		return []string{strings.Repeat("#", level) + " " + text}
+	}
+	switch n.DataAtom {
+	case atom.P:
+		if text := normalizeSpace(r.inline(n)); text != "" {
+			return []string{text}
+		}
+		return []string{}
+	case atom.Pre:
+		return []string{codeBlock(n)}
+	case atom.Ul, atom.Ol:
+		if lines := r.list(n, ""); len(lines) > 0 {
+			return []string{strings.Join(lines, "\n")}
+		}
+		return []string{}
+	case atom.Blockquote:
+		var lines []string
+		for _, block := range r.childBlocks(n) {
+			for _, line := range strings.Split(block, "\n") {
+				lines = append(lines, strings.TrimRight("> "+line, " "))
+			}
+			lines = append(lines, ">")
+		}
+		if len(lines) == 0 {
+			return []string{}
+		}
+		return []string{strings.Join(lines[:len(lines)-1], "\n")}
+	case atom.Table:
+		if table := r.table(n); table != "" {
+			return []string{table}
+		}
+		return []string{}
+	case atom.Hr:
+		return []string{"---"}
+	}
+	return nil
+}
+
+// listはul, olをMarkdownのリストの行に変換する
+// 入れ子のリストとコードブロックはインデントして項目に含める
+func (r *markdownRenderer) list(n *html.Node, indent string) []string {
+	var lines []string
+	index := 0
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		index++
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", index)
+		}
+		childIndent := indent + strings.Repeat(" ", len(marker))
+		text := strings.Builder{}
+		var nested []string
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			switch {
+			case c.Type == html.ElementNode && (c.DataAtom == atom.Ul || c.DataAtom == atom.Ol):
+				nested = append(nested, r.list(c, childIndent)...)
+			case c.Type == html.ElementNode && c.DataAtom == atom.Pre:
+				for _, line := range strings.Split(codeBlock(c), "\n") {
+					nested = append(nested, childIndent+line)
+				}
+			default:
+				text.WriteString(" " + r.inline(c))
+			}
+		}
+		line := normalizeSpace(text.String())
+		if line == "" && len(nested) == 0 {
+			index--
+			continue
+		}
+		// 項目内の改行は項目の続きになるようにインデントする
+		line = strings.ReplaceAll(line, "\n", "\n"+childIndent)
+		lines = append(lines, indent+marker+line)
+		lines = append(lines, nested...)
+	}
+	return lines
+}
+
+// tableはtableをMarkdownの表に変換する
+// 1行目を見出しの行とする
+func (r *markdownRenderer) table(n *html.Node) string {
+	var rows [][]string
+	columns := 0
+	walkElements(n, func(tr *html.Node) {
+		if tr.DataAtom != atom.Tr {
+			return
+		}
+		var row []string
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+				cell := normalizeSpace(r.inline(c))
+				row = append(row, strings.ReplaceAll(cell, "|", `\|`))
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+			if len(row) > columns {
+				columns = len(row)
+			}
+		}
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineはインライン要素をMarkdownに変換する
+// リンクは要約に不要なためURLを除いてテキストのみとする
+func (r *markdownRenderer) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		// ソースの改行はbrと区別するため空白として扱う
+		return collapseSpace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Img:
+		return ""
+	case atom.Code, atom.Kbd, atom.Samp:
+		if text := normalizeSpace(textContent(n)); text != "" {
+			return "`" + text + "`"
+		}
+		return ""
+	}
+	b := strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && blockAtoms[c.DataAtom] {
+			b.WriteString(" " + r.inline(c) + " ")
+			continue
+		}
+		b.WriteString(r.inline(c))
+	}
+	text := b.String()
+	switch n.DataAtom {
+	case atom.Strong, atom.B:
+		return emphasis(text, "**")
+	case atom.Em, atom.I:
+		return emphasis(text, "*")
+	}
+	return text
+}
+
+func emphasis(text string, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	return marker + trimmed + marker
+}
+
+// codeBlockはpreをフェンスで囲んだコードブロックに変換する
+// class="language-xxx"が指定されている場合は言語として利用する
+func codeBlock(n *html.Node) string {
+	lang := codeLanguage(n)
+	if code := findFirst(n, atom.Code); code != nil && lang == "" {
+		lang = codeLanguage(code)
+	}
+	code := strings.Trim(textContent(n), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func codeLanguage(n *html.Node) string {
+	class, _ := attr(n, "class")
+	for _, c := range strings.Fields(class) {
+		for _, prefix := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(c, prefix) {
+				return strings.TrimPrefix(c, prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// textContentは空白を保ったまま要素のテキストを返す
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	b := strings.Builder{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// inlineTextは空白を詰めた要素のテキストを返す
+func inlineText(n *html.Node) string {
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+// collapseSpaceは改行を含む連続する空白を1つの空白にまとめる
+func collapseSpace(text string) string {
+	b := strings.Builder{}
+	space := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// normalizeSpaceは行ごとに連続する空白を1つにまとめ、空行を取り除く
+func normalizeSpace(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
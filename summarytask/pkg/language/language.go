@@ -0,0 +1,143 @@
+package language
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// maxDetectRunesは言語の判定に利用する先頭からの文字数
+const maxDetectRunes = 10000
+
+// minDetectLettersは判定に必要な文字数。これより少ない場合は判定しない
+const minDetectLetters = 20
+
+// cjkWeightはCJKの1文字をラテン文字何文字分として数えるか
+// ラテン文字は1単語が複数文字になるため、文字数のまま比較するとCJKが過小に評価される
+const cjkWeight = 3
+
+// Detectはテキストの言語を判定し、BCP 47の言語タグ(ja, en など)を返す
+// 文字の種類(スクリプト)で判定し、ラテン文字の場合は頻出する単語で言語を推定する
+// 判定できない場合は空文字を返す
+func Detect(text string) string {
+	var kana, han, hangul, cyrillic, arabic, thai, latin int
+	n := 0
+	for _, r := range text {
+		if n >= maxDetectRunes {
+			break
+		}
+		n++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	if kana+han+hangul+cyrillic+arabic+thai+latin < minDetectLetters {
+		return ""
+	}
+
+	scores := []struct {
+		lang  string
+		score int
+	}{
+		// 日本語は漢字と仮名が混在し、中国語は仮名を含まない
+		{"ja", (kana + han) * cjkWeight * boolToInt(kana*10 >= han)},
+		{"zh", han * cjkWeight * boolToInt(kana*10 < han)},
+		{"ko", hangul * cjkWeight},
+		{"ru", cyrillic},
+		{"ar", arabic},
+		{"th", thai * cjkWeight},
+		{"latin", latin},
+	}
+	best := scores[0]
+	for _, s := range scores[1:] {
+		if s.score > best.score {
+			best = s
+		}
+	}
+	if best.lang == "latin" {
+		return detectLatin(text)
+	}
+	return best.lang
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// stopWordsはラテン文字の言語ごとの頻出単語
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "for", "with", "this", "are"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "dans", "que", "pour", "pas", "du"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "mit", "den", "auf", "zu"},
+	"es": {"el", "la", "los", "las", "y", "que", "es", "en", "por", "una", "con", "del"},
+	"pt": {"o", "os", "as", "e", "que", "não", "uma", "com", "para", "do", "da", "em"},
+	"it": {"il", "gli", "e", "che", "non", "una", "per", "con", "del", "della", "sono", "di"},
+}
+
+// latinLanguagesは同点の場合に優先する順序
+var latinLanguages = []string{"en", "fr", "de", "es", "pt", "it"}
+
+// detectLatinは頻出単語の出現回数でラテン文字の言語を推定する
+// 判定できない場合は英語とする
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) > maxDetectRunes/4 {
+		words = words[:maxDetectRunes/4]
+	}
+	counts := make(map[string]int, len(words))
+	for _, w := range words {
+		counts[w]++
+	}
+	best, bestScore := "en", 0
+	for _, lang := range latinLanguages {
+		score := 0
+		for _, w := range stopWords[lang] {
+			score += counts[w]
+		}
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	return best
+}
+
+// DisplayNameは言語タグをプロンプトで指示するための言語名に変換する
+// 例: en → 英語 (English)、ja → 日本語
+// 解析できない言語タグの場合はそのまま返す
+func DisplayName(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	ja := display.Japanese.Tags().Name(t)
+	self := display.Self.Name(t)
+	switch {
+	case ja == "" && self == "":
+		return tag
+	case ja == "" || ja == self:
+		return self
+	case self == "":
+		return ja
+	}
+	return ja + " (" + self + ")"
+}
@@ -0,0 +1,46 @@
+package language
+
+import "testing"
+
+func Test_Detect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "japanese", text: "本日は晴天なり。これは日本語の文章で、ひらがなとカタカナと漢字が含まれています。", want: "ja"},
+		{name: "japanese with english", text: "Go言語でWebページを要約するLambdaを作りました。ChatGPTのAPIを利用しています。", want: "ja"},
+		{name: "chinese", text: "这是一个中文句子，用于测试语言检测功能是否正常工作。我们需要足够的文字。", want: "zh"},
+		{name: "korean", text: "이것은 한국어 문장입니다. 언어 감지 기능이 제대로 작동하는지 테스트합니다.", want: "ko"},
+		{name: "english", text: "This is an English sentence that is used to test the language detection of the page.", want: "en"},
+		{name: "french", text: "Ceci est une phrase en français pour tester la détection de la langue dans les pages.", want: "fr"},
+		{name: "german", text: "Das ist ein deutscher Satz, und er ist nicht lang, aber er ist mit der Erkennung zu testen.", want: "de"},
+		{name: "russian", text: "Это предложение на русском языке для проверки определения языка страницы.", want: "ru"},
+		{name: "too short", text: "hello", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_DisplayName(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "ja", want: "日本語"},
+		{tag: "en", want: "英語 (English)"},
+		{tag: "invalid tag!", want: "invalid tag!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := DisplayName(tt.tag); got != tt.want {
+				t.Errorf("DisplayName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package pdftext
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// DefaultMaxPagesは抽出する最大のページ数のデフォルト値
+const DefaultMaxPages = 50
+
+// ErrNoTextはスキャンした画像のみのPDFなど、抽出できるテキストがない場合のエラー
+var ErrNoText = errors.New("pdf has no extractable text")
+
+type Options struct {
+	// Pagesは抽出するページ範囲。空の場合はすべてのページ
+	Pages entities.PageRanges
+	// MaxPagesは抽出する最大のページ数。0の場合はDefaultMaxPages
+	MaxPages int
+}
+
+// DocumentはPDFから抽出したテキスト
+type Document struct {
+	// TitleはPDFの文書情報のタイトル。ない場合は本文の最初の行
+	Title   string
+	Content string
+	// NumPagesはPDFの総ページ数
+	NumPages int
+	// Pagesは本文として抽出したページ番号
+	Pages []int
+	// TruncatedはMaxPagesを超えたため抽出しなかったページがあるか
+	Truncated bool
+}
+
+// ExtractはPDFからページ範囲のテキストを抽出する
+// 各ページのテキストは行ごとに並べ、段落の間は空行で区切る
+func Extract(r io.ReaderAt, size int64, options *Options) (*Document, error) {
+	opts := Options{MaxPages: DefaultMaxPages}
+	if options != nil {
+		opts.Pages = options.Pages
+		if options.MaxPages > 0 {
+			opts.MaxPages = options.MaxPages
+		}
+	}
+	reader, err := newReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{NumPages: reader.NumPage()}
+	var pages []string
+	for i := 1; i <= doc.NumPages; i++ {
+		if !opts.Pages.Contains(i) {
+			continue
+		}
+		if len(doc.Pages) >= opts.MaxPages {
+			doc.Truncated = true
+			break
+		}
+		text, err := pageText(reader.Page(i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract page %d: %w", i, err)
+		}
+		doc.Pages = append(doc.Pages, i)
+		if text != "" {
+			pages = append(pages, text)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, ErrNoText
+	}
+	doc.Content = strings.Join(pages, "\n\n")
+	doc.Title = documentTitle(reader)
+	if doc.Title == "" {
+		doc.Title, _, _ = strings.Cut(doc.Content, "\n")
+	}
+	return doc, nil
+}
+
+// newReaderはPDFを読み込む
+// 壊れたPDFではライブラリがpanicすることがあるためエラーとして返す
+func newReader(r io.ReaderAt, size int64) (reader *pdf.Reader, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("failed to read pdf: %v", p)
+		}
+	}()
+	reader, err = pdf.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pdf: %w", err)
+	}
+	return reader, nil
+}
+
+func documentTitle(reader *pdf.Reader) (title string) {
+	defer func() {
+		if p := recover(); p != nil {
+			title = ""
+		}
+	}()
+	return strings.TrimSpace(reader.Trailer().Key("Info").Key("Title").Text())
+}
+
+// pageTextはページのテキストを描画位置から行と段落にまとめる
+func pageText(page pdf.Page) (text string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+	if page.V.IsNull() {
+		return "", nil
+	}
+
+	var paragraphs []string
+	paragraph := strings.Builder{}
+	line := strings.Builder{}
+	flushLine := func() {
+		text := strings.Join(strings.Fields(line.String()), " ")
+		line.Reset()
+		if text == "" {
+			return
+		}
+		if paragraph.Len() > 0 {
+			paragraph.WriteString(lineSeparator(paragraph.String(), text))
+		}
+		paragraph.WriteString(text)
+	}
+	flushParagraph := func() {
+		flushLine()
+		if paragraph.Len() > 0 {
+			paragraphs = append(paragraphs, paragraph.String())
+		}
+		paragraph.Reset()
+	}
+
+	var prev *pdf.Text
+	for _, t := range page.Content().Text {
+		t := t
+		if t.S == "\n" || t.S == "" {
+			continue
+		}
+		if prev != nil {
+			size := math.Max(prev.FontSize, 1)
+			dy := math.Abs(prev.Y - t.Y)
+			switch {
+			case dy > size*1.8 || (dy > size*0.5 && math.Abs(prev.FontSize-t.FontSize) > 1):
+				// 行間が広い場合や、見出しなど文字の大きさが変わった場合は段落を分ける
+				flushParagraph()
+			case dy > size*0.5:
+				flushLine()
+			case t.X-(prev.X+charWidth(prev)) > size*0.15 || t.X < prev.X-size:
+				// 単語の間隔が空いている場合や、同じ行で前に戻った場合は空白で区切る
+				line.WriteString(" ")
+			}
+		}
+		line.WriteString(t.S)
+		prev = &t
+	}
+	flushParagraph()
+	return strings.Join(paragraphs, "\n\n"), nil
+}
+
+// charWidthは文字の幅を返す。フォントに幅の情報がない場合は文字の大きさから推定する
+func charWidth(t *pdf.Text) float64 {
+	if t.W > 0 {
+		return t.W
+	}
+	r, _ := utf8.DecodeRuneInString(t.S)
+	if isCJK(r) {
+		return t.FontSize
+	}
+	return t.FontSize * 0.5
+}
+
+// lineSeparatorは段落内で改行された行をつなぐ文字を返す
+// 日本語などの行は空白を入れずにつなぐ
+func lineSeparator(prev string, next string) string {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	if isCJK(last) || isCJK(first) {
+		return ""
+	}
+	return " "
+}
+
+// isCJKは日本語、中国語、韓国語の文字や全角の記号かを返す
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
@@ -0,0 +1,95 @@
+package pdftext
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+func Test_Extract(t *testing.T) {
+	data, err := os.ReadFile("testdata/whitepaper.pdf")
+	if err != nil {
+		t.Fatalf("failed to read pdf: %v", err)
+	}
+	tests := []struct {
+		name    string
+		options *Options
+		want    *Document
+	}{
+		{
+			name: "all pages",
+			want: &Document{
+				Title: "Test Whitepaper",
+				Content: "Introduction\n\n" +
+					"This whitepaper describes the summarization pipeline.\n\n" +
+					"Kerned words\n\n" +
+					"Conclusion page.",
+				NumPages: 3,
+				Pages:    []int{1, 2, 3},
+			},
+		},
+		{
+			name:    "page ranges",
+			options: &Options{Pages: entities.PageRanges{{First: 2, Last: 3}}},
+			want: &Document{
+				Title:    "Test Whitepaper",
+				Content:  "Kerned words\n\nConclusion page.",
+				NumPages: 3,
+				Pages:    []int{2, 3},
+			},
+		},
+		{
+			name:    "max pages",
+			options: &Options{MaxPages: 1},
+			want: &Document{
+				Title:     "Test Whitepaper",
+				Content:   "Introduction\n\nThis whitepaper describes the summarization pipeline.",
+				NumPages:  3,
+				Pages:     []int{1},
+				Truncated: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(bytes.NewReader(data), int64(len(data)), tt.options)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Extract() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	_, err = Extract(bytes.NewReader(data), int64(len(data)), &Options{Pages: entities.PageRanges{{First: 5, Last: 6}}})
+	if !errors.Is(err, ErrNoText) {
+		t.Errorf("Extract() out of range error = %v, want ErrNoText", err)
+	}
+}
+
+func Test_Extract_Invalid(t *testing.T) {
+	data := []byte("%PDF-1.4\nbroken")
+	if _, err := Extract(bytes.NewReader(data), int64(len(data)), nil); err == nil {
+		t.Errorf("Extract() should fail with broken pdf")
+	}
+}
+
+func Test_lineSeparator(t *testing.T) {
+	tests := []struct {
+		prev, next, want string
+	}{
+		{prev: "This is", next: "a pen.", want: " "},
+		{prev: "これは", next: "ペンです。", want: ""},
+		{prev: "要約の", next: "API", want: ""},
+	}
+	for _, tt := range tests {
+		if got := lineSeparator(tt.prev, tt.next); got != tt.want {
+			t.Errorf("lineSeparator(%q, %q) = %q, want %q", tt.prev, tt.next, got, tt.want)
+		}
+	}
+}
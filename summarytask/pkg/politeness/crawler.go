@@ -0,0 +1,99 @@
+package politeness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+const (
+	// DefaultRobotsAgentはrobots.txtのUser-agentと照合する名前
+	DefaultRobotsAgent = "WebPageSummarizer"
+	DefaultMinDelay    = time.Second
+	// DefaultMaxCrawlDelayはrobots.txtのCrawl-delayとして従う最大の間隔
+	DefaultMaxCrawlDelay = 30 * time.Second
+)
+
+// ErrDisallowedByRobotsはrobots.txtで取得が拒否されている場合のエラー
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// Crawlerはページのタイトルと本文を取得する
+type Crawler interface {
+	FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error)
+}
+
+type PoliteCrawlerConfig struct {
+	// Robotsがnilの場合はrobots.txtを確認しない
+	Robots      *RobotsCache
+	RobotsAgent string
+	// Limiterがnilの場合はドメインごとの取得の間隔と同時取得数を制限しない
+	Limiter *DomainLimiter
+	// MinDelayは同じドメインから取得する最小の間隔。robots.txtのCrawl-delayの方が長い場合はそちらに従う
+	MinDelay      time.Duration
+	MaxCrawlDelay time.Duration
+}
+
+// PoliteCrawlerはrobots.txtとドメインごとの取得の間隔、同時取得数の制限に従ってページを取得する
+// HTTPでの取得からブラウザでの取得に切り替えた場合も、1回の取得として扱う
+type PoliteCrawler struct {
+	next          Crawler
+	robots        *RobotsCache
+	robotsAgent   string
+	limiter       *DomainLimiter
+	minDelay      time.Duration
+	maxCrawlDelay time.Duration
+}
+
+func NewPoliteCrawler(next Crawler, config *PoliteCrawlerConfig) *PoliteCrawler {
+	c := &PoliteCrawler{
+		next:          next,
+		robotsAgent:   DefaultRobotsAgent,
+		minDelay:      DefaultMinDelay,
+		maxCrawlDelay: DefaultMaxCrawlDelay,
+	}
+	if config != nil {
+		c.robots = config.Robots
+		c.limiter = config.Limiter
+		if config.RobotsAgent != "" {
+			c.robotsAgent = config.RobotsAgent
+		}
+		if config.MinDelay > 0 {
+			c.minDelay = config.MinDelay
+		}
+		if config.MaxCrawlDelay > 0 {
+			c.maxCrawlDelay = config.MaxCrawlDelay
+		}
+	}
+	return c
+}
+
+func (c *PoliteCrawler) FetchContents(ctx context.Context, rawURL string, options *entities.FetchOptions) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	delay := c.minDelay
+	if c.robots != nil {
+		robots, err := c.robots.Get(ctx, u)
+		if err != nil {
+			return "", "", err
+		}
+		if !robots.Allowed(c.robotsAgent, u.RequestURI()) {
+			return "", "", fmt.Errorf("%w: %s", ErrDisallowedByRobots, rawURL)
+		}
+		delay = max(delay, min(robots.CrawlDelay(c.robotsAgent), c.maxCrawlDelay))
+	}
+	if c.limiter != nil {
+		release, err := c.limiter.Wait(ctx, strings.ToLower(u.Hostname()), delay)
+		if err != nil {
+			return "", "", err
+		}
+		defer release()
+	}
+	return c.next.FetchContents(ctx, rawURL, options)
+}
@@ -0,0 +1,183 @@
+package politeness
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// memoryThrottleRepositoryはDynamoDBの条件付き書き込みと同様に取得の予約と枠を管理するインメモリのリポジトリ
+type memoryThrottleRepository struct {
+	mu        sync.Mutex
+	nextFetch map[string]time.Time
+	slots     map[string]memorySlot
+}
+
+type memorySlot struct {
+	owner      string
+	leaseUntil time.Time
+}
+
+func newMemoryThrottleRepository() *memoryThrottleRepository {
+	return &memoryThrottleRepository{nextFetch: map[string]time.Time{}, slots: map[string]memorySlot{}}
+}
+
+func (r *memoryThrottleRepository) ReserveFetch(
+	ctx context.Context, domain string, now time.Time, minDelay time.Duration,
+) (bool, time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if next, ok := r.nextFetch[domain]; ok && next.After(now) {
+		return false, next, nil
+	}
+	r.nextFetch[domain] = now.Add(minDelay)
+	return true, time.Time{}, nil
+}
+
+func (r *memoryThrottleRepository) AcquireSlot(
+	ctx context.Context, domain string, maxInFlight int, owner string, now time.Time, lease time.Duration,
+) (string, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := 0; i < maxInFlight; i++ {
+		id := fmt.Sprintf("slot#%s#%d", domain, i)
+		if slot, ok := r.slots[id]; ok && !slot.leaseUntil.Before(now) {
+			continue
+		}
+		r.slots[id] = memorySlot{owner: owner, leaseUntil: now.Add(lease)}
+		return id, true, nil
+	}
+	return "", false, nil
+}
+
+func (r *memoryThrottleRepository) ReleaseSlot(ctx context.Context, slotId string, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.slots[slotId].owner == owner {
+		delete(r.slots, slotId)
+	}
+	return nil
+}
+
+type recordingCrawler struct {
+	mu        sync.Mutex
+	inFlight  int
+	maxFlight int
+	starts    []time.Time
+}
+
+func (c *recordingCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxFlight = max(c.maxFlight, c.inFlight)
+	c.starts = append(c.starts, time.Now())
+	c.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return "title", "content", nil
+}
+
+func newTestLimiter(t *testing.T, repo ThrottleRepository, maxInFlight int) *DomainLimiter {
+	t.Helper()
+	limiter, err := NewDomainLimiter(repo, &DomainLimiterConfig{MaxInFlight: maxInFlight, MaxWait: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	limiter.pollInterval = 5 * time.Millisecond
+	return limiter
+}
+
+func Test_PoliteCrawler_FetchContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	next := &recordingCrawler{}
+	repo := newMemoryThrottleRepository()
+	sut := NewPoliteCrawler(next, &PoliteCrawlerConfig{
+		Robots:   NewRobotsCache(server.Client(), "", 0),
+		Limiter:  newTestLimiter(t, repo, 1),
+		MinDelay: 30 * time.Millisecond,
+	})
+
+	if _, _, err := sut.FetchContents(context.Background(), server.URL+"/private/page", nil); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("FetchContents() error = %v, want ErrDisallowedByRobots", err)
+	}
+
+	// 複数のワーカーから同じドメインを取得しても、同時に取得せず間隔を空ける
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := sut.FetchContents(context.Background(), server.URL+"/articles/1", nil); err != nil {
+				t.Errorf("FetchContents() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if next.maxFlight != 1 {
+		t.Errorf("max in-flight = %d, want 1", next.maxFlight)
+	}
+	if len(next.starts) != 3 {
+		t.Fatalf("fetches = %d, want 3", len(next.starts))
+	}
+	for i := 1; i < len(next.starts); i++ {
+		if d := next.starts[i].Sub(next.starts[i-1]); d < 30*time.Millisecond {
+			t.Errorf("interval between fetches = %v, want >= 30ms", d)
+		}
+	}
+	if len(repo.slots) != 0 {
+		t.Errorf("slots should be released: %v", repo.slots)
+	}
+}
+
+func Test_DomainLimiter_Wait(t *testing.T) {
+	repo := newMemoryThrottleRepository()
+	sut := newTestLimiter(t, repo, 2)
+	sut.maxWait = 50 * time.Millisecond
+	ctx := context.Background()
+
+	release1, err := sut.Wait(ctx, "example.com", 0)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	release2, err := sut.Wait(ctx, "example.com", 0)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if _, err := sut.Wait(ctx, "example.com", 0); !errors.Is(err, ErrThrottleTimeout) {
+		t.Errorf("Wait() error = %v, want ErrThrottleTimeout", err)
+	}
+	// 他のドメインは制限しない
+	release3, err := sut.Wait(ctx, "example.org", 0)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	release1()
+	release2()
+	release3()
+
+	// 枠を解放しなかったワーカーの枠はleaseの期限を過ぎると再利用する
+	sut.lease = time.Millisecond
+	if _, err := sut.Wait(ctx, "example.net", 0); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if _, err := sut.Wait(ctx, "example.net", 0); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := sut.Wait(ctx, "example.net", 0); err != nil {
+		t.Errorf("expired slot should be reused: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package politeness
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultMaxInFlight = 2
+	// DefaultLeaseは同時取得数の枠を保持する最大の時間。ページの取得にかかる最大の時間より長くする
+	DefaultLease        = 5 * time.Minute
+	DefaultMaxWait      = 2 * time.Minute
+	defaultPollInterval = 500 * time.Millisecond
+)
+
+// ErrThrottleTimeoutはドメインの取得の順番をMaxWait以上待った場合のエラー
+var ErrThrottleTimeout = errors.New("timed out waiting for domain throttle")
+
+// ThrottleRepositoryはワーカー間で共有するドメインごとの取得間隔と同時取得数を管理する
+type ThrottleRepository interface {
+	ReserveFetch(ctx context.Context, domain string, now time.Time, minDelay time.Duration) (bool, time.Time, error)
+	AcquireSlot(ctx context.Context, domain string, maxInFlight int, owner string, now time.Time, lease time.Duration) (string, bool, error)
+	ReleaseSlot(ctx context.Context, slotId string, owner string) error
+}
+
+type DomainLimiterConfig struct {
+	// MaxInFlightはドメインごとの同時取得数の上限
+	MaxInFlight int
+	// Leaseは確保した枠を解放しなかった場合に再利用できるまでの時間
+	Lease time.Duration
+	// MaxWaitは取得の順番を待つ最大の時間
+	MaxWait time.Duration
+}
+
+// DomainLimiterはドメインごとに取得の間隔と同時取得数を制限する
+type DomainLimiter struct {
+	repo         ThrottleRepository
+	owner        string
+	maxInFlight  int
+	lease        time.Duration
+	maxWait      time.Duration
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+func NewDomainLimiter(repo ThrottleRepository, config *DomainLimiterConfig) (*DomainLimiter, error) {
+	// 枠を確保したワーカーを識別し、他のワーカーの枠を解放しないようにする
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate owner id: %w", err)
+	}
+	l := &DomainLimiter{
+		repo:         repo,
+		owner:        hex.EncodeToString(b),
+		maxInFlight:  DefaultMaxInFlight,
+		lease:        DefaultLease,
+		maxWait:      DefaultMaxWait,
+		pollInterval: defaultPollInterval,
+		now:          time.Now,
+	}
+	if config != nil {
+		if config.MaxInFlight > 0 {
+			l.maxInFlight = config.MaxInFlight
+		}
+		if config.Lease > 0 {
+			l.lease = config.Lease
+		}
+		if config.MaxWait > 0 {
+			l.maxWait = config.MaxWait
+		}
+	}
+	return l, nil
+}
+
+// Waitはdomainの同時取得数の枠を確保し、前回の取得からminDelayが経過するまで待つ
+// 取得が終わったら返り値のreleaseで枠を解放する
+func (l *DomainLimiter) Wait(ctx context.Context, domain string, minDelay time.Duration) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, l.maxWait)
+	defer cancel()
+
+	var slotId string
+	for {
+		id, ok, err := l.repo.AcquireSlot(ctx, domain, l.maxInFlight, l.owner, l.now(), l.lease)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire slot: %w", err)
+		}
+		if ok {
+			slotId = id
+			break
+		}
+		if err := sleep(ctx, l.pollInterval); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrThrottleTimeout, domain)
+		}
+	}
+	release := func() {
+		// 解放に失敗してもleaseの期限を過ぎれば再利用される
+		_ = l.repo.ReleaseSlot(context.Background(), slotId, l.owner)
+	}
+
+	for {
+		ok, next, err := l.repo.ReserveFetch(ctx, domain, l.now(), minDelay)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("failed to reserve fetch: %w", err)
+		}
+		if ok {
+			return release, nil
+		}
+		wait := max(next.Sub(l.now()), l.pollInterval/10)
+		if err := sleep(ctx, min(wait, l.pollInterval*4)); err != nil {
+			release()
+			return nil, fmt.Errorf("%w: %s", ErrThrottleTimeout, domain)
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
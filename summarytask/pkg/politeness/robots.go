@@ -0,0 +1,157 @@
+package politeness
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsBytesは読み込むrobots.txtの最大のサイズ (RFC 9309では少なくとも500KiBを読み込む)
+const maxRobotsBytes = 500 << 10
+
+// Robotsはrobots.txtのルール
+type Robots struct {
+	groups []robotsGroup
+	// disallowAllはrobots.txtを取得できずすべてのパスを拒否とみなす場合にtrue
+	disallowAll bool
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// ParseRobotsはrobots.txtを読み込む
+// 解釈できない行は無視する
+func ParseRobots(r io.Reader) *Robots {
+	robots := &Robots{}
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsBytes))
+	var current *robotsGroup
+	// 連続するUser-agentの行は同じグループとして扱う
+	inAgents := false
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				robots.groups = append(robots.groups, robotsGroup{})
+				current = &robots.groups[len(robots.groups)-1]
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			// Disallowが空の場合はすべてのパスを許可する
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	return robots
+}
+
+// Allowedはagentがpathを取得してよいかを返す
+// pathはクエリを含むURLのパスで、最も長く一致するルールに従う。同じ長さの場合はAllowを優先する
+func (r *Robots) Allowed(agent string, path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	allowed := true
+	matched := -1
+	for _, g := range r.matchGroups(agent) {
+		for _, rule := range g.rules {
+			if !matchRobotsPattern(rule.pattern, path) {
+				continue
+			}
+			if n := len(rule.pattern); n > matched || (n == matched && rule.allow) {
+				matched = n
+				allowed = rule.allow
+			}
+		}
+	}
+	return allowed
+}
+
+// CrawlDelayはagentに指定された取得の間隔を返す。指定がない場合は0
+func (r *Robots) CrawlDelay(agent string) time.Duration {
+	var delay time.Duration
+	for _, g := range r.matchGroups(agent) {
+		delay = max(delay, g.crawlDelay)
+	}
+	return delay
+}
+
+// matchGroupsはagentに一致するグループを返す
+// 一致するグループがない場合は"*"のグループを返す
+func (r *Robots) matchGroups(agent string) []robotsGroup {
+	agent = strings.ToLower(agent)
+	var matched, wildcard []robotsGroup
+	for _, g := range r.groups {
+		for _, a := range g.agents {
+			if a == agent {
+				matched = append(matched, g)
+				break
+			}
+			if a == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return wildcard
+}
+
+// matchRobotsPatternはpathがパターンに前方一致するかを返す
+// パターンの"*"は任意の文字列、末尾の"$"はパスの終端に一致する
+func matchRobotsPattern(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	rest, ok := strings.CutPrefix(path, parts[0])
+	if !ok {
+		return false
+	}
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		j := strings.Index(rest, part)
+		if j < 0 {
+			return false
+		}
+		rest = rest[j+len(part):]
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package politeness
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRobotsCacheTTLはrobots.txtをキャッシュする期間 (RFC 9309では24時間を超えて利用しない)
+	DefaultRobotsCacheTTL = 24 * time.Hour
+	// robotsErrorTTLはrobots.txtを取得できなかった結果をキャッシュする期間
+	robotsErrorTTL = 10 * time.Minute
+)
+
+// RobotsCacheはホストごとにrobots.txtを取得してキャッシュする
+// タスクをまたいで同じホストのrobots.txtを再利用する
+type RobotsCache struct {
+	client    *http.Client
+	userAgent string
+	ttl       time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]robotsEntry
+	// callsは取得中のrobots.txt。同じホストを同時に取得しない
+	calls map[string]*robotsCall
+}
+
+type robotsEntry struct {
+	robots    *Robots
+	expiresAt time.Time
+}
+
+type robotsCall struct {
+	done   chan struct{}
+	robots *Robots
+	err    error
+}
+
+func NewRobotsCache(client *http.Client, userAgent string, ttl time.Duration) *RobotsCache {
+	if ttl <= 0 {
+		ttl = DefaultRobotsCacheTTL
+	}
+	return &RobotsCache{
+		client:    client,
+		userAgent: userAgent,
+		ttl:       ttl,
+		now:       time.Now,
+		entries:   map[string]robotsEntry{},
+		calls:     map[string]*robotsCall{},
+	}
+}
+
+// Getはuのホストのrobots.txtを返す
+// robots.txtが存在しない場合(4xx)はすべて許可し、サーバーエラーや通信エラーの場合はすべて拒否する
+// ctxがキャンセルされた場合はキャッシュせずにエラーを返す
+func (c *RobotsCache) Get(ctx context.Context, u *url.URL) (*Robots, error) {
+	origin := strings.ToLower(u.Scheme + "://" + u.Host)
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[origin]
+		if ok && c.now().Before(entry.expiresAt) {
+			c.mu.Unlock()
+			return entry.robots, nil
+		}
+		call, inFlight := c.calls[origin]
+		if !inFlight {
+			call = &robotsCall{done: make(chan struct{})}
+			c.calls[origin] = call
+			c.mu.Unlock()
+			c.load(ctx, origin, call)
+			return call.robots, call.err
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to get robots.txt: %w", context.Cause(ctx))
+		case <-call.done:
+		}
+		if call.err == nil {
+			return call.robots, nil
+		}
+		// 取得していたタスクがキャンセルされた場合は取得し直す
+	}
+}
+
+// loadはrobots.txtを取得してキャッシュし、同じホストを待っている呼び出し元に結果を渡す
+func (c *RobotsCache) load(ctx context.Context, origin string, call *robotsCall) {
+	robots, err := c.fetch(ctx, origin)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer close(call.done)
+	delete(c.calls, origin)
+	if err != nil && ctx.Err() != nil {
+		// キャンセルやタスクの期限切れはrobots.txtの状態ではないため、キャッシュしない
+		call.err = fmt.Errorf("failed to get robots.txt: %w", context.Cause(ctx))
+		return
+	}
+	ttl := c.ttl
+	if err != nil {
+		robots = &Robots{disallowAll: true}
+		ttl = min(ttl, robotsErrorTTL)
+	}
+	c.entries[origin] = robotsEntry{robots: robots, expiresAt: c.now().Add(ttl)}
+	call.robots = robots
+}
+
+func (c *RobotsCache) fetch(ctx context.Context, origin string) (*Robots, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return ParseRobots(resp.Body), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &Robots{}, nil
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
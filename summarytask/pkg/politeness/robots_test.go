@@ -0,0 +1,203 @@
+package politeness
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Robots_Allowed(t *testing.T) {
+	robots := ParseRobots(strings.NewReader(`
+# コメント
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.json$
+Disallow: /search?*q=
+Crawl-delay: 2
+
+User-agent: WebPageSummarizer
+User-agent: OtherBot
+Disallow: /tmp
+Allow: /
+Crawl-delay: 5.5
+
+User-agent: BlockedBot
+Disallow: /
+`))
+	tests := []struct {
+		agent string
+		path  string
+		want  bool
+	}{
+		{agent: "SomeBot", path: "/", want: true},
+		{agent: "SomeBot", path: "/private/page", want: false},
+		{agent: "SomeBot", path: "/private/public/page", want: true},
+		{agent: "SomeBot", path: "/data/items.json", want: false},
+		{agent: "SomeBot", path: "/data/items.json?page=2", want: true},
+		{agent: "SomeBot", path: "/search?lang=ja&q=go", want: false},
+		{agent: "webpagesummarizer", path: "/private/page", want: true},
+		{agent: "WebPageSummarizer", path: "/tmp/file", want: false},
+		{agent: "BlockedBot", path: "/anything", want: false},
+		{agent: "BlockedBot", path: "/robots.txt", want: true},
+	}
+	for _, tt := range tests {
+		if got := robots.Allowed(tt.agent, tt.path); got != tt.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", tt.agent, tt.path, got, tt.want)
+		}
+	}
+	if got := robots.CrawlDelay("WebPageSummarizer"); got != 5500*time.Millisecond {
+		t.Errorf("CrawlDelay() = %v", got)
+	}
+	if got := robots.CrawlDelay("SomeBot"); got != 2*time.Second {
+		t.Errorf("CrawlDelay() = %v", got)
+	}
+}
+
+func Test_matchRobotsPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "/fish", path: "/fish.html", want: true},
+		{pattern: "/fish", path: "/Fish.html", want: false},
+		{pattern: "/fish*.php", path: "/fish/salmon.php?id=1", want: true},
+		{pattern: "/*.php$", path: "/index.php", want: true},
+		{pattern: "/*.php$", path: "/index.php5", want: false},
+		{pattern: "/fish$", path: "/fish", want: true},
+		{pattern: "/fish$", path: "/fish/", want: false},
+	}
+	for _, tt := range tests {
+		if got := matchRobotsPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobotsPattern(%s, %s) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_RobotsCache_Get(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/robots.txt" || r.Header.Get("User-Agent") != "TestAgent/1.0" {
+			t.Errorf("unexpected request: %s %s", r.URL.Path, r.Header.Get("User-Agent"))
+		}
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	now := time.Now()
+	sut := NewRobotsCache(server.Client(), "TestAgent/1.0", time.Hour)
+	sut.now = func() time.Time { return now }
+	u, _ := url.Parse(server.URL + "/private/page")
+	get := func() *Robots {
+		t.Helper()
+		robots, err := sut.Get(context.Background(), u)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return robots
+	}
+
+	if get().Allowed("TestAgent", "/private/page") {
+		t.Errorf("/private should be disallowed")
+	}
+	get()
+	if n := requests.Load(); n != 1 {
+		t.Errorf("robots.txt should be cached: requests = %d", n)
+	}
+
+	// 期限が切れた後に取得できなかった場合はすべて拒否する
+	now = now.Add(2 * time.Hour)
+	status.Store(http.StatusServiceUnavailable)
+	if get().Allowed("TestAgent", "/public") {
+		t.Errorf("all paths should be disallowed when robots.txt is unavailable")
+	}
+
+	// robots.txtが存在しない場合はすべて許可する
+	now = now.Add(time.Hour)
+	status.Store(http.StatusNotFound)
+	if !get().Allowed("TestAgent", "/private/page") {
+		t.Errorf("all paths should be allowed when robots.txt does not exist")
+	}
+}
+
+func Test_RobotsCache_Get_Cancel(t *testing.T) {
+	var slow atomic.Bool
+	slow.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	sut := NewRobotsCache(server.Client(), "", time.Hour)
+	u, _ := url.Parse(server.URL + "/page")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := sut.Get(ctx, u); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// キャンセルされた結果はすべて拒否としてキャッシュしない
+	slow.Store(false)
+	robots, err := sut.Get(context.Background(), u)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !robots.Allowed("TestAgent", "/page") {
+		t.Errorf("/page should be allowed after the cancelled fetch")
+	}
+}
+
+func Test_RobotsCache_Get_Concurrent(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	sut := NewRobotsCache(server.Client(), "", time.Hour)
+	u, _ := url.Parse(server.URL + "/page")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := sut.Get(context.Background(), u)
+			errs <- err
+		}()
+	}
+	// すべての呼び出しが取得を待つまで応答しない
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Get() error = %v", err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("robots.txt should be fetched once: requests = %d", n)
+	}
+}
@@ -2,42 +2,23 @@ package scraper
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/playwright-community/playwright-go"
+	"github.com/shoet/web-page-summarizer-task/pkg/extractor"
 )
 
+// NormalScraperはサイト固有の処理を持たないページの本文を抽出する
+// ナビゲーションやフッターなどを除いた本文を、見出しやリストを残したMarkdownで返す
 type NormalScraper struct{}
 
 func (s *NormalScraper) Scrape(page playwright.Page) (title, body string, err error) {
-	titles, err := page.Locator("h1").All()
+	html, err := page.Content()
 	if err != nil {
-		return "", "", fmt.Errorf("could not get h1: %v", err)
+		return "", "", fmt.Errorf("could not get content: %v", err)
 	}
-	titleBuilder := strings.Builder{}
-	for _, t := range titles {
-		text, err := t.TextContent()
-		if err != nil {
-			return "", "", fmt.Errorf("could not get text content: %v", err)
-		}
-		if _, err := titleBuilder.WriteString(text); err != nil {
-			return "", "", fmt.Errorf("could not write string: %v", err)
-		}
-	}
-
-	contents, err := page.Locator("p").All()
+	article, err := extractor.ExtractString(html)
 	if err != nil {
-		return "", "", fmt.Errorf("could not get p: %v", err)
-	}
-	contentBuilder := strings.Builder{}
-	for _, c := range contents {
-		text, err := c.TextContent()
-		if err != nil {
-			return "", "", fmt.Errorf("could not get text content: %v", err)
-		}
-		if _, err := contentBuilder.WriteString(text); err != nil {
-			return "", "", fmt.Errorf("could not write string: %v", err)
-		}
+		return "", "", fmt.Errorf("could not extract content: %v", err)
 	}
-	return titleBuilder.String(), contentBuilder.String(), nil
+	return article.Title, article.Content, nil
 }
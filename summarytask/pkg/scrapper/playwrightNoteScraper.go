@@ -1,9 +1,84 @@
 package scraper
 
-import "github.com/playwright-community/playwright-go"
+import (
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/playwright-community/playwright-go"
+	"github.com/shoet/web-page-summarizer-task/pkg/extractor"
+)
+
+const (
+	noteTitleSelector   = "h1"
+	noteBodySelector    = "div.note-common-styles__textnote-body"
+	notePaywallSelector = "[class*='paywall']"
+	// notePaywallTextは有料記事の購入を促す部分に表示されるテキスト
+	notePaywallText = "この続きをみるには"
+	// notePaywallNoticeは無料部分のみを要約することをLLMに伝えるため本文の末尾に追加するテキスト
+	notePaywallNotice = "※ この記事の続きは有料のため、無料で公開されている部分のみを掲載しています。"
+)
+
+// ErrPaywalledは有料記事で無料で公開されている本文がない場合のエラー
+var ErrPaywalled = errors.New("article is paywalled")
+
+// NoteScraperはnote(note.com)の記事を抽出する
+// 有料記事の場合は無料で公開されている部分のみを返す
 type NoteScraper struct{}
 
 func (s *NoteScraper) Scrape(page playwright.Page) (title, body string, err error) {
-	return "", "", nil
+	title, err = page.Locator(noteTitleSelector).First().InnerText()
+	if err != nil {
+		return "", "", fmt.Errorf("could not get title: %v", err)
+	}
+
+	elements, err := page.Locator(noteBodySelector).All()
+	if err != nil {
+		return "", "", fmt.Errorf("could not get body: %v", err)
+	}
+	if len(elements) == 0 {
+		// レイアウトが変わってセレクターに一致しない場合は汎用の抽出にフォールバックする
+		return (&NormalScraper{}).Scrape(page)
+	}
+	var bodies []string
+	for _, e := range elements {
+		html, err := e.InnerHTML()
+		if err != nil {
+			return "", "", fmt.Errorf("could not get inner html: %v", err)
+		}
+		markdown, err := extractor.ToMarkdown(html)
+		if err != nil {
+			return "", "", fmt.Errorf("could not convert body: %v", err)
+		}
+		if markdown != "" {
+			bodies = append(bodies, markdown)
+		}
+	}
+
+	paywalls, err := page.Locator(notePaywallSelector).Count()
+	if err != nil {
+		return "", "", fmt.Errorf("could not get paywall: %v", err)
+	}
+	content, err := page.Content()
+	if err != nil {
+		return "", "", fmt.Errorf("could not get content: %v", err)
+	}
+	paywalled := paywalls > 0 || strings.Contains(content, notePaywallText)
+	body, err = withPaywallNotice(strings.Join(bodies, "\n\n"), paywalled)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(title), body, nil
+}
+
+// withPaywallNoticeは有料記事の本文の末尾に無料部分のみであることの注記を追加する
+// 無料部分がない場合はErrPaywalledを返す
+func withPaywallNotice(body string, paywalled bool) (string, error) {
+	if !paywalled {
+		return body, nil
+	}
+	if strings.TrimSpace(body) == "" {
+		return "", ErrPaywalled
+	}
+	return body + "\n\n" + notePaywallNotice, nil
 }
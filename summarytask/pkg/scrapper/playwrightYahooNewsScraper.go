@@ -1,11 +1,161 @@
 package scraper
 
-import "github.com/playwright-community/playwright-go"
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	yahooNewsTitleSelector       = "article h1"
+	yahooNewsBodySelector        = "article .article_body"
+	yahooNewsPageLinkSelector    = "a[href*='page=']"
+	yahooNewsArticleLinkSelector = "a[href*='/articles/']"
+	// yahooNewsMaxPagesは複数ページの記事で取得する最大のページ数
+	yahooNewsMaxPages = 10
+)
+
+// PlaywrightYahooNewsScraperはYahoo!ニュース(news.yahoo.co.jp)の記事を抽出する
+// pickupのページは記事全文のページへ移動し、複数ページの記事はすべてのページの本文を結合する
 type PlaywrightYahooNewsScraper struct {
 }
 
 func (s *PlaywrightYahooNewsScraper) Scrape(page playwright.Page) (title, body string, err error) {
+	u, err := url.Parse(page.URL())
+	if err != nil {
+		return "", "", fmt.Errorf("could not parse url: %v", err)
+	}
+	if strings.HasPrefix(u.Path, "/pickup/") {
+		articleURL, err := s.articleURL(page)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := page.Goto(articleURL, gotoOptions); err != nil {
+			return "", "", fmt.Errorf("could not goto article page: %v", err)
+		}
+	}
+
+	title, err = page.Locator(yahooNewsTitleSelector).First().InnerText()
+	if err != nil {
+		return "", "", fmt.Errorf("could not get title: %v", err)
+	}
+	first, err := s.scrapeBody(page)
+	if err != nil {
+		return "", "", err
+	}
+	if first == "" {
+		// レイアウトが変わってセレクターに一致しない場合は汎用の抽出にフォールバックする
+		return (&NormalScraper{}).Scrape(page)
+	}
+	bodies := []string{first}
+
+	hrefs, err := attributes(page.Locator(yahooNewsPageLinkSelector), "href")
+	if err != nil {
+		return "", "", fmt.Errorf("could not get page links: %v", err)
+	}
+	articleURL := page.URL()
+	pages := min(maxPageNumber(hrefs), yahooNewsMaxPages)
+	for i := 2; i <= pages; i++ {
+		if _, err := page.Goto(pageURL(articleURL, i), gotoOptions); err != nil {
+			return "", "", fmt.Errorf("could not goto page %d: %v", i, err)
+		}
+		b, err := s.scrapeBody(page)
+		if err != nil {
+			return "", "", err
+		}
+		bodies = append(bodies, b)
+	}
+	return strings.TrimSpace(title), strings.Join(bodies, "\n\n"), nil
+}
+
+// scrapeBodyは表示中のページの本文をMarkdownで返す
+func (s *PlaywrightYahooNewsScraper) scrapeBody(page playwright.Page) (string, error) {
+	return markdownOf(page.Locator(yahooNewsBodySelector))
+}
+
+// articleURLはpickupのページから記事全文のページのURLを返す
+func (s *PlaywrightYahooNewsScraper) articleURL(page playwright.Page) (string, error) {
+	elements, err := page.Locator(yahooNewsArticleLinkSelector).All()
+	if err != nil {
+		return "", fmt.Errorf("could not get article links: %v", err)
+	}
+	var links []link
+	for _, e := range elements {
+		href, err := e.GetAttribute("href")
+		if err != nil {
+			return "", fmt.Errorf("could not get href: %v", err)
+		}
+		text, err := e.InnerText()
+		if err != nil {
+			return "", fmt.Errorf("could not get link text: %v", err)
+		}
+		links = append(links, link{href: href, text: text})
+	}
+	articleURL, ok := pickArticleURL(page.URL(), links)
+	if !ok {
+		return "", fmt.Errorf("could not find article link")
+	}
+	return articleURL, nil
+}
+
+type link struct {
+	href string
+	text string
+}
+
+// pickArticleURLはpickupのページのリンクから記事全文へのリンクを選ぶ
+// 「記事全文を読む」のリンクを優先し、見つからない場合は最初の記事へのリンクを返す
+func pickArticleURL(base string, links []link) (string, bool) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", false
+	}
+	var candidate *url.URL
+	for _, l := range links {
+		u, err := baseURL.Parse(l.href)
+		if err != nil || !strings.HasPrefix(u.Path, "/articles/") {
+			continue
+		}
+		if strings.Contains(l.text, "記事全文を読む") {
+			return u.String(), true
+		}
+		if candidate == nil {
+			candidate = u
+		}
+	}
+	if candidate == nil {
+		return "", false
+	}
+	return candidate.String(), true
+}
+
+// maxPageNumberはページ送りのリンクのpageパラメータの最大値を返す
+// ページ送りがない場合は1を返す
+func maxPageNumber(hrefs []string) int {
+	pages := 1
+	for _, href := range hrefs {
+		u, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(u.Query().Get("page")); err == nil && n > pages {
+			pages = n
+		}
+	}
+	return pages
+}
 
-	return "", "", nil
+// pageURLは記事のURLのpageパラメータをnにしたURLを返す
+func pageURL(articleURL string, n int) string {
+	u, err := url.Parse(articleURL)
+	if err != nil {
+		return articleURL
+	}
+	q := u.Query()
+	q.Set("page", strconv.Itoa(n))
+	u.RawQuery = q.Encode()
+	return u.String()
 }
@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// defaultRuleMaxPagesはルールでページ送りの最大ページ数を指定しない場合のページ数
+	defaultRuleMaxPages = 10
+	// rulesReloadIntervalはルールファイルの更新を確認する間隔
+	rulesReloadInterval = 10 * time.Second
+	// rulesLoadTimeoutはルールファイルの更新の確認と読み込みのタイムアウト
+	rulesLoadTimeout = 5 * time.Second
+)
+
+// SelectorRuleは設定ファイルで定義するサイトごとの抽出ルール
+// Goのコードを変更せずにサイトごとのセレクターを調整するために利用する
+type SelectorRule struct {
+	// HostはRule.HostPatternと同じ形式のホスト名のパターン
+	Host string `json:"host" yaml:"host"`
+	// PathはURLのパスの前方一致の条件。空の場合はすべてのパスに一致する
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Titleはタイトルの要素のセレクター。空の場合や一致しない場合はページのタイトルを利用する
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+	// Bodyは本文の要素のセレクター。一致したすべての要素を順に結合する
+	Body []string `json:"body" yaml:"body"`
+	// Removeは本文から取り除く要素のセレクター
+	Remove []string `json:"remove,omitempty" yaml:"remove,omitempty"`
+	// WaitForは抽出の前に表示を待つ要素のセレクター
+	WaitFor string `json:"waitFor,omitempty" yaml:"waitFor,omitempty"`
+	// NextPageは次のページへのリンクのセレクター
+	NextPage string `json:"nextPage,omitempty" yaml:"nextPage,omitempty"`
+	// MaxPagesはページ送りで取得する最大のページ数
+	MaxPages int `json:"maxPages,omitempty" yaml:"maxPages,omitempty"`
+}
+
+// Matchはurlがルールの対象かを返す
+func (r *SelectorRule) Match(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	return Rule{HostPattern: r.Host}.Match(host) && strings.HasPrefix(u.Path, r.Path)
+}
+
+func (r *SelectorRule) Validate() error {
+	var errs []error
+	if r.Host == "" {
+		errs = append(errs, fmt.Errorf("host is required"))
+	}
+	if len(r.Body) == 0 {
+		errs = append(errs, fmt.Errorf("body is required"))
+	}
+	for _, s := range r.Body {
+		if strings.TrimSpace(s) == "" {
+			errs = append(errs, fmt.Errorf("body selector must not be empty"))
+		}
+	}
+	if r.MaxPages < 0 {
+		errs = append(errs, fmt.Errorf("maxPages must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+func (r *SelectorRule) maxPages() int {
+	if r.MaxPages == 0 {
+		return defaultRuleMaxPages
+	}
+	return r.MaxPages
+}
+
+// RulesFileはルールファイルの形式
+type RulesFile struct {
+	Rules []SelectorRule `json:"rules" yaml:"rules"`
+}
+
+// ParseRulesはYAMLまたはJSONのルールファイルを読み込む
+// 誤字に気付けるよう未知の項目はエラーとする
+func ParseRules(data []byte, isYAML bool) ([]SelectorRule, error) {
+	var file RulesFile
+	if isYAML {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to decode yaml: %w", err)
+		}
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&file); err != nil {
+			return nil, fmt.Errorf("failed to decode json: %w", err)
+		}
+	}
+	for i, r := range file.Rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rule[%d] (%s): %w", i, r.Host, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// LoadRulesはsourceのルールファイルを読み込み、ルールと読み込んだ内容の版を返す
+// 拡張子が.yamlまたは.ymlの場合はYAML、それ以外はJSONとして読み込む
+func LoadRules(ctx context.Context, source RulesSource) ([]SelectorRule, string, error) {
+	data, version, err := source.Read(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	ext := strings.ToLower(path.Ext(source.Name()))
+	rules, err := ParseRules(data, ext == ".yaml" || ext == ".yml")
+	return rules, version, err
+}
+
+// RuleSetはルールファイルのルールとRegistryのスクレイパーを組み合わせてスクレイパーを選択する
+// ルールファイルは版(更新日時やETag)が変わると読み込み直すため、デプロイせずにルールを変更できる
+type RuleSet struct {
+	source   RulesSource
+	registry *Registry
+	interval time.Duration
+
+	mu        sync.Mutex
+	rules     []SelectorRule
+	version   string
+	checkedAt time.Time
+	err       error
+	// reloadingは他の呼び出し元がルールファイルを読み込み中か。読み込み中は直前のルールを返す
+	reloading bool
+}
+
+// NewRuleSetはsourceのルールファイルを読み込んだRuleSetを返す
+// 起動時に設定の誤りに気付けるよう、最初の読み込みに失敗した場合はエラーを返す
+func NewRuleSet(ctx context.Context, source RulesSource, registry *Registry) (*RuleSet, error) {
+	s := &RuleSet{source: source, registry: registry, interval: rulesReloadInterval}
+	rules, version, err := LoadRules(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	s.rules, s.version, s.checkedAt = rules, version, time.Now()
+	return s, nil
+}
+
+// Lookupはurlに一致するルールがあればルールで抽出するスクレイパーを返す
+// 一致するルールがない場合や、ルールで本文を抽出できない場合はRegistryのスクレイパーを利用する
+func (s *RuleSet) Lookup(rawURL string) (PlaywrightScraper, error) {
+	fallback, err := s.registry.Lookup(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	for _, rule := range s.Rules() {
+		if rule.Match(u) {
+			return &SelectorScraper{Rule: rule, Fallback: fallback}, nil
+		}
+	}
+	return fallback, nil
+}
+
+// HasRuleはurlにルールファイルのルールまたはサイト固有のスクレイパーがあるかを返す
+func (s *RuleSet) HasRule(rawURL string) bool {
+	if s.registry.HasRule(rawURL) {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, rule := range s.Rules() {
+		if rule.Match(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rulesは現在のルールを返す
+// 前回の確認から一定時間が経過していればルールファイルの更新を確認する
+// 確認はロックの外で行い、他の呼び出し元は確認を待たずに直前のルールを利用する
+func (s *RuleSet) Rules() []SelectorRule {
+	s.mu.Lock()
+	if s.reloading || time.Since(s.checkedAt) < s.interval {
+		rules := s.rules
+		s.mu.Unlock()
+		return rules
+	}
+	s.checkedAt = time.Now()
+	s.reloading = true
+	current := s.version
+	s.mu.Unlock()
+
+	rules, version, err := s.reload(current)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reloading = false
+	s.version = version
+	if err != nil {
+		s.err = err
+	} else if version != current {
+		s.rules, s.err = rules, nil
+	}
+	return s.rules
+}
+
+// Errは最後に読み込みに失敗したときのエラーを返す
+func (s *RuleSet) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// reloadはルールファイルが更新されていれば読み込み直し、ルールと記録する版を返す
+// 更新されていない場合はcurrentを返す。読み込みに失敗した場合は呼び出し元で直前のルールを使い続ける
+// 同じ内容の読み込みを繰り返さないよう、不正なルールファイルの場合も読み込んだ内容の版を返す
+func (s *RuleSet) reload(current string) ([]SelectorRule, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rulesLoadTimeout)
+	defer cancel()
+	version, err := s.source.Version(ctx)
+	if err != nil {
+		return nil, current, err
+	}
+	if version == current {
+		return nil, current, nil
+	}
+	return LoadRules(ctx, s.source)
+}
@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// RulesSourceはルールファイルを読み込む場所
+type RulesSource interface {
+	// Nameはルールファイルのパスやキー。拡張子でYAMLかJSONかを判定する
+	Name() string
+	// Versionはルールファイルの版を返す。版が変わった場合のみ読み込み直す
+	Version(ctx context.Context) (string, error)
+	// Readはルールファイルの内容と、その内容の版を返す
+	// Versionの後に更新された場合も、読み込んだ内容と版が一致するよう同時に取得する
+	Read(ctx context.Context) ([]byte, string, error)
+}
+
+// NewRulesSourceはlocationのルールファイルを読み込むRulesSourceを返す
+// locationがs3://bucket/keyの形式の場合はnewStorageで生成したオブジェクトストレージから、それ以外はローカルのファイルから読み込む
+func NewRulesSource(location string, newStorage func(bucket string) ObjectStorage) (RulesSource, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return NewFileRulesSource(location), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rules location: %w", err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, fmt.Errorf("invalid rules location: %s", location)
+	}
+	return NewObjectRulesSource(newStorage(u.Host), key), nil
+}
+
+// FileRulesSourceはローカルのルールファイル。更新日時とサイズを版とする
+type FileRulesSource struct {
+	path string
+}
+
+func NewFileRulesSource(path string) *FileRulesSource {
+	return &FileRulesSource{path: path}
+}
+
+func (s *FileRulesSource) Name() string {
+	return s.path
+}
+
+func (s *FileRulesSource) Version(ctx context.Context) (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat rules file: %w", err)
+	}
+	return fileVersion(info), nil
+}
+
+func (s *FileRulesSource) Read(ctx context.Context) ([]byte, string, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open rules file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to stat rules file: %w", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read rules file: %w", err)
+	}
+	return data, fileVersion(info), nil
+}
+
+func fileVersion(info os.FileInfo) string {
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// ObjectStorageはルールファイルを保存したオブジェクトストレージ
+type ObjectStorage interface {
+	GetObjectETag(ctx context.Context, key string) (string, error)
+	GetObjectWithETag(ctx context.Context, key string) ([]byte, string, error)
+}
+
+// ObjectRulesSourceはS3などのオブジェクトストレージのルールファイル。ETagを版とする
+// オブジェクトを更新すると、ワーカーをデプロイし直さずにルールを変更できる
+type ObjectRulesSource struct {
+	storage ObjectStorage
+	key     string
+}
+
+func NewObjectRulesSource(storage ObjectStorage, key string) *ObjectRulesSource {
+	return &ObjectRulesSource{storage: storage, key: key}
+}
+
+func (s *ObjectRulesSource) Name() string {
+	return s.key
+}
+
+func (s *ObjectRulesSource) Version(ctx context.Context) (string, error) {
+	etag, err := s.storage.GetObjectETag(ctx, s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get rules object etag: %w", err)
+	}
+	return etag, nil
+}
+
+func (s *ObjectRulesSource) Read(ctx context.Context) ([]byte, string, error) {
+	data, etag, err := s.storage.GetObjectWithETag(ctx, s.key)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get rules object: %w", err)
+	}
+	return data, etag, nil
+}
@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryObjectStorageはオブジェクトの内容をメモリに保持し、内容ごとのETagを返す
+// headが指定された場合は、HeadObjectのみが先に更新された内容を返すことを再現する
+type memoryObjectStorage struct {
+	mu      sync.Mutex
+	objects map[string]string
+	head    map[string]string
+}
+
+func (s *memoryObjectStorage) GetObjectETag(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data, ok := s.head[key]; ok {
+		return etag(data), nil
+	}
+	data, ok := s.objects[key]
+	if !ok {
+		return "", fmt.Errorf("no such key: %s", key)
+	}
+	return etag(data), nil
+}
+
+func (s *memoryObjectStorage) GetObjectWithETag(ctx context.Context, key string) ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, "", fmt.Errorf("no such key: %s", key)
+	}
+	return []byte(data), etag(data), nil
+}
+
+func (s *memoryObjectStorage) put(key string, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = data
+	delete(s.head, key)
+}
+
+func etag(data string) string {
+	return fmt.Sprintf(`"%08x"`, crc32.ChecksumIEEE([]byte(data)))
+}
+
+func Test_NewRulesSource(t *testing.T) {
+	tests := []struct {
+		location   string
+		wantBucket string
+		wantName   string
+		wantErr    bool
+	}{
+		{location: "/etc/scraper/rules.yaml", wantName: "/etc/scraper/rules.yaml"},
+		{location: "s3://rules-bucket/scraper/rules.yaml", wantBucket: "rules-bucket", wantName: "scraper/rules.yaml"},
+		{location: "s3://rules-bucket/", wantErr: true},
+		{location: "s3:///rules.yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			var bucket string
+			got, err := NewRulesSource(tt.location, func(b string) ObjectStorage {
+				bucket = b
+				return &memoryObjectStorage{}
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRulesSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Name() != tt.wantName || bucket != tt.wantBucket {
+				t.Errorf("NewRulesSource() = %s in %q, want %s in %q", got.Name(), bucket, tt.wantName, tt.wantBucket)
+			}
+		})
+	}
+}
+
+func Test_RuleSet_ObjectRulesSource(t *testing.T) {
+	storage := &memoryObjectStorage{objects: map[string]string{"rules.yaml": testRulesYAML}}
+	sut, err := NewRuleSet(context.Background(), NewObjectRulesSource(storage, "rules.yaml"), DefaultRegistry)
+	if err != nil {
+		t.Fatalf("failed to create rule set: %v", err)
+	}
+	sut.interval = 0
+	if got := sut.Rules(); len(got) != 1 || got[0].Title != "article h1" {
+		t.Fatalf("Rules() = %+v", got)
+	}
+
+	// オブジェクトを更新するとETagが変わり、次の参照で反映される
+	storage.put("rules.yaml", "rules:\n  - host: example.com\n    body: [main]\n")
+	if got := sut.Rules(); len(got) != 1 || got[0].Body[0] != "main" {
+		t.Errorf("updated rules are not loaded: %+v", got)
+	}
+
+	// 取得できない場合は直前のルールを使い続ける
+	delete(storage.objects, "rules.yaml")
+	if got := sut.Rules(); len(got) != 1 || got[0].Body[0] != "main" {
+		t.Errorf("previous rules should be kept: %+v", got)
+	}
+	if sut.Err() == nil {
+		t.Errorf("reload error should be reported")
+	}
+}
+
+func Test_RuleSet_ObjectRulesSource_VersionOfContent(t *testing.T) {
+	updated := "rules:\n  - host: example.com\n    body: [main]\n"
+	storage := &memoryObjectStorage{objects: map[string]string{"rules.yaml": testRulesYAML}}
+	sut, err := NewRuleSet(context.Background(), NewObjectRulesSource(storage, "rules.yaml"), DefaultRegistry)
+	if err != nil {
+		t.Fatalf("failed to create rule set: %v", err)
+	}
+	sut.interval = 0
+
+	// HeadObjectは更新後のETagを返すが、GetObjectはまだ更新前の内容を返す
+	storage.mu.Lock()
+	storage.head = map[string]string{"rules.yaml": updated}
+	storage.mu.Unlock()
+	if got := sut.Rules(); got[0].Title != "article h1" {
+		t.Fatalf("Rules() = %+v", got)
+	}
+
+	// 読み込んだ内容の版を記録しているため、更新後の内容を取得できるようになると反映される
+	storage.put("rules.yaml", updated)
+	if got := sut.Rules(); len(got) != 1 || len(got[0].Body) != 1 || got[0].Body[0] != "main" {
+		t.Errorf("updated rules are not loaded: %+v", got)
+	}
+}
+
+// blockingObjectStorageはreleaseが閉じられるまでHeadObjectに応答しない
+type blockingObjectStorage struct {
+	*memoryObjectStorage
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingObjectStorage) GetObjectETag(ctx context.Context, key string) (string, error) {
+	close(s.started)
+	<-s.release
+	return s.memoryObjectStorage.GetObjectETag(ctx, key)
+}
+
+func Test_RuleSet_Rules_NotBlockedByReload(t *testing.T) {
+	storage := &blockingObjectStorage{
+		memoryObjectStorage: &memoryObjectStorage{objects: map[string]string{"rules.yaml": testRulesYAML}},
+		started:             make(chan struct{}),
+		release:             make(chan struct{}),
+	}
+	sut, err := NewRuleSet(context.Background(), NewObjectRulesSource(storage, "rules.yaml"), DefaultRegistry)
+	if err != nil {
+		t.Fatalf("failed to create rule set: %v", err)
+	}
+	sut.interval = 0
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sut.Rules()
+	}()
+	<-storage.started
+
+	// 他の呼び出し元が更新を確認している間も、直前のルールをすぐに返す
+	got := make(chan []SelectorRule, 1)
+	go func() { got <- sut.Rules() }()
+	select {
+	case rules := <-got:
+		if len(rules) != 1 || rules[0].Title != "article h1" {
+			t.Errorf("Rules() = %+v", rules)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Rules() is blocked by reload")
+	}
+	close(storage.release)
+	<-done
+}
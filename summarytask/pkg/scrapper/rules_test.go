@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testRulesYAML = `
+rules:
+  - host: example.com
+    path: /blog/
+    title: article h1
+    body:
+      - article .content
+    remove:
+      - .ads
+    waitFor: article
+    nextPage: a.next
+    maxPages: 3
+`
+
+func Test_ParseRules(t *testing.T) {
+	want := []SelectorRule{{
+		Host:     "example.com",
+		Path:     "/blog/",
+		Title:    "article h1",
+		Body:     []string{"article .content"},
+		Remove:   []string{".ads"},
+		WaitFor:  "article",
+		NextPage: "a.next",
+		MaxPages: 3,
+	}}
+	tests := []struct {
+		name    string
+		data    string
+		isYAML  bool
+		want    []SelectorRule
+		wantErr bool
+	}{
+		{name: "yaml", data: testRulesYAML, isYAML: true, want: want},
+		{
+			name: "json",
+			data: `{"rules": [{"host": "example.com", "path": "/blog/", "title": "article h1",
+				"body": ["article .content"], "remove": [".ads"], "waitFor": "article",
+				"nextPage": "a.next", "maxPages": 3}]}`,
+			want: want,
+		},
+		{name: "empty yaml", data: "", isYAML: true},
+		{name: "unknown field", data: "rules:\n  - host: example.com\n    bdy: [article]\n", isYAML: true, wantErr: true},
+		{name: "missing body", data: `{"rules": [{"host": "example.com"}]}`, wantErr: true},
+		{name: "missing host", data: `{"rules": [{"body": ["article"]}]}`, wantErr: true},
+		{name: "invalid json", data: `{"rules": `, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRules([]byte(tt.data), tt.isYAML)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseRules() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_SelectorRule_Match(t *testing.T) {
+	rule := SelectorRule{Host: "*.example.com", Path: "/news/"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://www.example.com/news/1", want: true},
+		{url: "https://WWW.example.com/news/", want: true},
+		{url: "https://www.example.com/blog/1", want: false},
+		{url: "https://example.com/news/1", want: false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("failed to parse url: %v", err)
+		}
+		if got := rule.Match(u); got != tt.want {
+			t.Errorf("Match(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func Test_RuleSet_Lookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	writeRules := func(t *testing.T, data string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write rules: %v", err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("failed to change mod time: %v", err)
+		}
+	}
+	modTime := time.Now().Add(-time.Hour)
+	writeRules(t, testRulesYAML, modTime)
+
+	note := &NoteScraper{}
+	fallback := &NormalScraper{}
+	sut, err := NewRuleSet(context.Background(), NewFileRulesSource(path), NewRegistry(fallback, Rule{HostPattern: "example.com", Scraper: note}))
+	if err != nil {
+		t.Fatalf("failed to create rule set: %v", err)
+	}
+	sut.interval = 0
+
+	got, err := sut.Lookup("https://example.com/blog/post")
+	if err != nil {
+		t.Fatalf("failed to lookup: %v", err)
+	}
+	selector, ok := got.(*SelectorScraper)
+	if !ok {
+		t.Fatalf("Lookup() = %T, want *SelectorScraper", got)
+	}
+	if selector.Rule.Title != "article h1" || selector.Fallback != note {
+		t.Errorf("unexpected selector scraper: %+v", selector)
+	}
+	if got, _ := sut.Lookup("https://example.com/about"); got != note {
+		t.Errorf("registry scraper should be used when no rule matches: %T", got)
+	}
+
+	// ルールファイルを更新すると次の参照で反映される
+	writeRules(t, "rules:\n  - host: example.com\n    body: [main]\n", modTime.Add(time.Minute))
+	got, _ = sut.Lookup("https://example.com/about")
+	if selector, ok := got.(*SelectorScraper); !ok || selector.Rule.Body[0] != "main" {
+		t.Errorf("updated rules are not loaded: %T", got)
+	}
+
+	// 不正なルールファイルの場合は直前のルールを使い続ける
+	writeRules(t, "rules:\n  - host: example.com\n", modTime.Add(2*time.Minute))
+	got, _ = sut.Lookup("https://example.com/about")
+	if selector, ok := got.(*SelectorScraper); !ok || selector.Rule.Body[0] != "main" {
+		t.Errorf("previous rules should be kept: %T", got)
+	}
+	if sut.Err() == nil {
+		t.Errorf("reload error should be reported")
+	}
+}
+
+func Test_NewRuleSet_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"rules": [{"host": "example.com"}]}`), 0o644); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	if _, err := NewRuleSet(context.Background(), NewFileRulesSource(path), DefaultRegistry); err == nil {
+		t.Errorf("NewRuleSet() should fail with invalid rules")
+	}
+	if _, err := NewRuleSet(
+		context.Background(), NewFileRulesSource(filepath.Join(t.TempDir(), "missing.json")), DefaultRegistry,
+	); err == nil {
+		t.Errorf("NewRuleSet() should fail without rules file")
+	}
+}
+
+func Test_resolveNextPage(t *testing.T) {
+	tests := []struct {
+		href   string
+		want   string
+		wantOK bool
+	}{
+		{href: "?page=2", want: "https://example.com/blog/post?page=2", wantOK: true},
+		{href: "/blog/post/2#top", want: "https://example.com/blog/post/2", wantOK: true},
+		{href: "javascript:void(0)", wantOK: false},
+		{href: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		got, ok := resolveNextPage("https://example.com/blog/post", tt.href)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("resolveNextPage(%q) = %v, %v, want %v, %v", tt.href, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,22 +1,141 @@
 package scraper
 
 import (
+	"fmt"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/playwright-community/playwright-go"
+	"github.com/shoet/web-page-summarizer-task/pkg/extractor"
 )
 
 type PlaywrightScraper interface {
 	Scrape(page playwright.Page) (title, body string, err error)
 }
 
+// Ruleはホスト名のパターンと、そのホストのページに利用するスクレイパーの対応
+type Rule struct {
+	// HostPatternはホスト名のパターン
+	// "example.com"はホスト名が完全に一致する場合、"*.example.com"はサブドメインの場合に一致する
+	HostPattern string
+	Scraper     PlaywrightScraper
+}
+
+// Matchはホスト名がパターンに一致するかを返す
+func (r Rule) Match(host string) bool {
+	pattern := strings.ToLower(r.HostPattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == pattern
+}
+
+// RegistryはURLのホスト名に応じてスクレイパーを選択する
+// 登録した順にルールを評価し、どのルールにも一致しない場合はデフォルトのスクレイパーを返す
+type Registry struct {
+	mu       sync.RWMutex
+	rules    []Rule
+	fallback PlaywrightScraper
+}
+
+func NewRegistry(fallback PlaywrightScraper, rules ...Rule) *Registry {
+	return &Registry{rules: rules, fallback: fallback}
+}
+
+// Registerはルールを末尾に追加する
+func (r *Registry) Register(rules ...Rule) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rules...)
+}
+
+// Lookupはurlに対応するスクレイパーを返す
+func (r *Registry) Lookup(rawURL string) (PlaywrightScraper, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	host := strings.ToLower(u.Hostname())
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rule := range r.rules {
+		if rule.Match(host) {
+			return rule.Scraper, nil
+		}
+	}
+	return r.fallback, nil
+}
+
+// HasRuleはurlにサイト固有のスクレイパーが登録されているかを返す
+func (r *Registry) HasRule(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rule := range r.rules {
+		if rule.Match(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultRegistryはサイト固有のスクレイパーを登録したRegistry
+var DefaultRegistry = NewRegistry(
+	&NormalScraper{},
+	Rule{HostPattern: "news.yahoo.co.jp", Scraper: &PlaywrightYahooNewsScraper{}},
+	Rule{HostPattern: "note.com", Scraper: &NoteScraper{}},
+)
+
 func NewPlaywrightScraper(url string) (PlaywrightScraper, error) {
-	switch {
-	case strings.HasPrefix(url, "https://yahoo.com"):
-		return &NormalScraper{}, nil // TODO
-	case strings.HasPrefix(url, "https://note.com"):
-		return &NormalScraper{}, nil // TODO
-	default:
-		return &NormalScraper{}, nil
+	return DefaultRegistry.Lookup(url)
+}
+
+// gotoOptionsはスクレイパーがページを移動する際のオプション
+var gotoOptions = playwright.PageGotoOptions{
+	Timeout: playwright.Float(120000), // ページ表示までのタイムアウト: 2分
+}
+
+// attributesはlocatorに一致するすべての要素の属性の値を返す
+func attributes(locator playwright.Locator, name string) ([]string, error) {
+	elements, err := locator.All()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(elements))
+	for _, e := range elements {
+		v, err := e.GetAttribute(name)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// markdownOfはlocatorに一致するすべての要素の内容をMarkdownに変換して結合する
+func markdownOf(locator playwright.Locator) (string, error) {
+	elements, err := locator.All()
+	if err != nil {
+		return "", fmt.Errorf("could not get body: %v", err)
+	}
+	var bodies []string
+	for _, e := range elements {
+		html, err := e.InnerHTML()
+		if err != nil {
+			return "", fmt.Errorf("could not get inner html: %v", err)
+		}
+		markdown, err := extractor.ToMarkdown(html)
+		if err != nil {
+			return "", fmt.Errorf("could not convert body: %v", err)
+		}
+		if markdown != "" {
+			bodies = append(bodies, markdown)
+		}
 	}
+	return strings.Join(bodies, "\n\n"), nil
 }
@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_Registry_Lookup(t *testing.T) {
+	fallback := &NormalScraper{}
+	yahoo := &PlaywrightYahooNewsScraper{}
+	note := &NoteScraper{}
+	sut := NewRegistry(fallback,
+		Rule{HostPattern: "news.yahoo.co.jp", Scraper: yahoo},
+		Rule{HostPattern: "*.note.com", Scraper: note},
+	)
+	tests := []struct {
+		url  string
+		want PlaywrightScraper
+	}{
+		{url: "https://news.yahoo.co.jp/articles/abc", want: yahoo},
+		{url: "https://NEWS.yahoo.co.jp:443/pickup/123", want: yahoo},
+		{url: "https://yahoo.co.jp/", want: fallback},
+		{url: "https://user.note.com/n/n123", want: note},
+		{url: "https://note.com/user/n/n123", want: fallback},
+		{url: "https://example.com/", want: fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := sut.Lookup(tt.url)
+			if err != nil {
+				t.Fatalf("failed to lookup: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() = %T, want %T", got, tt.want)
+			}
+		})
+	}
+
+	if !sut.HasRule("https://news.yahoo.co.jp/articles/abc") || sut.HasRule("https://example.com/") {
+		t.Errorf("HasRule() should report only registered hosts")
+	}
+
+	sut.Register(Rule{HostPattern: "note.com", Scraper: note})
+	if got, _ := sut.Lookup("https://note.com/user/n/n123"); got != note {
+		t.Errorf("registered rule is not used: %T", got)
+	}
+}
+
+func Test_pickArticleURL(t *testing.T) {
+	got, ok := pickArticleURL("https://news.yahoo.co.jp/pickup/6484213", []link{
+		{href: "/articles/related", text: "関連記事"},
+		{href: "https://news.yahoo.co.jp/articles/abc", text: "記事全文を読む"},
+	})
+	if !ok || got != "https://news.yahoo.co.jp/articles/abc" {
+		t.Errorf("pickArticleURL() = %v, %v", got, ok)
+	}
+	got, ok = pickArticleURL("https://news.yahoo.co.jp/pickup/6484213", []link{
+		{href: "/ranking", text: "ランキング"},
+		{href: "/articles/first", text: "記事"},
+	})
+	if !ok || got != "https://news.yahoo.co.jp/articles/first" {
+		t.Errorf("pickArticleURL() = %v, %v", got, ok)
+	}
+	if _, ok := pickArticleURL("https://news.yahoo.co.jp/pickup/6484213", nil); ok {
+		t.Errorf("pickArticleURL() should not find article")
+	}
+}
+
+func Test_maxPageNumber(t *testing.T) {
+	hrefs := []string{"/articles/abc?page=2", "/articles/abc?page=3", "/articles/abc?page=next", "%%"}
+	if got := maxPageNumber(hrefs); got != 3 {
+		t.Errorf("maxPageNumber() = %v, want 3", got)
+	}
+	if got := maxPageNumber(nil); got != 1 {
+		t.Errorf("maxPageNumber() = %v, want 1", got)
+	}
+	if got := pageURL("https://news.yahoo.co.jp/articles/abc?page=1", 2); got != "https://news.yahoo.co.jp/articles/abc?page=2" {
+		t.Errorf("pageURL() = %v", got)
+	}
+}
+
+func Test_withPaywallNotice(t *testing.T) {
+	if got, err := withPaywallNotice("本文", false); err != nil || got != "本文" {
+		t.Errorf("withPaywallNotice() = %v, %v", got, err)
+	}
+	if got, err := withPaywallNotice("無料部分", true); err != nil || got != "無料部分\n\n"+notePaywallNotice {
+		t.Errorf("withPaywallNotice() = %v, %v", got, err)
+	}
+	if _, err := withPaywallNotice(" ", true); !errors.Is(err, ErrPaywalled) {
+		t.Errorf("withPaywallNotice() error = %v, want ErrPaywalled", err)
+	}
+}
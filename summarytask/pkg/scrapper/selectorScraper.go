@@ -0,0 +1,156 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// removeElementsScriptはセレクターに一致する要素をページから取り除く
+const removeElementsScript = `(selectors) => {
+	for (const selector of selectors) {
+		document.querySelectorAll(selector).forEach((e) => e.remove());
+	}
+}`
+
+// waitForTimeoutはWaitForの要素の表示を待つ時間(ミリ秒)
+const waitForTimeout = 30000
+
+// SelectorScraperはSelectorRuleのセレクターでページを抽出する
+// 本文が見つからない場合はFallbackのスクレイパーで抽出する
+type SelectorScraper struct {
+	Rule     SelectorRule
+	Fallback PlaywrightScraper
+}
+
+func (s *SelectorScraper) Scrape(page playwright.Page) (title, body string, err error) {
+	if s.Rule.WaitFor != "" {
+		// 表示されない場合もレイアウトの変更などが考えられるため、そのまま抽出を試みる
+		_ = page.Locator(s.Rule.WaitFor).First().WaitFor(playwright.LocatorWaitForOptions{
+			Timeout: playwright.Float(waitForTimeout),
+		})
+	}
+	title, err = s.title(page)
+	if err != nil {
+		return "", "", err
+	}
+
+	visited := map[string]bool{}
+	var bodies []string
+	for i := 0; i < s.Rule.maxPages(); i++ {
+		visited[page.URL()] = true
+		b, err := s.scrapeBody(page)
+		if err != nil {
+			return "", "", err
+		}
+		if i == 0 && b == "" {
+			return s.fallback(page)
+		}
+		if b != "" {
+			bodies = append(bodies, b)
+		}
+		next, ok, err := s.nextPageURL(page, visited)
+		if err != nil {
+			return "", "", err
+		}
+		if !ok {
+			break
+		}
+		if _, err := page.Goto(next, gotoOptions); err != nil {
+			return "", "", fmt.Errorf("could not goto next page: %v", err)
+		}
+	}
+	return title, strings.Join(bodies, "\n\n"), nil
+}
+
+func (s *SelectorScraper) fallback(page playwright.Page) (string, string, error) {
+	if s.Fallback == nil {
+		return (&NormalScraper{}).Scrape(page)
+	}
+	return s.Fallback.Scrape(page)
+}
+
+// titleはTitleのセレクターに一致する要素のテキストを返す
+// 一致する要素がない場合はページのタイトルを返す
+func (s *SelectorScraper) title(page playwright.Page) (string, error) {
+	if s.Rule.Title != "" {
+		locator := page.Locator(s.Rule.Title).First()
+		count, err := locator.Count()
+		if err != nil {
+			return "", fmt.Errorf("could not get title: %v", err)
+		}
+		if count > 0 {
+			title, err := locator.InnerText()
+			if err != nil {
+				return "", fmt.Errorf("could not get title: %v", err)
+			}
+			if title = strings.TrimSpace(title); title != "" {
+				return title, nil
+			}
+		}
+	}
+	title, err := page.Title()
+	if err != nil {
+		return "", fmt.Errorf("could not get title: %v", err)
+	}
+	return strings.TrimSpace(title), nil
+}
+
+// scrapeBodyは不要な要素を取り除いた後、表示中のページの本文をMarkdownで返す
+func (s *SelectorScraper) scrapeBody(page playwright.Page) (string, error) {
+	if len(s.Rule.Remove) > 0 {
+		if _, err := page.Evaluate(removeElementsScript, s.Rule.Remove); err != nil {
+			return "", fmt.Errorf("could not remove elements: %v", err)
+		}
+	}
+	var bodies []string
+	for _, selector := range s.Rule.Body {
+		b, err := markdownOf(page.Locator(selector))
+		if err != nil {
+			return "", err
+		}
+		if b != "" {
+			bodies = append(bodies, b)
+		}
+	}
+	return strings.Join(bodies, "\n\n"), nil
+}
+
+// nextPageURLは次のページのURLを返す
+// 次のページへのリンクがない場合や、取得済みのページへのリンクの場合はfalseを返す
+func (s *SelectorScraper) nextPageURL(page playwright.Page, visited map[string]bool) (string, bool, error) {
+	if s.Rule.NextPage == "" {
+		return "", false, nil
+	}
+	hrefs, err := attributes(page.Locator(s.Rule.NextPage).First(), "href")
+	if err != nil {
+		return "", false, fmt.Errorf("could not get next page link: %v", err)
+	}
+	if len(hrefs) == 0 {
+		return "", false, nil
+	}
+	next, ok := resolveNextPage(page.URL(), hrefs[0])
+	if !ok || visited[next] {
+		return "", false, nil
+	}
+	return next, true, nil
+}
+
+// resolveNextPageはページ送りのリンクを絶対URLに変換する
+func resolveNextPage(base string, href string) (string, bool) {
+	if strings.TrimSpace(href) == "" {
+		return "", false
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", false
+	}
+	u, err := baseURL.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	u.Fragment = ""
+	return u.String(), true
+}
@@ -0,0 +1,66 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// ErrEmptyCaptureは保存するページの内容がない場合のエラー
+var ErrEmptyCapture = errors.New("capture is empty")
+
+// ObjectStorageはスナップショットを保存するオブジェクトストレージ
+type ObjectStorage interface {
+	PutObject(ctx context.Context, key string, contentType string, body []byte) error
+}
+
+// Archiverはクローラーが取得した時点のページをタスクIDと内容のSHA-256をキーとして保存する
+type Archiver struct {
+	storage ObjectStorage
+	now     func() time.Time
+}
+
+func NewArchiver(storage ObjectStorage) *Archiver {
+	return &Archiver{storage: storage, now: time.Now}
+}
+
+// Saveはページの生の内容と、あればスクリーンショットとMHTMLを保存し、保存したスナップショットを返す
+func (a *Archiver) Save(ctx context.Context, taskId string, capture *entities.PageCapture) (*entities.PageSnapshot, error) {
+	if capture.Empty() {
+		return nil, ErrEmptyCapture
+	}
+	hash := capture.ContentHash()
+	prefix := entities.SnapshotKeyPrefix(taskId, hash)
+	contentType := capture.ContentType
+	if contentType == "" {
+		contentType = "text/html; charset=utf-8"
+	}
+	snapshot := &entities.PageSnapshot{
+		Url:         capture.Url,
+		ContentHash: hash,
+		ContentType: contentType,
+		RawKey:      prefix + entities.SnapshotRawFileName(contentType),
+		CapturedAt:  a.now().Unix(),
+	}
+	if err := a.storage.PutObject(ctx, snapshot.RawKey, contentType, capture.Raw); err != nil {
+		return nil, fmt.Errorf("failed to put raw content: %w", err)
+	}
+	if len(capture.Screenshot) > 0 {
+		key := prefix + "screenshot.png"
+		if err := a.storage.PutObject(ctx, key, "image/png", capture.Screenshot); err != nil {
+			return nil, fmt.Errorf("failed to put screenshot: %w", err)
+		}
+		snapshot.ScreenshotKey = key
+	}
+	if len(capture.MHTML) > 0 {
+		key := prefix + "page.mhtml"
+		if err := a.storage.PutObject(ctx, key, "multipart/related", capture.MHTML); err != nil {
+			return nil, fmt.Errorf("failed to put mhtml: %w", err)
+		}
+		snapshot.MHTMLKey = key
+	}
+	return snapshot, nil
+}
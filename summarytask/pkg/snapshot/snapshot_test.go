@@ -0,0 +1,72 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+type memoryStorage struct {
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+func (s *memoryStorage) PutObject(ctx context.Context, key string, contentType string, body []byte) error {
+	s.objects[key] = body
+	s.contentTypes[key] = contentType
+	return nil
+}
+
+func Test_Archiver_Save(t *testing.T) {
+	storage := &memoryStorage{objects: map[string][]byte{}, contentTypes: map[string]string{}}
+	sut := NewArchiver(storage)
+	sut.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	capture := &entities.PageCapture{
+		Url:         "https://example.com/article",
+		ContentType: "text/html; charset=utf-8",
+		Raw:         []byte("<html>hello</html>"),
+		Screenshot:  []byte("png"),
+		MHTML:       []byte("mhtml"),
+	}
+	got, err := sut.Save(context.Background(), "task-1", capture)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// sha256("<html>hello</html>")
+	hash := "7e537e903df5bfa9c9de2dc590d2646f8b4aa71dd14877bd3e2eceda829a4618"
+	prefix := "snapshots/task-1/" + hash + "/"
+	want := &entities.PageSnapshot{
+		Url:           "https://example.com/article",
+		ContentHash:   hash,
+		ContentType:   "text/html; charset=utf-8",
+		RawKey:        prefix + "page.html",
+		ScreenshotKey: prefix + "screenshot.png",
+		MHTMLKey:      prefix + "page.mhtml",
+		CapturedAt:    1700000000,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Save() mismatch (-want +got):\n%s", diff)
+	}
+	if string(storage.objects[prefix+"page.html"]) != "<html>hello</html>" || storage.contentTypes[prefix+"screenshot.png"] != "image/png" {
+		t.Errorf("unexpected objects: %v", storage.contentTypes)
+	}
+
+	// HTTPで取得したPDFはスクリーンショットを保存しない
+	pdf, err := sut.Save(context.Background(), "task-2", &entities.PageCapture{ContentType: "application/pdf", Raw: []byte("%PDF-1.7")})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if pdf.ScreenshotKey != "" || pdf.MHTMLKey != "" || !strings.HasSuffix(pdf.RawKey, "/page.pdf") {
+		t.Errorf("unexpected snapshot: %+v", pdf)
+	}
+
+	if _, err := sut.Save(context.Background(), "task-3", &entities.PageCapture{}); !errors.Is(err, ErrEmptyCapture) {
+		t.Errorf("Save() error = %v, want ErrEmptyCapture", err)
+	}
+}
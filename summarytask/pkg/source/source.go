@@ -0,0 +1,49 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// ErrNoTranscriptは字幕が公開されていない場合や、字幕に表示区間がない場合のエラー
+var ErrNoTranscript = errors.New("transcript is not available")
+
+// Crawlerはページのタイトルと本文を取得する
+type Crawler interface {
+	FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error)
+}
+
+// Sourceは動画の字幕など、Webページ以外の形式のコンテンツを要約の本文として取得する
+type Source interface {
+	Crawler
+	// Matchはurlがこのソースで取得する対象かを返す
+	Match(u *url.URL) bool
+}
+
+// RouterはURLに一致するSourceで本文を取得し、どのSourceにも一致しない場合はWebページとしてCrawlerで取得する
+type Router struct {
+	crawler Crawler
+	sources []Source
+}
+
+// NewRouterはRouterを生成する。sourcesは指定した順に評価する
+func NewRouter(crawler Crawler, sources ...Source) *Router {
+	return &Router{crawler: crawler, sources: sources}
+}
+
+func (r *Router) FetchContents(ctx context.Context, rawURL string, options *entities.FetchOptions) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	for _, s := range r.sources {
+		if s.Match(u) {
+			return s.FetchContents(ctx, rawURL, options)
+		}
+	}
+	return r.crawler.FetchContents(ctx, rawURL, options)
+}
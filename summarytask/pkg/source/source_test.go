@@ -0,0 +1,105 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+type fakeCrawler struct {
+	name string
+	urls []string
+}
+
+func (c *fakeCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	c.urls = append(c.urls, url)
+	return c.name, "", nil
+}
+
+type fakeSource struct {
+	fakeCrawler
+	host string
+}
+
+func (s *fakeSource) Match(u *url.URL) bool {
+	return u.Hostname() == s.host
+}
+
+func Test_Router_FetchContents(t *testing.T) {
+	crawler := &fakeCrawler{name: "page"}
+	video := &fakeSource{fakeCrawler: fakeCrawler{name: "video"}, host: "video.example.com"}
+	sut := NewRouter(crawler, video)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://video.example.com/watch/1", want: "video"},
+		{url: "https://example.com/article", want: "page"},
+	}
+	for _, tt := range tests {
+		title, _, err := sut.FetchContents(context.Background(), tt.url, nil)
+		if err != nil {
+			t.Fatalf("FetchContents() error = %v", err)
+		}
+		if title != tt.want {
+			t.Errorf("FetchContents(%s) routed to %v, want %v", tt.url, title, tt.want)
+		}
+	}
+}
+
+func Test_TranscriptSource_FetchContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/episodes/42.vtt":
+			w.Header().Set("Content-Type", "text/vtt")
+			fmt.Fprint(w, "WEBVTT\n\n00:00:00.000 --> 00:00:05.000\nようこそ\n\n00:01:10.000 --> 00:01:15.000\n本題です\n")
+		case "/episodes/43.srt":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprint(w, "1\n00:00:03,000 --> 00:00:05,000\nWelcome\n")
+		case "/episodes/44.vtt":
+			fmt.Fprint(w, "WEBVTT\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sut := NewTranscriptSource(server.Client())
+	tests := []struct {
+		path        string
+		wantTitle   string
+		wantContent string
+		wantErr     error
+	}{
+		{path: "/episodes/42.vtt", wantTitle: "42", wantContent: "[00:00] ようこそ\n\n[01:10] 本題です"},
+		{path: "/episodes/43.srt", wantTitle: "43", wantContent: "[00:03] Welcome"},
+		{path: "/episodes/44.vtt", wantErr: ErrNoTranscript},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			u, _ := url.Parse(server.URL + tt.path)
+			if !sut.Match(u) {
+				t.Fatalf("Match(%s) = false", u)
+			}
+			title, content, err := sut.FetchContents(context.Background(), u.String(), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FetchContents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if title != tt.wantTitle || content != tt.wantContent {
+				t.Errorf("FetchContents() = %q, %q, want %q, %q", title, content, tt.wantTitle, tt.wantContent)
+			}
+		})
+	}
+
+	if _, _, err := sut.FetchContents(context.Background(), server.URL+"/missing.vtt", nil); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("FetchContents() error = %v, want status error", err)
+	}
+}
@@ -0,0 +1,167 @@
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paragraphIntervalは字幕をまとめて1つの段落にする時間
+const paragraphInterval = 30 * time.Second
+
+// Cueは字幕の1つの表示区間
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+var (
+	// timingPatternは"00:01:02.345 --> 00:01:04.000"の形式の表示区間の行
+	// SRTは小数点にカンマを使い、WebVTTは時間を省略できる
+	timingPattern = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})\s*-->\s*((?:\d+:)?\d{1,2}:\d{2}[.,]\d{1,3})`)
+	// cueTagPatternは<c>や<v 話者>、<00:00:01.000>などの字幕内のタグ
+	cueTagPattern = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ParseVTTはWebVTTの字幕を読み込む
+func ParseVTT(r io.Reader) ([]Cue, error) {
+	cues, err := parseCues(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse vtt: %w", err)
+	}
+	return cues, nil
+}
+
+// ParseSRTはSubRip(SRT)の字幕を読み込む
+func ParseSRT(r io.Reader) ([]Cue, error) {
+	cues, err := parseCues(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse srt: %w", err)
+	}
+	return cues, nil
+}
+
+// parseCuesは空行で区切られたブロックから表示区間の行とその後のテキストを読み込む
+// WebVTTとSRTはブロックの構造が同じため、表示区間の行を含まないヘッダーや番号、NOTEなどは読み飛ばす
+func parseCues(r io.Reader) ([]Cue, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var cues []Cue
+	var current *Cue
+	var lines []string
+	flush := func() {
+		if current != nil {
+			current.Text = cueText(lines)
+			if current.Text != "" {
+				cues = append(cues, *current)
+			}
+		}
+		current, lines = nil, nil
+	}
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		line = strings.TrimPrefix(line, "\ufeff")
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if m := timingPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			start, err := parseTimestamp(m[1])
+			if err != nil {
+				return nil, err
+			}
+			end, err := parseTimestamp(m[2])
+			if err != nil {
+				return nil, err
+			}
+			current = &Cue{Start: start, End: end}
+			continue
+		}
+		if current != nil {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return cues, nil
+}
+
+func cueText(lines []string) string {
+	text := cueTagPattern.ReplaceAllString(strings.Join(lines, " "), "")
+	return strings.Join(strings.Fields(html.UnescapeString(text)), " ")
+}
+
+// parseTimestampは"01:02:03.456"、"02:03.456"、"01:02:03,456"の形式の時刻を読み込む
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	clock, fraction, _ := strings.Cut(s, ".")
+	parts := strings.Split(clock, ":")
+	var d time.Duration
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %s", s)
+		}
+		d = d*60 + time.Duration(n)
+	}
+	d *= time.Second
+	if fraction != "" {
+		// "5"は500ミリ秒、"05"は50ミリ秒として扱う
+		ms, err := strconv.Atoi((fraction + "00")[:3])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %s", s)
+		}
+		d += time.Duration(ms) * time.Millisecond
+	}
+	return d, nil
+}
+
+// FormatTranscriptは字幕を一定時間ごとの段落にまとめ、段落の先頭に開始時刻を付けたテキストにする
+// 自動生成の字幕では前の表示区間と同じ行が繰り返されるため、重複する行は取り除く
+func FormatTranscript(cues []Cue) string {
+	var paragraphs []string
+	var texts []string
+	var start time.Duration
+	previous := ""
+	flush := func() {
+		if len(texts) > 0 {
+			paragraphs = append(paragraphs, fmt.Sprintf("[%s] %s", FormatTimestamp(start), strings.Join(texts, " ")))
+		}
+		texts = nil
+	}
+	for _, cue := range cues {
+		text := strings.TrimSpace(strings.TrimPrefix(cue.Text, previous))
+		previous = cue.Text
+		if text == "" {
+			continue
+		}
+		if len(texts) > 0 && cue.Start-start >= paragraphInterval {
+			flush()
+		}
+		if len(texts) == 0 {
+			start = cue.Start
+		}
+		texts = append(texts, text)
+	}
+	flush()
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// FormatTimestampは時刻を"12:34"、1時間以上の場合は"1:23:45"の形式にする
+func FormatTimestamp(d time.Duration) string {
+	total := int(d / time.Second)
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
@@ -0,0 +1,80 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// maxTranscriptBytesは取得する字幕ファイルの最大のサイズ
+const maxTranscriptBytes = 10 << 20
+
+// TranscriptSourceはポッドキャストなどで公開されているWebVTTやSRTの字幕ファイルを取得する
+type TranscriptSource struct {
+	client *http.Client
+}
+
+func NewTranscriptSource(client *http.Client) *TranscriptSource {
+	return &TranscriptSource{client: client}
+}
+
+// Matchは拡張子が.vttまたは.srtのURLを対象とする
+func (s *TranscriptSource) Match(u *url.URL) bool {
+	ext := strings.ToLower(path.Ext(u.Path))
+	return ext == ".vtt" || ext == ".srt"
+}
+
+func (s *TranscriptSource) FetchContents(ctx context.Context, rawURL string, options *entities.FetchOptions) (string, string, error) {
+	body, contentType, err := get(ctx, s.client, rawURL, maxTranscriptBytes)
+	if err != nil {
+		return "", "", err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var cues []Cue
+	if mediaType == "application/x-subrip" || strings.EqualFold(path.Ext(u.Path), ".srt") {
+		cues, err = ParseSRT(strings.NewReader(body))
+	} else {
+		cues, err = ParseVTT(strings.NewReader(body))
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if len(cues) == 0 {
+		return "", "", ErrNoTranscript
+	}
+	title := strings.TrimSuffix(path.Base(u.Path), path.Ext(u.Path))
+	return title, FormatTranscript(cues), nil
+}
+
+// getはurlの本文とContent-Typeを返す
+func get(ctx context.Context, client *http.Client, rawURL string, limit int64) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept-Language", "ja,en;q=0.8")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read body: %w", err)
+	}
+	return string(b), resp.Header.Get("Content-Type"), nil
+}
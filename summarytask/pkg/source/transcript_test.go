@@ -0,0 +1,84 @@
+package source
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ParseVTT(t *testing.T) {
+	vtt := "\ufeffWEBVTT\nKind: captions\nLanguage: ja\n\n" +
+		"NOTE 自動生成の字幕\n\n" +
+		"00:00:01.000 --> 00:00:03.500 align:start position:0%\n" +
+		"<v 話者>こんにちは<00:00:02.000><c>、今日は</c>\n\n" +
+		"1\n00:02.5 --> 00:04.000\nAT&amp;Tの話です\n\n" +
+		"00:00:05.000 --> 00:00:06.000\n\n"
+	got, err := ParseVTT(strings.NewReader(vtt))
+	if err != nil {
+		t.Fatalf("ParseVTT() error = %v", err)
+	}
+	want := []Cue{
+		{Start: time.Second, End: 3500 * time.Millisecond, Text: "こんにちは、今日は"},
+		{Start: 2500 * time.Millisecond, End: 4 * time.Second, Text: "AT&Tの話です"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseVTT() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_ParseSRT(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:02,000\r\nFirst line\r\nsecond line\r\n\r\n" +
+		"2\r\n01:00:00,250 --> 01:00:01,000\r\n<i>Last</i>\r\n"
+	got, err := ParseSRT(strings.NewReader(srt))
+	if err != nil {
+		t.Fatalf("ParseSRT() error = %v", err)
+	}
+	want := []Cue{
+		{Start: time.Second, End: 2 * time.Second, Text: "First line second line"},
+		{Start: time.Hour + 250*time.Millisecond, End: time.Hour + time.Second, Text: "Last"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ParseSRT() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func Test_FormatTranscript(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, Text: "hello"},
+		// 自動生成の字幕は前の表示区間の行を繰り返す
+		{Start: 2 * time.Second, Text: "hello everyone"},
+		{Start: 4 * time.Second, Text: "hello everyone"},
+		{Start: 35 * time.Second, Text: "next topic"},
+		{Start: time.Hour + 5*time.Second, Text: "closing"},
+	}
+	want := "[00:00] hello everyone\n\n[00:35] next topic\n\n[1:00:05] closing"
+	if got := FormatTranscript(cues); got != want {
+		t.Errorf("FormatTranscript() = %q, want %q", got, want)
+	}
+}
+
+func Test_FormatTimestamp(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "00:00"},
+		{d: 83*time.Second + 900*time.Millisecond, want: "01:23"},
+		{d: 2*time.Hour + 3*time.Minute + 4*time.Second, want: "2:03:04"},
+	}
+	for _, tt := range tests {
+		if got := FormatTimestamp(tt.d); got != tt.want {
+			t.Errorf("FormatTimestamp(%v) = %v, want %v", tt.d, got, tt.want)
+		}
+	}
+}
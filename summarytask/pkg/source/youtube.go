@@ -0,0 +1,174 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+const (
+	youtubeBaseURL = "https://www.youtube.com"
+	// maxWatchPageBytesは取得する動画ページの最大のサイズ
+	maxWatchPageBytes = 10 << 20
+	// playerResponseMarkerは動画ページに埋め込まれた動画の情報のJSONの変数名
+	playerResponseMarker = "ytInitialPlayerResponse"
+)
+
+// youtubeVideoIdPatternはYouTubeの動画IDの形式
+var youtubeVideoIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// YouTubeSourceはYouTubeの動画の字幕を取得する
+// 字幕は動画ページに埋め込まれた字幕の一覧からWebVTTの形式で取得する
+type YouTubeSource struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewYouTubeSource(client *http.Client) *YouTubeSource {
+	return &YouTubeSource{client: client, baseURL: youtubeBaseURL}
+}
+
+func (s *YouTubeSource) Match(u *url.URL) bool {
+	_, ok := youtubeVideoId(u)
+	return ok
+}
+
+// youtubeVideoIdはYouTubeの動画のURLから動画IDを返す
+// watch?v=、youtu.be、shorts、live、embedの形式に対応する
+func youtubeVideoId(u *url.URL) (string, bool) {
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"/shorts/", "/live/", "/embed/"} {
+			if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+				id, _, _ = strings.Cut(rest, "/")
+			}
+		}
+	}
+	if !youtubeVideoIdPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+type youtubePlayerResponse struct {
+	VideoDetails struct {
+		Title  string `json:"title"`
+		Author string `json:"author"`
+	} `json:"videoDetails"`
+	Captions struct {
+		PlayerCaptionsTracklistRenderer struct {
+			CaptionTracks []youtubeCaptionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+}
+
+type youtubeCaptionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	// Kindは自動生成の字幕の場合"asr"
+	Kind string `json:"kind"`
+}
+
+func (s *YouTubeSource) FetchContents(ctx context.Context, rawURL string, options *entities.FetchOptions) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	id, ok := youtubeVideoId(u)
+	if !ok {
+		return "", "", fmt.Errorf("invalid youtube url: %s", rawURL)
+	}
+	page, _, err := get(ctx, s.client, s.baseURL+"/watch?v="+id, maxWatchPageBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get watch page: %w", err)
+	}
+	player, err := parsePlayerResponse(page)
+	if err != nil {
+		return "", "", err
+	}
+	track, ok := selectCaptionTrack(player.Captions.PlayerCaptionsTracklistRenderer.CaptionTracks)
+	if !ok {
+		return "", "", ErrNoTranscript
+	}
+	captionURL, err := url.Parse(track.BaseURL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse caption url: %w", err)
+	}
+	q := captionURL.Query()
+	q.Set("fmt", "vtt")
+	captionURL.RawQuery = q.Encode()
+	vtt, _, err := get(ctx, s.client, captionURL.String(), maxTranscriptBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get caption: %w", err)
+	}
+	cues, err := ParseVTT(strings.NewReader(vtt))
+	if err != nil {
+		return "", "", err
+	}
+	if len(cues) == 0 {
+		return "", "", ErrNoTranscript
+	}
+	return player.VideoDetails.Title, FormatTranscript(cues), nil
+}
+
+// parsePlayerResponseは動画ページに埋め込まれた動画の情報を読み込む
+func parsePlayerResponse(page string) (*youtubePlayerResponse, error) {
+	i := strings.Index(page, playerResponseMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("could not find player response")
+	}
+	j := strings.Index(page[i:], "{")
+	if j < 0 {
+		return nil, fmt.Errorf("could not find player response")
+	}
+	// JSONの後に続くスクリプトは読まないよう、1つの値のみをデコードする
+	var player youtubePlayerResponse
+	if err := json.NewDecoder(strings.NewReader(page[i+j:])).Decode(&player); err != nil {
+		return nil, fmt.Errorf("failed to decode player response: %w", err)
+	}
+	return &player, nil
+}
+
+// selectCaptionTrackは要約に利用する字幕を選ぶ
+// 自動生成の字幕の言語を動画の言語とみなし、その言語の手動の字幕、自動生成の字幕、その他の手動の字幕の順に優先する
+func selectCaptionTrack(tracks []youtubeCaptionTrack) (youtubeCaptionTrack, bool) {
+	var asr, manual *youtubeCaptionTrack
+	for i, t := range tracks {
+		if t.BaseURL == "" {
+			continue
+		}
+		if t.Kind == "asr" {
+			if asr == nil {
+				asr = &tracks[i]
+			}
+		} else if manual == nil {
+			manual = &tracks[i]
+		}
+	}
+	if asr != nil {
+		for _, t := range tracks {
+			if t.Kind != "asr" && t.BaseURL != "" && t.LanguageCode == asr.LanguageCode {
+				return t, true
+			}
+		}
+		return *asr, true
+	}
+	if manual != nil {
+		return *manual, true
+	}
+	return youtubeCaptionTrack{}, false
+}
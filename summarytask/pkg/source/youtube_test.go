@@ -0,0 +1,126 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func Test_youtubeVideoId(t *testing.T) {
+	tests := []struct {
+		url    string
+		want   string
+		wantOk bool
+	}{
+		{url: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s", want: "dQw4w9WgXcQ", wantOk: true},
+		{url: "https://m.youtube.com/watch?v=dQw4w9WgXcQ", want: "dQw4w9WgXcQ", wantOk: true},
+		{url: "https://youtu.be/dQw4w9WgXcQ?si=abc", want: "dQw4w9WgXcQ", wantOk: true},
+		{url: "https://www.youtube.com/shorts/dQw4w9WgXcQ", want: "dQw4w9WgXcQ", wantOk: true},
+		{url: "https://www.youtube.com/live/dQw4w9WgXcQ/", want: "dQw4w9WgXcQ", wantOk: true},
+		{url: "https://www.youtube.com/embed/dQw4w9WgXcQ", want: "dQw4w9WgXcQ", wantOk: true},
+		{url: "https://www.youtube.com/@channel", wantOk: false},
+		{url: "https://www.youtube.com/watch?v=short", wantOk: false},
+		{url: "https://example.com/watch?v=dQw4w9WgXcQ", wantOk: false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("failed to parse url: %v", err)
+		}
+		got, ok := youtubeVideoId(u)
+		if got != tt.want || ok != tt.wantOk {
+			t.Errorf("youtubeVideoId(%s) = %v, %v, want %v, %v", tt.url, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func Test_selectCaptionTrack(t *testing.T) {
+	tests := []struct {
+		name   string
+		tracks []youtubeCaptionTrack
+		want   string
+		wantOk bool
+	}{
+		{
+			name: "manual in spoken language",
+			tracks: []youtubeCaptionTrack{
+				{BaseURL: "en", LanguageCode: "en"},
+				{BaseURL: "ja-asr", LanguageCode: "ja", Kind: "asr"},
+				{BaseURL: "ja", LanguageCode: "ja"},
+			},
+			want:   "ja",
+			wantOk: true,
+		},
+		{
+			name: "asr",
+			tracks: []youtubeCaptionTrack{
+				{BaseURL: "en", LanguageCode: "en"},
+				{BaseURL: "ja-asr", LanguageCode: "ja", Kind: "asr"},
+			},
+			want:   "ja-asr",
+			wantOk: true,
+		},
+		{name: "manual only", tracks: []youtubeCaptionTrack{{BaseURL: "en", LanguageCode: "en"}}, want: "en", wantOk: true},
+		{name: "none", tracks: nil, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := selectCaptionTrack(tt.tracks)
+			if got.BaseURL != tt.want || ok != tt.wantOk {
+				t.Errorf("selectCaptionTrack() = %v, %v, want %v, %v", got.BaseURL, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func Test_YouTubeSource_FetchContents(t *testing.T) {
+	var server *httptest.Server
+	captions := true
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			if r.URL.Query().Get("v") != "dQw4w9WgXcQ" {
+				http.NotFound(w, r)
+				return
+			}
+			tracks := "[]"
+			if captions {
+				tracks = fmt.Sprintf(`[{"baseUrl":"%s/api/timedtext?v=dQw4w9WgXcQ&lang=ja","languageCode":"ja","kind":"asr"}]`, server.URL)
+			}
+			fmt.Fprintf(w, `<html><script>var ytInitialPlayerResponse = {"videoDetails":{"title":"テスト動画","author":"テスト"},`+
+				`"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":%s}}};var meta = {};</script></html>`, tracks)
+		case "/api/timedtext":
+			if r.URL.Query().Get("fmt") != "vtt" || r.URL.Query().Get("lang") != "ja" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nこんにちは\n\n00:00:02.000 --> 00:00:04.000\nこんにちは\n皆さん\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sut := NewYouTubeSource(server.Client())
+	sut.baseURL = server.URL
+
+	title, content, err := sut.FetchContents(context.Background(), "https://youtu.be/dQw4w9WgXcQ", nil)
+	if err != nil {
+		t.Fatalf("FetchContents() error = %v", err)
+	}
+	if title != "テスト動画" {
+		t.Errorf("title = %v", title)
+	}
+	if want := "[00:00] こんにちは 皆さん"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+
+	captions = false
+	if _, _, err := sut.FetchContents(context.Background(), "https://youtu.be/dQw4w9WgXcQ", nil); !errors.Is(err, ErrNoTranscript) {
+		t.Errorf("FetchContents() error = %v, want ErrNoTranscript", err)
+	}
+}
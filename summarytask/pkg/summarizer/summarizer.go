@@ -0,0 +1,91 @@
+package summarizer
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/anthropic"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/web-page-summarizer-task/pkg/task"
+	"github.com/shoet/webpagesummary/pkg/config"
+)
+
+const (
+	ProviderOpenAI           = "openai"
+	ProviderAnthropic        = "anthropic"
+	ProviderAzureOpenAI      = "azure-openai"
+	ProviderOpenAICompatible = "openai-compatible"
+)
+
+// NewSummarizerは設定(LLM_PROVIDER)に応じたSummarizerを生成する
+func NewSummarizer(cfg *config.Config, client *http.Client) (task.Summarizer, error) {
+	options := NewChatCompletionsOptions(&cfg.LLMConfig)
+	retryPolicy := NewRetryPolicy(&cfg.LLMConfig)
+	switch cfg.LLMProvider {
+	case ProviderOpenAI, "":
+		s, err := chatgpt.NewChatGPTService(cfg.OpenAIApiKey, client, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create openai service: %w", err)
+		}
+		s.SetRetryPolicy(retryPolicy)
+		return s, nil
+	case ProviderAnthropic:
+		s, err := anthropic.NewAnthropicService(cfg.AnthropicApiKey, client, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create anthropic service: %w", err)
+		}
+		s.SetRetryPolicy(retryPolicy)
+		return s, nil
+	case ProviderAzureOpenAI:
+		s, err := chatgpt.NewAzureOpenAIService(
+			cfg.AzureOpenAIApiKey,
+			cfg.AzureOpenAIEndpoint,
+			cfg.AzureOpenAIDeployment,
+			cfg.AzureOpenAIApiVersion,
+			client,
+			options,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create azure openai service: %w", err)
+		}
+		s.SetRetryPolicy(retryPolicy)
+		return s, nil
+	case ProviderOpenAICompatible:
+		s, err := chatgpt.NewOpenAICompatibleService(
+			cfg.OpenAICompatibleBaseUrl,
+			cfg.OpenAICompatibleApiKey,
+			client,
+			options,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create openai compatible service: %w", err)
+		}
+		s.SetRetryPolicy(retryPolicy)
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider: %s", cfg.LLMProvider)
+	}
+}
+
+// NewChatCompletionsOptionsは環境変数から読み込んだ設定をChatCompletionsOptionsに変換する
+func NewChatCompletionsOptions(cfg *config.LLMConfig) *chatgpt.ChatCompletionsOptions {
+	return &chatgpt.ChatCompletionsOptions{
+		Model:        cfg.LLMModel,
+		BaseUrl:      cfg.LLMBaseUrl,
+		Temperature:  cfg.LLMTemperature,
+		TopP:         cfg.LLMTopP,
+		MaxTokens:    cfg.LLMMaxTokens,
+		Seed:         cfg.LLMSeed,
+		Organization: cfg.OpenAIOrganization,
+	}
+}
+
+// NewRetryPolicyは環境変数から読み込んだ設定をRetryPolicyに変換する
+func NewRetryPolicy(cfg *config.LLMConfig) chatgpt.RetryPolicy {
+	return chatgpt.RetryPolicy{
+		MaxRetries: cfg.LLMMaxRetries,
+		BaseDelay:  time.Duration(cfg.LLMRetryBaseDelayMs) * time.Millisecond,
+		MaxDelay:   time.Duration(cfg.LLMRetryMaxDelayMs) * time.Millisecond,
+	}
+}
@@ -0,0 +1,88 @@
+package summarizer
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/anthropic"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/webpagesummary/pkg/config"
+)
+
+func Test_NewSummarizer(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.LLMConfig
+		apiKey  string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "openai",
+			config: config.LLMConfig{LLMProvider: ProviderOpenAI},
+			apiKey: "test_key",
+			want:   &chatgpt.ChatGPTService{},
+		},
+		{
+			name:   "anthropic",
+			config: config.LLMConfig{LLMProvider: ProviderAnthropic, AnthropicApiKey: "test_key"},
+			want:   &anthropic.AnthropicService{},
+		},
+		{
+			name: "azure openai",
+			config: config.LLMConfig{
+				LLMProvider:           ProviderAzureOpenAI,
+				AzureOpenAIApiKey:     "test_key",
+				AzureOpenAIEndpoint:   "https://example.openai.azure.com",
+				AzureOpenAIDeployment: "gpt-4",
+				AzureOpenAIApiVersion: "2024-02-01",
+			},
+			want: &chatgpt.ChatGPTService{},
+		},
+		{
+			name: "openai compatible",
+			config: config.LLMConfig{
+				LLMProvider:             ProviderOpenAICompatible,
+				LLMModel:                "llama3",
+				OpenAICompatibleBaseUrl: "http://localhost:11434/v1",
+			},
+			want: &chatgpt.ChatGPTService{},
+		},
+		{
+			name:    "openai compatible without model",
+			config:  config.LLMConfig{LLMProvider: ProviderOpenAICompatible, OpenAICompatibleBaseUrl: "http://localhost:11434/v1"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown provider",
+			config:  config.LLMConfig{LLMProvider: "unknown"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{OpenAIApiKey: tt.apiKey, LLMConfig: tt.config}
+			got, err := NewSummarizer(cfg, &http.Client{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create summarizer: %v", err)
+			}
+			switch tt.want.(type) {
+			case *chatgpt.ChatGPTService:
+				if _, ok := got.(*chatgpt.ChatGPTService); !ok {
+					t.Fatalf("got is not ChatGPTService: %T", got)
+				}
+			case *anthropic.AnthropicService:
+				if _, ok := got.(*anthropic.AnthropicService); !ok {
+					t.Fatalf("got is not AnthropicService: %T", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/logging"
+)
+
+// ErrCancelledはタスクのキャンセルが依頼されたため実行を中断した場合のエラー
+var ErrCancelled = errors.New("task is cancelled")
+
+// DefaultCancelPollIntervalはキャンセルの依頼を確認する既定の間隔
+const DefaultCancelPollInterval = 5 * time.Second
+
+// cancelRequestedReasonはキャンセルした場合に状態の履歴に記録する理由
+const cancelRequestedReason = "user_requested"
+
+// watchCancelはキャンセルの依頼をCancelPollIntervalごとに確認し、依頼された場合はErrCancelledを理由に
+// 返却するcontextをキャンセルする。実行中のページの取得やLLMへのリクエストはこのcontextで中断する
+// 返却する関数で確認を終了する
+func (st *SummaryTask) watchCancel(ctx context.Context, taskId string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(st.config.CancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				requested, err := st.repo.IsCancelRequested(ctx, taskId)
+				if err != nil {
+					// 確認できない場合は次の確認まで実行を継続する
+					logging.GetLogger(ctx).Error("failed to check cancel requested", err)
+					continue
+				}
+				if requested {
+					cancel(ErrCancelled)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		cancel(nil)
+	}
+}
+
+// checkCancelはキャンセルが依頼されている場合にErrCancelledを返す
+func (st *SummaryTask) checkCancel(ctx context.Context, taskId string) error {
+	if errors.Is(context.Cause(ctx), ErrCancelled) {
+		return ErrCancelled
+	}
+	requested, err := st.repo.IsCancelRequested(ctx, taskId)
+	if err != nil {
+		return fmt.Errorf("failed to check cancel requested: %w", err)
+	}
+	if requested {
+		return ErrCancelled
+	}
+	return nil
+}
+
+// cancelTaskはタスクをキャンセルした状態にする
+// 実行中のcontextはキャンセルされているため、キャンセルされないcontextで更新する
+func (st *SummaryTask) cancelTask(ctx context.Context, s *entities.Summary) error {
+	ctx = context.WithoutCancel(ctx)
+	if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusCancelled, cancelRequestedReason); err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
+	}
+	logging.GetLogger(ctx).Info(fmt.Sprintf("task is cancelled at attempt %d", s.Attempts))
+	return nil
+}
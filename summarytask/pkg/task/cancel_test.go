@@ -0,0 +1,151 @@
+package task
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt/chatgpttest"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/logging"
+)
+
+// cancellingCrawlerはページの取得中にキャンセルが依頼されたことを再現する
+type cancellingCrawler struct {
+	repo *memorySummaryRepository
+}
+
+func (c *cancellingCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	c.repo.requestCancel("task1")
+	return "記事", "記事の本文です。", nil
+}
+
+// blockingSummarizerは要約中にキャンセルが依頼され、ctxがキャンセルされるまで応答しないLLMを再現する
+type blockingSummarizer struct {
+	repo *memorySummaryRepository
+}
+
+func (s *blockingSummarizer) ChatCompletions(
+	ctx context.Context, input *chatgpt.ChatCompletionsInput,
+) (*chatgpt.ChatCompletionsOutput, error) {
+	s.repo.requestCancel("task1")
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(5 * time.Second):
+		return &chatgpt.ChatCompletionsOutput{Text: "ページの要約"}, nil
+	}
+}
+
+func (s *blockingSummarizer) Model() string {
+	return "gpt-4o-mini"
+}
+
+func Test_ExecuteSummaryTask_Cancel(t *testing.T) {
+	tests := []struct {
+		name       string
+		summary    *entities.Summary
+		crawler    func(repo *memorySummaryRepository) Crawler
+		summarizer func(t *testing.T, repo *memorySummaryRepository) Summarizer
+		wantFrom   entities.TaskStatus
+	}{
+		{
+			name: "before start",
+			summary: &entities.Summary{
+				Id: "task1", UserId: "user1", PageUrl: "https://example.com/article",
+				TaskStatus: entities.TaskStatusQueued, CancelRequested: true,
+			},
+			crawler: func(repo *memorySummaryRepository) Crawler { return &fakeCrawler{} },
+			summarizer: func(t *testing.T, repo *memorySummaryRepository) Summarizer {
+				return chatgpttest.NewServer(t).NewChatGPTService(t)
+			},
+			wantFrom: entities.TaskStatusQueued,
+		},
+		{
+			name: "between crawl and summarize",
+			summary: &entities.Summary{
+				Id: "task1", UserId: "user1", PageUrl: "https://example.com/article",
+				TaskStatus: entities.TaskStatusQueued,
+			},
+			crawler: func(repo *memorySummaryRepository) Crawler { return &cancellingCrawler{repo: repo} },
+			summarizer: func(t *testing.T, repo *memorySummaryRepository) Summarizer {
+				return chatgpttest.NewServer(t).NewChatGPTService(t)
+			},
+			wantFrom: entities.TaskStatusCrawled,
+		},
+		{
+			name: "while summarizing",
+			summary: &entities.Summary{
+				Id: "task1", UserId: "user1", PageUrl: "https://example.com/article",
+				TaskStatus: entities.TaskStatusQueued,
+			},
+			crawler: func(repo *memorySummaryRepository) Crawler {
+				return &fakeCrawler{title: "記事", content: "記事の本文です。"}
+			},
+			summarizer: func(t *testing.T, repo *memorySummaryRepository) Summarizer {
+				return &blockingSummarizer{repo: repo}
+			},
+			wantFrom: entities.TaskStatusSummarizing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+			repo := newMemorySummaryRepository(t, tt.summary)
+
+			sut := NewSummaryTask(
+				repo, nil, tt.crawler(repo), nil, tt.summarizer(t, repo),
+				&SummaryTaskConfig{CancelPollInterval: 10 * time.Millisecond},
+			)
+			if err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"}); err != nil {
+				t.Fatalf("ExecuteSummaryTask() error = %v", err)
+			}
+			got, err := repo.GetSummary(ctx, "task1", nil)
+			if err != nil {
+				t.Fatalf("failed to get summary: %v", err)
+			}
+			if got.TaskStatus != entities.TaskStatusCancelled {
+				t.Fatalf("TaskStatus = %v, want %v", got.TaskStatus, entities.TaskStatusCancelled)
+			}
+			last := got.StatusHistory[len(got.StatusHistory)-1]
+			if last.From != tt.wantFrom || last.Reason != cancelRequestedReason {
+				t.Errorf("last status change = %+v, want from %v", last, tt.wantFrom)
+			}
+			if got.Summary != "" {
+				t.Errorf("Summary = %q, want empty", got.Summary)
+			}
+		})
+	}
+}
+
+func Test_ExecuteSummaryTask_RetryAfterCancelRequested(t *testing.T) {
+	ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+	// 実行中にキャンセルが依頼された後、キャンセルを確認する前に別のエラーで失敗したタスク
+	summary := &entities.Summary{
+		Id: "task1", UserId: "user1", PageUrl: "https://example.com/article",
+		TaskStatus: entities.TaskStatusFailed, CancelRequested: true,
+		TaskFailedReason: string(entities.TaskFailureUpstreamUnavailable),
+	}
+	repo := newMemorySummaryRepository(t, summary)
+	if err := repo.TransitionTaskStatus(ctx, summary, entities.TaskStatusRetrying, "retry_requested"); err != nil {
+		t.Fatalf("failed to retry task: %v", err)
+	}
+
+	server := chatgpttest.NewServer(t, chatgpttest.Completion("ページの要約"))
+	sut := NewSummaryTask(
+		repo, nil, &fakeCrawler{title: "記事", content: "記事の本文です。"}, nil, server.NewChatGPTService(t),
+		&SummaryTaskConfig{CancelPollInterval: 10 * time.Millisecond},
+	)
+	if err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"}); err != nil {
+		t.Fatalf("ExecuteSummaryTask() error = %v", err)
+	}
+	got, err := repo.GetSummary(ctx, "task1", nil)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if got.TaskStatus != entities.TaskStatusComplete || got.CancelRequested {
+		t.Errorf("TaskStatus = %v, CancelRequested = %v, want complete without cancel request", got.TaskStatus, got.CancelRequested)
+	}
+}
@@ -0,0 +1,158 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/web-page-summarizer-task/pkg/crawler"
+	"github.com/shoet/web-page-summarizer-task/pkg/document"
+	"github.com/shoet/web-page-summarizer-task/pkg/pdftext"
+	"github.com/shoet/web-page-summarizer-task/pkg/politeness"
+	scraper "github.com/shoet/web-page-summarizer-task/pkg/scrapper"
+	"github.com/shoet/web-page-summarizer-task/pkg/source"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/logging"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
+)
+
+// RetryPolicyは一時的なエラーで失敗したタスクの再実行の方法
+type RetryPolicy struct {
+	// MaxAttemptsは初回を含めてタスクを実行する最大の回数
+	MaxAttempts int
+	// BaseDelayは1回目の再実行までの待機時間 (以降は指数関数的に増加する)
+	BaseDelay time.Duration
+	// MaxDelayは1回あたりの待機時間の上限。SQSの可視性タイムアウトの上限(12時間)を超えないようにする
+	MaxDelay time.Duration
+	// MaxReceiveCountはSQSのリドライブポリシーのmaxReceiveCount。0の場合は受信回数で制限しない
+	// この回数受信したメッセージは次に受信できないため、失敗した場合は再実行しない
+	MaxReceiveCount int
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Minute,
+	MaxDelay:    15 * time.Minute,
+}
+
+// Delayはattempt回目(1始まり)の実行が失敗した後、再実行するまでの待機時間を返す
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	// 混雑時に失敗したタスクが同時に再実行されないように待機時間を半分から全体の間でばらつかせる
+	if half := int64(delay / 2); half > 0 {
+		delay = time.Duration(half + rand.Int63n(half+1))
+	}
+	return delay
+}
+
+// ClassifyFailureはタスクのエラーを失敗の分類に変換する
+func ClassifyFailure(err error) entities.TaskFailureCode {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrInterrupted):
+		return entities.TaskFailureInterrupted
+	case errors.Is(err, chatgpt.ErrRateLimited):
+		return entities.TaskFailureRateLimited
+	case errors.Is(err, chatgpt.ErrTimeout),
+		errors.Is(err, playwright.ErrTimeout),
+		errors.Is(err, politeness.ErrThrottleTimeout),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return entities.TaskFailureTimeout
+	case errors.Is(err, chatgpt.ErrServerError):
+		return entities.TaskFailureUpstreamUnavailable
+	case errors.Is(err, chatgpt.ErrContextLengthExceeded):
+		return entities.TaskFailureContentTooLong
+	case errors.Is(err, chatgpt.ErrInvalidAPIKey):
+		return entities.TaskFailureConfiguration
+	case errors.Is(err, urlpolicy.ErrBlockedURL),
+		errors.Is(err, urlpolicy.ErrInvalidURL):
+		return entities.TaskFailureBlockedURL
+	case errors.Is(err, politeness.ErrDisallowedByRobots):
+		return entities.TaskFailureDisallowedByRobots
+	case errors.Is(err, crawler.ErrUnreadablePDF),
+		errors.Is(err, pdftext.ErrNoText),
+		errors.Is(err, document.ErrEmptyDocument),
+		errors.Is(err, source.ErrNoTranscript),
+		errors.Is(err, scraper.ErrPaywalled):
+		return entities.TaskFailureNoContent
+	case errors.Is(err, ErrEmptySummary),
+		errors.Is(err, ErrInvalidStructuredSummary):
+		return entities.TaskFailureInvalidOutput
+	case errors.Is(err, ErrMissingSource),
+		errors.Is(err, entities.ErrInvalidPageRanges),
+		errors.Is(err, entities.ErrUnsupportedUpload),
+		errors.Is(err, entities.ErrUploadTooLarge):
+		return entities.TaskFailureInvalidRequest
+	}
+	return entities.TaskFailureUnknown
+}
+
+// FailureDecisionは失敗したタスクのメッセージの扱い
+type FailureDecision struct {
+	Code    entities.TaskFailureCode
+	Attempt int
+	// Retryがtrueの場合は、Delayの後にメッセージを再び受信して再実行する
+	Retry bool
+	Delay time.Duration
+	// DeadLetterがtrueの場合は、再実行しても成功しないためメッセージをデッドレターキューに移す
+	// Retry、DeadLetterのいずれもfalseの場合はメッセージを削除する
+	DeadLetter bool
+}
+
+// FailTaskはタスクのエラーを分類し、一時的な失敗で最大の実行回数に達していない場合は再実行を待つ状態に、
+// それ以外の場合は失敗した状態にする
+// 実行回数はメッセージの受信回数(receiveCount)で数え、ページの取得を開始する前の失敗も実行回数に含める
+// 他のワーカーが状態を変えた場合や、既に終了したタスクの場合は状態を変えない
+func (st *SummaryTask) FailTask(
+	ctx context.Context, taskId string, receiveCount int, cause error,
+) (*FailureDecision, error) {
+	decision := &FailureDecision{Code: ClassifyFailure(cause)}
+	if errors.Is(cause, entities.ErrInvalidTransition) {
+		// 重複して配信されたメッセージを他のワーカーが処理している
+		return decision, nil
+	}
+	s, err := st.repo.GetSummary(ctx, taskId, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+	// ローカルでの実行時など受信回数が分からない場合は、ページの取得を開始した回数で数える
+	decision.Attempt = s.Attempts
+	if receiveCount > decision.Attempt {
+		decision.Attempt = receiveCount
+	}
+	if s.TaskStatus.Terminal() || s.TaskStatus == entities.TaskStatusFailed {
+		return decision, nil
+	}
+	policy := st.config.RetryPolicy
+	redelivered := policy.MaxReceiveCount <= 0 || receiveCount < policy.MaxReceiveCount
+	if decision.Code.Retryable() && decision.Attempt < policy.MaxAttempts && redelivered {
+		// 実行を開始する前に失敗した場合は、queuedやretryingのまま再実行を待つ
+		if s.TaskStatus.CanTransitionTo(entities.TaskStatusRetrying) {
+			if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusRetrying, string(decision.Code)); err != nil {
+				return nil, fmt.Errorf("failed to update task status: %w", err)
+			}
+		}
+		decision.Retry = true
+		decision.Delay = policy.Delay(decision.Attempt)
+		return decision, nil
+	}
+	if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusFailed, string(decision.Code)); err != nil {
+		return nil, fmt.Errorf("failed to update task status: %w", err)
+	}
+	decision.DeadLetter = true
+	logging.GetLogger(ctx).Logger().Info().
+		Str("failureCode", string(decision.Code)).
+		Int("attempts", decision.Attempt).
+		Msg("task is failed")
+	return decision, nil
+}
@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt/chatgpttest"
+	"github.com/shoet/web-page-summarizer-task/pkg/politeness"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/logging"
+	"github.com/shoet/webpagesummary/pkg/urlpolicy"
+)
+
+func Test_ClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want entities.TaskFailureCode
+	}{
+		{name: "llm timeout", err: fmt.Errorf("failed to summarize: %w", chatgpt.ErrTimeout), want: entities.TaskFailureTimeout},
+		{name: "navigation timeout", err: fmt.Errorf("could not goto page: %w", playwright.ErrTimeout), want: entities.TaskFailureTimeout},
+		{name: "throttle timeout", err: politeness.ErrThrottleTimeout, want: entities.TaskFailureTimeout},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: entities.TaskFailureTimeout},
+		{name: "rate limited", err: chatgpt.ErrRateLimited, want: entities.TaskFailureRateLimited},
+		{name: "server error", err: chatgpt.ErrServerError, want: entities.TaskFailureUpstreamUnavailable},
+		{name: "context length", err: chatgpt.ErrContextLengthExceeded, want: entities.TaskFailureContentTooLong},
+		{name: "invalid api key", err: chatgpt.ErrInvalidAPIKey, want: entities.TaskFailureConfiguration},
+		{name: "blocked url", err: fmt.Errorf("could not goto page: %w", urlpolicy.ErrBlockedURL), want: entities.TaskFailureBlockedURL},
+		{name: "robots", err: politeness.ErrDisallowedByRobots, want: entities.TaskFailureDisallowedByRobots},
+		{name: "empty summary", err: fmt.Errorf("failed to get summary: %w", ErrEmptySummary), want: entities.TaskFailureInvalidOutput},
+		{name: "missing source", err: ErrMissingSource, want: entities.TaskFailureInvalidRequest},
+		{name: "interrupted", err: ErrInterrupted, want: entities.TaskFailureInterrupted},
+		{name: "unknown", err: errors.New("something went wrong"), want: entities.TaskFailureUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyFailure(tt.err); got != tt.want {
+				t.Errorf("ClassifyFailure() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_RetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Minute, MaxDelay: 5 * time.Minute}
+	for i, max := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		attempt := i + 1
+		got := policy.Delay(attempt)
+		if got < max/2 || got > max {
+			t.Errorf("Delay(%d) = %v, want between %v and %v", attempt, got, max/2, max)
+		}
+	}
+}
+
+func Test_SummaryTask_FailTask(t *testing.T) {
+	tests := []struct {
+		name           string
+		summary        *entities.Summary
+		receiveCount   int
+		policy         RetryPolicy
+		cause          error
+		wantStatus     entities.TaskStatus
+		wantRetry      bool
+		wantDeadLetter bool
+	}{
+		{
+			name:       "retry timeout",
+			summary:    &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusCrawling, Attempts: 1},
+			cause:      fmt.Errorf("could not goto page: %w", playwright.ErrTimeout),
+			wantStatus: entities.TaskStatusRetrying,
+			wantRetry:  true,
+		},
+		{
+			name:           "max attempts exceeded",
+			summary:        &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusSummarizing, Attempts: 3},
+			cause:          chatgpt.ErrRateLimited,
+			wantStatus:     entities.TaskStatusFailed,
+			wantDeadLetter: true,
+		},
+		{
+			name:           "permanent failure",
+			summary:        &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusCrawling, Attempts: 1},
+			cause:          politeness.ErrDisallowedByRobots,
+			wantStatus:     entities.TaskStatusFailed,
+			wantDeadLetter: true,
+		},
+		{
+			// 実行を開始する前の失敗は状態を変えずに再実行する
+			name:       "before crawling",
+			summary:    &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusQueued},
+			cause:      context.DeadlineExceeded,
+			wantStatus: entities.TaskStatusQueued,
+			wantRetry:  true,
+		},
+		{
+			// 実行を開始する前の失敗も受信回数で実行回数に数える
+			name:           "max receives before crawling",
+			summary:        &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusRetrying},
+			receiveCount:   3,
+			cause:          context.DeadlineExceeded,
+			wantStatus:     entities.TaskStatusFailed,
+			wantDeadLetter: true,
+		},
+		{
+			// リドライブポリシーでデッドレターキューに移される前に失敗した状態にする
+			name:    "redrive limit",
+			summary: &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusCrawling, Attempts: 2},
+			policy: RetryPolicy{
+				MaxAttempts: 10, BaseDelay: time.Minute, MaxDelay: time.Hour, MaxReceiveCount: 5,
+			},
+			receiveCount:   5,
+			cause:          chatgpt.ErrServerError,
+			wantStatus:     entities.TaskStatusFailed,
+			wantDeadLetter: true,
+		},
+		{
+			name:    "below redrive limit",
+			summary: &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusQueued},
+			policy: RetryPolicy{
+				MaxAttempts: 10, BaseDelay: time.Minute, MaxDelay: time.Hour, MaxReceiveCount: 5,
+			},
+			receiveCount: 4,
+			cause:        chatgpt.ErrServerError,
+			wantStatus:   entities.TaskStatusQueued,
+			wantRetry:    true,
+		},
+		{
+			name:       "already complete",
+			summary:    &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusComplete},
+			cause:      chatgpt.ErrTimeout,
+			wantStatus: entities.TaskStatusComplete,
+		},
+		{
+			// 他のワーカーが状態を変えた場合はメッセージを削除するのみ
+			name:       "handled by other worker",
+			summary:    &entities.Summary{Id: "task1", UserId: "user1", TaskStatus: entities.TaskStatusSummarizing},
+			cause:      fmt.Errorf("failed to update task status: %w", entities.ErrInvalidTransition),
+			wantStatus: entities.TaskStatusSummarizing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+			repo := newMemorySummaryRepository(t, tt.summary)
+			sut := NewSummaryTask(repo, nil, &fakeCrawler{}, nil, nil, &SummaryTaskConfig{RetryPolicy: tt.policy})
+
+			got, err := sut.FailTask(ctx, "task1", tt.receiveCount, tt.cause)
+			if err != nil {
+				t.Fatalf("FailTask() error = %v", err)
+			}
+			if got.Retry != tt.wantRetry || got.DeadLetter != tt.wantDeadLetter {
+				t.Errorf("FailTask() = %+v, want retry %v, dead letter %v", got, tt.wantRetry, tt.wantDeadLetter)
+			}
+			if got.Retry && got.Delay <= 0 {
+				t.Errorf("Delay = %v, want positive", got.Delay)
+			}
+			s, err := repo.GetSummary(ctx, "task1", nil)
+			if err != nil {
+				t.Fatalf("failed to get summary: %v", err)
+			}
+			if s.TaskStatus != tt.wantStatus {
+				t.Errorf("TaskStatus = %v, want %v", s.TaskStatus, tt.wantStatus)
+			}
+			if tt.wantDeadLetter && s.TaskFailedReason != string(got.Code) {
+				t.Errorf("TaskFailedReason = %v, want %v", s.TaskFailedReason, got.Code)
+			}
+		})
+	}
+}
+
+func Test_ExecuteSummaryTask_Interrupted(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempts   int
+		wantErr    error
+		wantStatus entities.TaskStatus
+	}{
+		// 中断された実行を再開し、実行回数を数える
+		{name: "resume", attempts: 1, wantStatus: entities.TaskStatusComplete},
+		{name: "max attempts exceeded", attempts: 3, wantErr: ErrInterrupted, wantStatus: entities.TaskStatusCrawling},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+			server := chatgpttest.NewServer(t, chatgpttest.Completion("ページの要約"))
+			repo := newMemorySummaryRepository(t, &entities.Summary{
+				Id: "task1", UserId: "user1", PageUrl: "https://example.com/article",
+				TaskStatus: entities.TaskStatusCrawling, Attempts: tt.attempts,
+			})
+			c := &fakeCrawler{title: "記事", content: "記事の本文です。"}
+
+			sut := NewSummaryTask(repo, nil, c, nil, server.NewChatGPTService(t), nil)
+			err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExecuteSummaryTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, err := repo.GetSummary(ctx, "task1", nil)
+			if err != nil {
+				t.Fatalf("failed to get summary: %v", err)
+			}
+			if got.TaskStatus != tt.wantStatus {
+				t.Errorf("TaskStatus = %v, want %v", got.TaskStatus, tt.wantStatus)
+			}
+			if tt.wantErr == nil && got.Attempts != tt.attempts+1 {
+				t.Errorf("Attempts = %v, want %v", got.Attempts, tt.attempts+1)
+			}
+		})
+	}
+}
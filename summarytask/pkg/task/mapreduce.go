@@ -0,0 +1,136 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/logging"
+)
+
+// maxReduceDepthは部分要約を再分割して統合する最大の回数
+const maxReduceDepth = 3
+
+// summarizeContentは本文を要約する
+// プロンプトがトークン予算に収まる場合は一度で要約し、収まらない場合は
+// 本文をチャンクに分割してそれぞれを要約(map)した後に部分要約を統合(reduce)する
+// 返却するUsageは分割した要約を含むすべてのリクエストの合計
+func (st *SummaryTask) summarizeContent(
+	ctx context.Context, s *entities.Summary, title string, content string,
+) (*chatgpt.ChatCompletionsOutput, error) {
+	logger := logging.GetLogger(ctx)
+	input := &chatgpt.ChatCompletionsInput{
+		Options: NewChatCompletionsOptions(s.Options),
+	}
+	if s.Structured {
+		// 部分要約はテキストで生成し、最後のリクエストのみ構造化出力にする
+		input.Tool = chatgpt.StructuredSummaryTool
+	}
+	budget := st.config.TokenBudgets.ForModel(st.model(input.Options))
+
+	build, err := st.promptBuilder(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := build(&chatgpt.SummaryTemplateInput{
+		Title:    title,
+		Content:  content,
+		Language: outputLanguage(s),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build template: %w", err)
+	}
+	if chatgpt.EstimateTokens(prompt) <= budget {
+		input.Text = prompt
+		return st.summarize(ctx, s, input)
+	}
+
+	logger.Info(fmt.Sprintf("content exceeds token budget %d, summarize with map-reduce", budget))
+	var usage chatgpt.Usage
+	summaries, err := st.mapSummaries(ctx, input.Options, title, outputLanguage(s), content, budget, &usage)
+	if err != nil {
+		return nil, err
+	}
+	for depth := 0; ; depth++ {
+		prompt, err := reducePrompt(s, build, title, summaries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build template: %w", err)
+		}
+		if chatgpt.EstimateTokens(prompt) <= budget {
+			input.Text = prompt
+			output, err := st.summarize(ctx, s, input)
+			if err != nil {
+				return nil, err
+			}
+			output.Usage = output.Usage.Add(usage)
+			return output, nil
+		}
+		if depth >= maxReduceDepth {
+			return nil, fmt.Errorf("failed to reduce summaries within token budget %d", budget)
+		}
+		// 部分要約を合わせても予算を超える場合は部分要約を本文として再度分割して要約する
+		summaries, err = st.mapSummaries(
+			ctx, input.Options, title, outputLanguage(s), strings.Join(summaries, "\n"), budget, &usage)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// mapSummariesは本文をトークン予算に収まるチャンクに分割し、チャンクごとの要約を返す
+// チャンクごとの要約もlanguageで出力し、要約で消費したトークン数はusageに加算する
+func (st *SummaryTask) mapSummaries(
+	ctx context.Context,
+	options *chatgpt.ChatCompletionsOptions,
+	title string,
+	language string,
+	content string,
+	budget int,
+	usage *chatgpt.Usage,
+) ([]string, error) {
+	logger := logging.GetLogger(ctx)
+	// チャンク以外のテンプレート部分のトークン数を差し引いて1チャンクの予算とする
+	overhead, err := chatgpt.ChunkSummaryTemplateBuilder(
+		&chatgpt.ChunkSummaryTemplateInput{Title: title, Index: 9999, Total: 9999, Language: language})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build template: %w", err)
+	}
+	chunkBudget := budget - chatgpt.EstimateTokens(overhead)
+	if chunkBudget < 1 {
+		return nil, fmt.Errorf("failed to split content: template exceeds token budget %d", budget)
+	}
+
+	chunks := chatgpt.SplitByTokens(content, chunkBudget)
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		logger.Info(fmt.Sprintf("summarize chunk %d/%d", i+1, len(chunks)))
+		prompt, err := chatgpt.ChunkSummaryTemplateBuilder(&chatgpt.ChunkSummaryTemplateInput{
+			Title:    title,
+			Content:  chunk,
+			Index:    i + 1,
+			Total:    len(chunks),
+			Language: language,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build template: %w", err)
+		}
+		output, err := st.summarizer.ChatCompletions(
+			ctx, &chatgpt.ChatCompletionsInput{Text: prompt, Options: options})
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		*usage = usage.Add(output.Usage)
+		summaries = append(summaries, output.Text)
+	}
+	return summaries, nil
+}
+
+// modelはタスクのオプションを考慮したリクエストのモデル名を返す
+func (st *SummaryTask) model(options *chatgpt.ChatCompletionsOptions) string {
+	if options != nil && options.Model != "" {
+		return options.Model
+	}
+	return st.summarizer.Model()
+}
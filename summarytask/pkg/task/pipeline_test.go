@@ -0,0 +1,602 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt/chatgpttest"
+	"github.com/shoet/web-page-summarizer-task/pkg/document"
+	"github.com/shoet/web-page-summarizer-task/pkg/language"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+	"github.com/shoet/webpagesummary/pkg/logging"
+)
+
+// memorySummaryRepositoryはDynamoDBのUpdateSummaryと同様に空でない項目のみを上書きするインメモリのリポジトリ
+// 状態はTransitionTaskStatusでのみ更新する
+type memorySummaryRepository struct {
+	mu        sync.Mutex
+	summaries map[string]map[string]types.AttributeValue
+}
+
+func newMemorySummaryRepository(t *testing.T, summaries ...*entities.Summary) *memorySummaryRepository {
+	t.Helper()
+	r := &memorySummaryRepository{summaries: map[string]map[string]types.AttributeValue{}}
+	for _, s := range summaries {
+		item, err := attributevalue.MarshalMap(s)
+		if err != nil {
+			t.Fatalf("failed to marshal summary: %v", err)
+		}
+		r.summaries[s.Id] = item
+	}
+	return r
+}
+
+func (r *memorySummaryRepository) GetSummary(
+	ctx context.Context, id string, userId *string,
+) (*entities.Summary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.summaries[id]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	var s entities.Summary
+	if err := attributevalue.UnmarshalMap(item, &s); err != nil {
+		return nil, err
+	}
+	// DynamoDBのGetSummaryと同様にアップロードされた文書は取得しない
+	s.Upload = nil
+	return &s, nil
+}
+
+func (r *memorySummaryRepository) GetUploadedDocument(ctx context.Context, id string) (*entities.UploadedDocument, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.summaries[id]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	av, ok := item["uploaded_document"]
+	if !ok {
+		return nil, nil
+	}
+	var document entities.UploadedDocument
+	if err := attributevalue.Unmarshal(av, &document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *memorySummaryRepository) UpdateSummary(ctx context.Context, summary *entities.Summary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.summaries[summary.Id]
+	if !ok {
+		return fmt.Errorf("conditional check failed")
+	}
+	update, err := attributevalue.MarshalMap(summary)
+	if err != nil {
+		return err
+	}
+	for k, v := range update {
+		switch k {
+		case "task_status", "status_updated_at", "status_history", "attempts", "cancel_requested":
+			continue
+		}
+		item[k] = v
+	}
+	return nil
+}
+
+func (r *memorySummaryRepository) TransitionTaskStatus(
+	ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	item, ok := r.summaries[summary.Id]
+	if !ok {
+		return fmt.Errorf("conditional check failed")
+	}
+	var current entities.Summary
+	if err := attributevalue.UnmarshalMap(item, &current); err != nil {
+		return err
+	}
+	if current.TaskStatus != summary.TaskStatus {
+		return fmt.Errorf("%w: status is %s", entities.ErrInvalidTransition, current.TaskStatus)
+	}
+	change, err := entities.NewTaskStatusChange(&current, next, reason, time.Now())
+	if err != nil {
+		return err
+	}
+	change.Apply(&current)
+	if next == entities.TaskStatusFailed {
+		current.TaskFailedReason = reason
+	}
+	if change.From == entities.TaskStatusFailed {
+		current.TaskFailedReason = ""
+		current.CancelRequested = false
+	}
+	updated, err := attributevalue.MarshalMap(&current)
+	if err != nil {
+		return err
+	}
+	for _, k := range []string{"task_status", "status_updated_at", "status_history", "attempts", "task_failed_reason", "cancel_requested"} {
+		if v, ok := updated[k]; ok {
+			item[k] = v
+		} else {
+			delete(item, k)
+		}
+	}
+	summary.TaskStatus = current.TaskStatus
+	summary.StatusUpdatedAt = current.StatusUpdatedAt
+	summary.StatusHistory = current.StatusHistory
+	summary.Attempts = current.Attempts
+	summary.TaskFailedReason = current.TaskFailedReason
+	summary.CancelRequested = current.CancelRequested
+	return nil
+}
+
+func (r *memorySummaryRepository) IsCancelRequested(ctx context.Context, id string) (bool, error) {
+	s, err := r.GetSummary(ctx, id, nil)
+	if err != nil {
+		return false, err
+	}
+	return s.CancelRequested, nil
+}
+
+// requestCancelはAPIからのキャンセルの依頼と同様にcancel_requestedのみを更新する
+func (r *memorySummaryRepository) requestCancel(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summaries[id]["cancel_requested"] = &types.AttributeValueMemberBOOL{Value: true}
+}
+
+type fakeCrawler struct {
+	title   string
+	content string
+	raw     string
+	options *entities.FetchOptions
+}
+
+func (c *fakeCrawler) FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error) {
+	c.options = options
+	if options != nil && options.Capture != nil {
+		*options.Capture = entities.PageCapture{Url: url, Raw: []byte(c.raw)}
+	}
+	return c.title, c.content, nil
+}
+
+func Test_ExecuteSummaryTask_Pipeline(t *testing.T) {
+	crawler := &fakeCrawler{
+		title:   "テスト用のページ",
+		content: "これはテスト用のページの本文です。要約のパイプラインをネットワークなしで検証します。",
+	}
+
+	tests := []struct {
+		name      string
+		summary   *entities.Summary
+		config    *SummaryTaskConfig
+		responses func(t *testing.T) []chatgpttest.Response
+		content   string
+		want      *entities.Summary
+		wantCalls int
+		wantPages entities.PageRanges
+		// wantLanguageが指定された場合は、すべてのリクエストでその言語での出力を指示する
+		wantLanguage string
+		wantErr      error
+	}{
+		{
+			name:    "completion",
+			summary: &entities.Summary{Id: "task1", UserId: "user1", PageUrl: "https://example.com", TaskStatus: "request"},
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{chatgpttest.Fixture(t, "completion.json")}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "complete",
+				Summary:        "これはテスト用の要約です。",
+				SourceLanguage: "ja",
+				Usage: &entities.SummaryUsage{
+					Model:            "gpt-4-0613",
+					PromptTokens:     120,
+					CompletionTokens: 15,
+					TotalTokens:      135,
+					Cost:             chatgpt.Cost("gpt-4-0613", chatgpt.Usage{PromptTokens: 120, CompletionTokens: 15}),
+				},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:    "stream",
+			summary: &entities.Summary{Id: "task2", UserId: "user1", PageUrl: "https://example.com", TaskStatus: "request"},
+			config:  &SummaryTaskConfig{Stream: true},
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{chatgpttest.Fixture(t, "completion_stream.txt")}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "complete",
+				Summary:        "これはテスト用の要約です。",
+				SourceLanguage: "ja",
+				Usage: &entities.SummaryUsage{
+					Model:            "gpt-4-0613",
+					PromptTokens:     120,
+					CompletionTokens: 15,
+					TotalTokens:      135,
+					Cost:             chatgpt.Cost("gpt-4-0613", chatgpt.Usage{PromptTokens: 120, CompletionTokens: 15}),
+				},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:    "map reduce",
+			summary: &entities.Summary{Id: "task3", UserId: "user1", PageUrl: "https://example.com", TaskStatus: "request"},
+			config:  &SummaryTaskConfig{TokenBudgets: chatgpt.TokenBudgets{"gpt-4": 1000}},
+			content: strings.Repeat("長い本文のテキストです。", 300),
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{chatgpttest.Completion("部分要約")}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "complete",
+				Summary:        "部分要約",
+				SourceLanguage: "ja",
+			},
+		},
+		{
+			name: "map reduce with language",
+			summary: &entities.Summary{
+				Id: "task7", UserId: "user1", PageUrl: "https://example.com", TaskStatus: "request", Language: "en",
+			},
+			config:  &SummaryTaskConfig{TokenBudgets: chatgpt.TokenBudgets{"gpt-4": 1000}},
+			content: strings.Repeat("長い本文のテキストです。", 300),
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{chatgpttest.Completion("partial summary")}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "complete",
+				Summary:        "partial summary",
+				SourceLanguage: "ja",
+				Language:       "en",
+			},
+			wantLanguage: "en",
+		},
+		{
+			name:    "structured",
+			summary: &entities.Summary{Id: "task4", UserId: "user1", PageUrl: "https://example.com", TaskStatus: "request", Structured: true},
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{chatgpttest.Fixture(t, "structured_summary.json")}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "complete",
+				Summary:        "テスト用の見出し\n\n- 1つ目の要点\n- 2つ目の要点",
+				SourceLanguage: "ja",
+				Structured:     true,
+				StructuredSummary: &entities.StructuredSummary{
+					Headline:  "テスト用の見出し",
+					KeyPoints: []string{"1つ目の要点", "2つ目の要点"},
+					Entities:  []entities.SummaryEntity{{Name: "OpenAI", Type: "organization"}},
+					Sentiment: "neutral",
+					Tags:      []string{"test"},
+				},
+				Usage: &entities.SummaryUsage{
+					Model:            "gpt-4-0613",
+					PromptTokens:     150,
+					CompletionTokens: 40,
+					TotalTokens:      190,
+					Cost:             chatgpt.Cost("gpt-4-0613", chatgpt.Usage{PromptTokens: 150, CompletionTokens: 40}),
+				},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:    "pdf pages",
+			summary: &entities.Summary{Id: "task6", UserId: "user1", PageUrl: "https://example.com/paper.pdf", TaskStatus: "request", PdfPages: "2-3,5"},
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{chatgpttest.Completion("PDFの要約")}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "complete",
+				Summary:        "PDFの要約",
+				SourceLanguage: "ja",
+				PdfPages:       "2-3,5",
+			},
+			wantCalls: 1,
+			wantPages: entities.PageRanges{{First: 2, Last: 3}, {First: 5, Last: 5}},
+		},
+		{
+			name:    "rate limited",
+			summary: &entities.Summary{Id: "task5", UserId: "user1", PageUrl: "https://example.com", TaskStatus: "request"},
+			responses: func(t *testing.T) []chatgpttest.Response {
+				return []chatgpttest.Response{
+					chatgpttest.ErrorFixture(t, http.StatusTooManyRequests, "rate_limit.json"),
+				}
+			},
+			want: &entities.Summary{
+				TaskStatus:     "summarizing",
+				SourceLanguage: "ja",
+			},
+			wantCalls: chatgpttest.RetryPolicy.MaxRetries + 1,
+			wantErr:   chatgpt.ErrRateLimited,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+			server := chatgpttest.NewServer(t, tt.responses(t)...)
+			repo := newMemorySummaryRepository(t, tt.summary)
+			c := *crawler
+			if tt.content != "" {
+				c.content = tt.content
+			}
+
+			sut := NewSummaryTask(repo, nil, &c, nil, server.NewChatGPTService(t), tt.config)
+			err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: tt.summary.Id})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExecuteSummaryTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, err := repo.GetSummary(ctx, tt.summary.Id, nil)
+			if err != nil {
+				t.Fatalf("failed to get summary: %v", err)
+			}
+			if got.Title != c.title || got.Content != c.content {
+				t.Errorf("title or content is not persisted: %v, %v", got.Title, got.Content)
+			}
+			opts := cmp.FilterPath(func(p cmp.Path) bool {
+				switch p.String() {
+				case "Id", "UserId", "PageUrl", "Title", "Content", "StatusUpdatedAt", "StatusHistory", "Attempts":
+					return true
+				case "Usage":
+					// map-reduceの使用量や固定の応答の使用量は検証しない
+					return tt.want.Usage == nil
+				}
+				return false
+			}, cmp.Ignore())
+			if diff := cmp.Diff(tt.want, got, opts); diff != "" {
+				t.Errorf("summary mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantPages, c.options.Pages); diff != "" {
+				t.Errorf("pdf pages mismatch (-want +got):\n%s", diff)
+			}
+			requests := server.Requests()
+			if tt.wantCalls > 0 && len(requests) != tt.wantCalls {
+				t.Errorf("requests = %d, want %d", len(requests), tt.wantCalls)
+			}
+			if tt.wantCalls == 0 && len(requests) < 3 {
+				t.Errorf("content should be summarized with map-reduce: requests = %d", len(requests))
+			}
+			if tt.wantLanguage != "" {
+				instruction := "要約は必ず" + language.DisplayName(tt.wantLanguage) + "で出力してください。"
+				for i, request := range requests {
+					prompt := request.Messages[len(request.Messages)-1].Content
+					if !strings.Contains(prompt, instruction) {
+						t.Errorf("request %d does not specify the output language: %s", i, prompt)
+					}
+				}
+			}
+		})
+	}
+}
+
+// memoryUploadStorageはキーごとの文書の内容をメモリに保持する
+type memoryUploadStorage map[string][]byte
+
+func (s memoryUploadStorage) GetObject(ctx context.Context, key string) ([]byte, error) {
+	data, ok := s[key]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", key)
+	}
+	return data, nil
+}
+
+func Test_ExecuteSummaryTask_Upload(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		upload    *entities.UploadedDocument
+		data      string
+		wantTitle string
+		wantErr   bool
+	}{
+		{
+			name: "markdown",
+			upload: &entities.UploadedDocument{
+				FileName:    "minutes.md",
+				ContentType: entities.UploadContentTypeMarkdown,
+				Key:         entities.UploadKey("task1"),
+			},
+			data:      "# 定例会議の議事録\n\n社内の定例会議で決まったことをまとめます。",
+			wantTitle: "定例会議の議事録",
+		},
+		{
+			name:  "title in request",
+			title: "社内資料",
+			upload: &entities.UploadedDocument{
+				ContentType: entities.UploadContentTypeText,
+				Key:         entities.UploadKey("task1"),
+			},
+			data:      "社内の定例会議で決まったことをまとめます。",
+			wantTitle: "社内資料",
+		},
+		{
+			name: "object not found",
+			upload: &entities.UploadedDocument{
+				ContentType: entities.UploadContentTypeText,
+				Key:         entities.UploadKey("other"),
+			},
+			data:    "社内の定例会議で決まったことをまとめます。",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+			server := chatgpttest.NewServer(t, chatgpttest.Completion("議事録の要約"))
+			repo := newMemorySummaryRepository(t, &entities.Summary{
+				Id: "task1", UserId: "user1", TaskStatus: "request", Title: tt.title, Upload: tt.upload,
+			})
+			c := &fakeCrawler{}
+			uploads := memoryUploadStorage{entities.UploadKey("task1"): []byte(tt.data)}
+
+			sut := NewSummaryTask(repo, nil, c, document.NewReader(nil), server.NewChatGPTService(t), &SummaryTaskConfig{
+				Uploads: uploads,
+			})
+			err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteSummaryTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if c.options != nil {
+				t.Errorf("page should not be crawled for uploaded document")
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := repo.GetSummary(ctx, "task1", nil)
+			if err != nil {
+				t.Fatalf("failed to get summary: %v", err)
+			}
+			if got.TaskStatus != "complete" || got.Summary != "議事録の要約" {
+				t.Errorf("unexpected summary: %v, %v", got.TaskStatus, got.Summary)
+			}
+			if got.Title != tt.wantTitle || !strings.Contains(got.Content, "定例会議で決まったこと") {
+				t.Errorf("unexpected title or content: %v, %v", got.Title, got.Content)
+			}
+			upload, err := repo.GetUploadedDocument(ctx, "task1")
+			if err != nil {
+				t.Fatalf("failed to get uploaded document: %v", err)
+			}
+			if upload == nil || upload.Key != tt.upload.Key {
+				t.Errorf("uploaded document should be kept: %+v", upload)
+			}
+		})
+	}
+}
+
+type fakeArchiver struct {
+	err      error
+	captures []*entities.PageCapture
+}
+
+func (a *fakeArchiver) Save(ctx context.Context, taskId string, capture *entities.PageCapture) (*entities.PageSnapshot, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	a.captures = append(a.captures, capture)
+	return &entities.PageSnapshot{
+		Url:         capture.Url,
+		ContentHash: capture.ContentHash(),
+		RawKey:      entities.SnapshotKeyPrefix(taskId, capture.ContentHash()) + "page.html",
+	}, nil
+}
+
+func Test_ExecuteSummaryTask_Snapshot(t *testing.T) {
+	tests := []struct {
+		name         string
+		archiver     *fakeArchiver
+		wantSnapshot bool
+	}{
+		{name: "saved", archiver: &fakeArchiver{}, wantSnapshot: true},
+		// スナップショットの保存に失敗しても要約は完了する
+		{name: "failed to save", archiver: &fakeArchiver{err: errors.New("s3 is unavailable")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+			server := chatgpttest.NewServer(t, chatgpttest.Completion("ページの要約"))
+			repo := newMemorySummaryRepository(t, &entities.Summary{
+				Id: "task1", UserId: "user1", TaskStatus: "request", PageUrl: "https://example.com/article",
+			})
+			c := &fakeCrawler{title: "記事", content: "記事の本文です。", raw: "<html><body>記事の本文です。</body></html>"}
+
+			sut := NewSummaryTask(repo, nil, c, nil, server.NewChatGPTService(t), &SummaryTaskConfig{Snapshots: tt.archiver})
+			if err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"}); err != nil {
+				t.Fatalf("ExecuteSummaryTask() error = %v", err)
+			}
+			got, err := repo.GetSummary(ctx, "task1", nil)
+			if err != nil {
+				t.Fatalf("failed to get summary: %v", err)
+			}
+			if got.TaskStatus != "complete" {
+				t.Errorf("TaskStatus = %v, want complete", got.TaskStatus)
+			}
+			if !tt.wantSnapshot {
+				if got.Snapshot != nil {
+					t.Errorf("Snapshot = %+v, want nil", got.Snapshot)
+				}
+				return
+			}
+			if len(tt.archiver.captures) != 1 || string(tt.archiver.captures[0].Raw) != c.raw {
+				t.Fatalf("unexpected captures: %v", tt.archiver.captures)
+			}
+			want := &entities.PageSnapshot{
+				Url:         "https://example.com/article",
+				ContentHash: tt.archiver.captures[0].ContentHash(),
+				RawKey:      "snapshots/task1/" + tt.archiver.captures[0].ContentHash() + "/page.html",
+			}
+			if diff := cmp.Diff(want, got.Snapshot); diff != "" {
+				t.Errorf("Snapshot mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ExecuteSummaryTask_StatusHistory(t *testing.T) {
+	ctx := logging.SetLogger(context.Background(), logging.NewLogger(io.Discard))
+	server := chatgpttest.NewServer(t, chatgpttest.Completion("ページの要約"))
+	summary := &entities.Summary{Id: "task1", UserId: "user1", PageUrl: "https://example.com/article"}
+	summary.InitTaskStatus(time.Now())
+	repo := newMemorySummaryRepository(t, summary)
+	c := &fakeCrawler{title: "記事", content: "記事の本文です。"}
+
+	sut := NewSummaryTask(repo, nil, c, nil, server.NewChatGPTService(t), nil)
+	if err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"}); err != nil {
+		t.Fatalf("ExecuteSummaryTask() error = %v", err)
+	}
+	got, err := repo.GetSummary(ctx, "task1", nil)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	var statuses []entities.TaskStatus
+	for _, change := range got.StatusHistory {
+		statuses = append(statuses, change.Status)
+	}
+	want := []entities.TaskStatus{
+		entities.TaskStatusQueued,
+		entities.TaskStatusCrawling,
+		entities.TaskStatusCrawled,
+		entities.TaskStatusSummarizing,
+		entities.TaskStatusComplete,
+	}
+	if diff := cmp.Diff(want, statuses); diff != "" {
+		t.Errorf("status history mismatch (-want +got):\n%s", diff)
+	}
+
+	// 重複して配信されたメッセージでは完了したタスクを処理しない
+	if err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: "task1"}); err != nil {
+		t.Fatalf("ExecuteSummaryTask() error = %v", err)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("requests = %d, want 1", len(server.Requests()))
+	}
+
+	// 完了したタスクは失敗に遷移しない
+	if _, err := sut.FailTask(ctx, "task1", 0, chatgpt.ErrTimeout); err != nil {
+		t.Fatalf("FailTask() error = %v", err)
+	}
+	got, err = repo.GetSummary(ctx, "task1", nil)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if got.TaskStatus != entities.TaskStatusComplete || got.TaskFailedReason != "" {
+		t.Errorf("unexpected status: %v, %v", got.TaskStatus, got.TaskFailedReason)
+	}
+}
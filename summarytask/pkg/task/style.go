@@ -0,0 +1,74 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
+	"github.com/shoet/web-page-summarizer-task/pkg/language"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
+)
+
+// promptBuilderは要約のスタイルに応じてタイトルと本文からプロンプトを生成する関数
+type promptBuilder func(input *chatgpt.SummaryTemplateInput) (string, error)
+
+// promptBuilderはタスクに指定されたスタイルのpromptBuilderを返す
+// 組み込みのスタイルでない場合はタスクを依頼したユーザーのテンプレートを利用する
+// 構造化出力の場合はスタイルによらず構造化出力用のテンプレートを利用する
+func (st *SummaryTask) promptBuilder(ctx context.Context, s *entities.Summary) (promptBuilder, error) {
+	if s.Structured {
+		return chatgpt.StructuredSummaryTemplateBuilder, nil
+	}
+	style := s.Style
+	if style == "" || style == entities.SummaryStyleDefault {
+		return chatgpt.SummaryTemplateBuilder, nil
+	}
+	if entities.IsBuiltinSummaryStyle(style) {
+		return func(input *chatgpt.SummaryTemplateInput) (string, error) {
+			return chatgpt.StyleTemplateBuilder(style, input)
+		}, nil
+	}
+	if st.templateRepo == nil {
+		return nil, fmt.Errorf("prompt template repository is not configured")
+	}
+	t, err := st.templateRepo.GetPromptTemplate(ctx, s.UserId, style)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt template %s: %w", style, err)
+	}
+	return func(input *chatgpt.SummaryTemplateInput) (string, error) {
+		return chatgpt.CustomTemplateBuilder(t.Body, input)
+	}, nil
+}
+
+// reducePromptは部分要約を統合するプロンプトを生成する
+// デフォルトのスタイルでは統合用のテンプレートを利用し、それ以外のスタイルや構造化出力では
+// 部分要約を本文としてスタイルのテンプレートを適用して、最終的な要約の形をスタイルに合わせる
+func reducePrompt(
+	s *entities.Summary, build promptBuilder, title string, summaries []string,
+) (string, error) {
+	if !s.Structured && (s.Style == "" || s.Style == entities.SummaryStyleDefault) {
+		return chatgpt.ReduceSummaryTemplateBuilder(&chatgpt.ReduceSummaryTemplateInput{
+			Title:     title,
+			Summaries: summaries,
+			Language:  outputLanguage(s),
+		})
+	}
+	content := strings.Builder{}
+	for i, summary := range summaries {
+		fmt.Fprintf(&content, "[%d]\n%s\n\n", i+1, summary)
+	}
+	return build(&chatgpt.SummaryTemplateInput{
+		Title:    title,
+		Content:  content.String(),
+		Language: outputLanguage(s),
+	})
+}
+
+// outputLanguageはタスクに指定された出力言語をプロンプトで指示する言語名に変換する
+func outputLanguage(s *entities.Summary) string {
+	if s.Language == "" {
+		return ""
+	}
+	return language.DisplayName(s.Language)
+}
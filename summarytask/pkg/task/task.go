@@ -2,102 +2,408 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/shoet/web-page-summarizer-task/pkg/chatgpt"
-	"github.com/shoet/webpagesummary/pkg/infrastracture/repository"
+	"github.com/shoet/web-page-summarizer-task/pkg/language"
+	"github.com/shoet/webpagesummary/pkg/infrastracture/entities"
 	"github.com/shoet/webpagesummary/pkg/logging"
 )
 
+var (
+	// ErrMissingSourceはタスクに要約するURLもアップロードされた文書もない場合のエラー
+	ErrMissingSource = errors.New("pageurl is empty")
+	// ErrEmptySummaryはLLMが空の要約を返した場合のエラー
+	ErrEmptySummary = errors.New("summary is empty")
+	// ErrInvalidStructuredSummaryは構造化出力の要約がスキーマを満たさない場合のエラー
+	ErrInvalidStructuredSummary = errors.New("invalid structured summary")
+	// ErrInterruptedは前回の実行がワーカーのタイムアウトなどで中断され、最大の実行回数に達した場合のエラー
+	ErrInterrupted = errors.New("previous attempt was interrupted")
+)
+
 type Logger interface {
 	Info(args ...interface{})
 	Error(args ...interface{})
 }
 
 type Crawler interface {
-	FetchContents(url string) (string, string, error)
+	FetchContents(ctx context.Context, url string, options *entities.FetchOptions) (string, string, error)
+}
+
+// DocumentReaderはアップロードされた文書からタイトルと本文を抽出する
+type DocumentReader interface {
+	ReadDocument(document *entities.UploadedDocument, options *entities.FetchOptions) (string, string, error)
+}
+
+// SnapshotArchiverはクローラーが取得した時点のページを保存する
+type SnapshotArchiver interface {
+	Save(ctx context.Context, taskId string, capture *entities.PageCapture) (*entities.PageSnapshot, error)
+}
+
+// SummaryRepositoryはタスクの状態と要約を保存するリポジトリ
+type SummaryRepository interface {
+	GetSummary(ctx context.Context, id string, userId *string) (*entities.Summary, error)
+	// UpdateSummaryは状態以外の項目を更新する
+	UpdateSummary(ctx context.Context, summary *entities.Summary) error
+	// TransitionTaskStatusは状態を遷移させ、遷移できない場合はentities.ErrInvalidTransitionを返す
+	TransitionTaskStatus(ctx context.Context, summary *entities.Summary, next entities.TaskStatus, reason string) error
+	// IsCancelRequestedはタスクのキャンセルが依頼されているかを返す
+	IsCancelRequested(ctx context.Context, id string) (bool, error)
+	// GetUploadedDocumentはアップロードされた文書のキーと形式を返す。文書がない場合はnil
+	GetUploadedDocument(ctx context.Context, id string) (*entities.UploadedDocument, error)
+}
+
+// UploadStorageはアップロードされた文書の内容を保存したオブジェクトストレージ
+type UploadStorage interface {
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// PromptTemplateRepositoryはユーザーが定義したプロンプトテンプレートを取得するリポジトリ
+type PromptTemplateRepository interface {
+	GetPromptTemplate(ctx context.Context, userId string, name string) (*entities.PromptTemplate, error)
+}
+
+// Summarizerは要約に利用するLLMプロバイダーを抽象化したインターフェース
+type Summarizer interface {
+	ChatCompletions(
+		ctx context.Context, input *chatgpt.ChatCompletionsInput,
+	) (*chatgpt.ChatCompletionsOutput, error)
+	Model() string
+}
+
+// StreamSummarizerはストリーミングでの要約に対応したSummarizer
+type StreamSummarizer interface {
+	Summarizer
+	ChatCompletionsStream(
+		ctx context.Context, input *chatgpt.ChatCompletionsInput, onDelta chatgpt.StreamDeltaFunc,
+	) (*chatgpt.ChatCompletionsOutput, error)
+}
+
+const DefaultStreamFlushInterval = time.Second
+
+type SummaryTaskConfig struct {
+	// StreamがtrueかつSummarizerがStreamSummarizerを満たす場合はストリーミングで要約する
+	Stream bool
+	// StreamFlushIntervalはストリーミング中の要約をDynamoDBに書き込む最小間隔
+	StreamFlushInterval time.Duration
+	// TokenBudgetsはモデルごとの1リクエストあたりのトークン予算
+	// 本文が予算を超える場合は分割して要約する
+	TokenBudgets chatgpt.TokenBudgets
+	// Snapshotsが指定された場合は、取得したページのスナップショットを保存する
+	Snapshots SnapshotArchiver
+	// Uploadsはアップロードされた文書の内容を読み込むオブジェクトストレージ
+	Uploads UploadStorage
+	// RetryPolicyは一時的なエラーで失敗したタスクを再実行する方法
+	RetryPolicy RetryPolicy
+	// CancelPollIntervalは実行中にキャンセルの依頼を確認する間隔
+	CancelPollInterval time.Duration
 }
 
 type SummaryTask struct {
-	repo    *repository.SummaryRepository
-	crawler Crawler
-	chatgpt *chatgpt.ChatGPTService
-	logger  Logger
+	repo         SummaryRepository
+	templateRepo PromptTemplateRepository
+	crawler      Crawler
+	documents    DocumentReader
+	summarizer   Summarizer
+	logger       Logger
+	config       SummaryTaskConfig
 }
 
 func NewSummaryTask(
-	repo *repository.SummaryRepository,
+	repo SummaryRepository,
+	templateRepo PromptTemplateRepository,
 	crawler Crawler,
-	chatgpt *chatgpt.ChatGPTService,
+	documents DocumentReader,
+	summarizer Summarizer,
+	config *SummaryTaskConfig,
 ) *SummaryTask {
+	cfg := SummaryTaskConfig{
+		StreamFlushInterval: DefaultStreamFlushInterval,
+		RetryPolicy:         DefaultRetryPolicy,
+		CancelPollInterval:  DefaultCancelPollInterval,
+	}
+	if config != nil {
+		cfg.Stream = config.Stream
+		cfg.TokenBudgets = config.TokenBudgets
+		cfg.Snapshots = config.Snapshots
+		cfg.Uploads = config.Uploads
+		if config.StreamFlushInterval > 0 {
+			cfg.StreamFlushInterval = config.StreamFlushInterval
+		}
+		if config.RetryPolicy.MaxAttempts > 0 {
+			cfg.RetryPolicy = config.RetryPolicy
+		}
+		if config.CancelPollInterval > 0 {
+			cfg.CancelPollInterval = config.CancelPollInterval
+		}
+	}
 	return &SummaryTask{
-		repo:    repo,
-		crawler: crawler,
-		chatgpt: chatgpt,
+		repo:         repo,
+		templateRepo: templateRepo,
+		crawler:      crawler,
+		documents:    documents,
+		summarizer:   summarizer,
+		config:       cfg,
 	}
 }
 
-func (st *SummaryTask) ExecuteSummaryTask(ctx context.Context, taskId string) error {
+func (st *SummaryTask) ExecuteSummaryTask(ctx context.Context, message *entities.TaskMessage) error {
 	logger := logging.GetLogger(ctx)
 	logger.Info("start to execute task")
 
 	// get task from dynamodb
 	logger.Info("get task from dynamodb")
-	s, err := st.repo.GetSummary(ctx, taskId, nil)
+	s, err := st.repo.GetSummary(ctx, message.TaskId, nil)
 	if err != nil {
 		return fmt.Errorf("failed to get summary: %w", err)
 	}
+	if message.Language != "" {
+		s.Language = message.Language
+	}
 
-	if s.PageUrl == "" {
-		return fmt.Errorf("pageurl is empty")
+	if s.TaskStatus.Terminal() || s.TaskStatus == entities.TaskStatusFailed {
+		// SQSのメッセージが重複して配信された場合など、終了したタスクは処理しない
+		// 失敗したタスクは再実行の依頼で新たに登録されたメッセージでのみ処理する
+		logger.Info(fmt.Sprintf("task is already %s", s.TaskStatus))
+		return nil
+	}
+	if s.CancelRequested {
+		// 実行を開始する前にキャンセルが依頼された
+		return st.cancelTask(ctx, s)
+	}
+	// 文書の形式とキーはGetSummaryでは取得しないため、別に取得する
+	upload, err := st.repo.GetUploadedDocument(ctx, s.Id)
+	if err != nil {
+		return fmt.Errorf("failed to get uploaded document: %w", err)
+	}
+	s.Upload = upload
+	if s.PageUrl == "" && s.Upload == nil {
+		return ErrMissingSource
+	}
+	if s.TaskStatus.InProgress() {
+		// 前回の実行がLambdaのタイムアウトなどで中断され、メッセージが再び配信された
+		if s.Attempts >= st.config.RetryPolicy.MaxAttempts {
+			return ErrInterrupted
+		}
+		logger.Info(fmt.Sprintf("previous attempt %d was interrupted while %s", s.Attempts, s.TaskStatus))
+		if err := st.repo.TransitionTaskStatus(
+			ctx, s, entities.TaskStatusRetrying, string(entities.TaskFailureInterrupted),
+		); err != nil {
+			return fmt.Errorf("failed to update task status: %w", err)
+		}
 	}
 
-	// dynamodb update taskId status to processing
-	s.TaskStatus = "processing"
-	logger.Info("task is processing")
-	if err := st.repo.UpdateSummary(ctx, s); err != nil {
-		return fmt.Errorf("failed to update summary: %w", err)
+	runCtx, stop := st.watchCancel(ctx, s.Id)
+	err = st.runSummaryTask(runCtx, s)
+	cancelled := errors.Is(context.Cause(runCtx), ErrCancelled) || errors.Is(err, ErrCancelled)
+	stop()
+	if err != nil && cancelled {
+		// キャンセルにより中断したページの取得や要約のエラーは失敗として扱わない
+		return st.cancelTask(ctx, s)
+	}
+	return err
+}
+
+// runSummaryTaskはページを取得して要約し、タスクを完了した状態にする
+// キャンセルが依頼された場合はctxがキャンセルされ、実行中の処理を中断する
+func (st *SummaryTask) runSummaryTask(ctx context.Context, s *entities.Summary) error {
+	logger := logging.GetLogger(ctx)
+	logger.Info("task is crawling")
+	if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusCrawling, ""); err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
 	}
 
 	// scrape title, content
 	logger.Info("processing scrape contents")
-	title, content, err := st.crawler.FetchContents(s.PageUrl)
+	pages, err := entities.ParsePageRanges(s.PdfPages)
+	if err != nil {
+		return fmt.Errorf("failed to parse pdf pages: %w", err)
+	}
+	title, content, snapshot, err := st.fetchContents(ctx, s, &entities.FetchOptions{Pages: pages})
 	if err != nil {
-		return fmt.Errorf("failed to scrape body: %w", err)
+		return err
 	}
 
 	// dynamodb update title, content
 	logger.Info("update title, content")
 	s.Title = title
 	s.Content = content
+	s.SourceLanguage = language.Detect(content)
+	s.Snapshot = snapshot
 	if err := st.repo.UpdateSummary(ctx, s); err != nil {
 		return fmt.Errorf("failed to update summary: %w", err)
 	}
+	if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusCrawled, ""); err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
+	}
+
+	if err := st.checkCancel(ctx, s.Id); err != nil {
+		return err
+	}
+
+	logger.Info("task is summarizing")
+	if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusSummarizing, ""); err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
+	}
 
 	// request chatgpt api get content summary
 	logger.Info("processing text summary")
-	summaryTemplate, err := chatgpt.SummaryTemplateBuilder(&chatgpt.SummaryTemplateInput{
-		Title:   title,
-		Content: content,
-	})
+	output, err := st.summarizeContent(ctx, s, title, content)
 	if err != nil {
-		return fmt.Errorf("failed to build summary template: %w", err)
+		return fmt.Errorf("failed to summarize: %w", err)
 	}
-
-	logger.Info("request chatgpt api")
-	summary, err := st.chatgpt.ChatCompletions(&chatgpt.ChatCompletionsInput{
-		Text: summaryTemplate,
-	})
-	if summary == "" {
-		return fmt.Errorf("failed to get summary is empty: %w", err)
+	if output.Text == "" {
+		return fmt.Errorf("failed to get summary: %w", ErrEmptySummary)
 	}
-	s.Summary = summary
-	s.TaskStatus = "complete"
+	s.Summary = output.Text
+	if s.Structured {
+		// 不正な構造のまま保存しないよう、検証に失敗した場合はタスクを失敗させる
+		structured, err := entities.ParseStructuredSummary(output.Text)
+		if err != nil {
+			return fmt.Errorf("failed to parse structured summary: %w: %w", ErrInvalidStructuredSummary, err)
+		}
+		s.StructuredSummary = structured
+		s.Summary = structured.Text()
+	}
+	s.Usage = NewSummaryUsage(output)
 
 	// dynamodb update summary, status complete
 	logger.Info("update summary, status complete")
 	if err := st.repo.UpdateSummary(ctx, s); err != nil {
 		return fmt.Errorf("failed to update summary: %w", err)
 	}
+	if err := st.repo.TransitionTaskStatus(ctx, s, entities.TaskStatusComplete, ""); err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
+	}
+	logger.Logger().Info().
+		Interface("stageDurationsMs", entities.StageDurations(s.StatusHistory)).
+		Msg("task is complete")
 	return nil
 }
+
+// fetchContentsはアップロードされた文書があれば文書から、なければページを取得して本文を返す
+// ページを取得した場合は、設定に応じて取得した時点のページのスナップショットを保存する
+func (st *SummaryTask) fetchContents(
+	ctx context.Context, s *entities.Summary, options *entities.FetchOptions,
+) (string, string, *entities.PageSnapshot, error) {
+	if s.Upload == nil {
+		if st.config.Snapshots != nil {
+			options.Capture = &entities.PageCapture{}
+		}
+		title, content, err := st.crawler.FetchContents(ctx, s.PageUrl, options)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("failed to scrape body: %w", err)
+		}
+		return title, content, st.saveSnapshot(ctx, s.Id, options.Capture), nil
+	}
+	if st.documents == nil {
+		return "", "", nil, fmt.Errorf("document reader is not configured")
+	}
+	document, err := st.loadUpload(ctx, s.Upload)
+	if err != nil {
+		return "", "", nil, err
+	}
+	title, content, err := st.documents.ReadDocument(document, options)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to read uploaded document: %w", err)
+	}
+	if s.Title != "" {
+		// リクエストで指定されたタイトルを優先する
+		title = s.Title
+	}
+	return title, content, nil, nil
+}
+
+// loadUploadはオブジェクトストレージから文書の内容を読み込む
+func (st *SummaryTask) loadUpload(ctx context.Context, upload *entities.UploadedDocument) (*entities.UploadedDocument, error) {
+	if len(upload.Data) > 0 {
+		return upload, nil
+	}
+	if st.config.Uploads == nil {
+		return nil, fmt.Errorf("upload storage is not configured")
+	}
+	if upload.Key == "" {
+		return nil, fmt.Errorf("uploaded document has no key")
+	}
+	data, err := st.config.Uploads.GetObject(ctx, upload.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uploaded document: %w", err)
+	}
+	document := *upload
+	document.Data = data
+	return &document, nil
+}
+
+// saveSnapshotはクローラーが記録したページを保存する
+// スナップショットは調査のためのもので、保存に失敗しても要約は継続する
+func (st *SummaryTask) saveSnapshot(ctx context.Context, taskId string, capture *entities.PageCapture) *entities.PageSnapshot {
+	if st.config.Snapshots == nil || capture.Empty() {
+		return nil
+	}
+	snapshot, err := st.config.Snapshots.Save(ctx, taskId, capture)
+	if err != nil {
+		logging.GetLogger(ctx).Error("failed to save snapshot", err)
+		return nil
+	}
+	return snapshot
+}
+
+// summarizeは設定に応じて通常もしくはストリーミングで要約する
+// ストリーミング時は途中までの要約をStreamFlushIntervalごとにDynamoDBのsummaryへ書き込む
+// 構造化出力は途中までのJSONに意味がないためストリーミングしない
+func (st *SummaryTask) summarize(
+	ctx context.Context, s *entities.Summary, input *chatgpt.ChatCompletionsInput,
+) (*chatgpt.ChatCompletionsOutput, error) {
+	streamSummarizer, ok := st.summarizer.(StreamSummarizer)
+	if !st.config.Stream || !ok || input.Tool != nil {
+		return st.summarizer.ChatCompletions(ctx, input)
+	}
+
+	logger := logging.GetLogger(ctx)
+	partial := strings.Builder{}
+	lastFlushedAt := time.Now()
+	return streamSummarizer.ChatCompletionsStream(ctx, input, func(delta string) error {
+		partial.WriteString(delta)
+		if time.Since(lastFlushedAt) < st.config.StreamFlushInterval {
+			return nil
+		}
+		lastFlushedAt = time.Now()
+		// 途中経過の書き込みに失敗しても要約自体は継続する
+		if err := st.repo.UpdateSummary(ctx, &entities.Summary{
+			Id:      s.Id,
+			UserId:  s.UserId,
+			Summary: partial.String(),
+		}); err != nil {
+			logger.Error("failed to flush partial summary", err)
+		}
+		return nil
+	})
+}
+
+// NewSummaryUsageは要約の結果からトークン使用量と料金を算出する
+func NewSummaryUsage(output *chatgpt.ChatCompletionsOutput) *entities.SummaryUsage {
+	return &entities.SummaryUsage{
+		Model:            output.Model,
+		PromptTokens:     output.Usage.PromptTokens,
+		CompletionTokens: output.Usage.CompletionTokens,
+		TotalTokens:      output.Usage.TotalTokens,
+		Cost:             chatgpt.Cost(output.Model, output.Usage),
+	}
+}
+
+// NewChatCompletionsOptionsはタスクに指定されたオプションをChatCompletionsOptionsに変換する
+func NewChatCompletionsOptions(options *entities.SummaryOptions) *chatgpt.ChatCompletionsOptions {
+	if options == nil {
+		return nil
+	}
+	return &chatgpt.ChatCompletionsOptions{
+		Model:       options.Model,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		MaxTokens:   options.MaxTokens,
+		Seed:        options.Seed,
+	}
+}
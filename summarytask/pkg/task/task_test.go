@@ -1,3 +1,5 @@
+//go:build integration
+
 package task
 
 import (
@@ -105,7 +107,7 @@ func Prepare_ExecuteSummaryTask(t *testing.T, ctx context.Context, cfg aws.Confi
 		t.Fatalf("failed to queue: %v", err)
 	}
 
-	repo := repository.NewSummaryRepository(dynamodb.NewFromConfig(cfg))
+	repo := repository.NewSummaryRepository(dynamodb.NewFromConfig(cfg), nil)
 	_, err := repo.CreateSummary(ctx, &entities.Summary{
 		Id:         taskId,
 		PageUrl:    "https://news.yahoo.co.jp/pickup/6484213",
@@ -130,7 +132,7 @@ func Test_ExecuteSummaryTask(t *testing.T) {
 	taskId := Prepare_ExecuteSummaryTask(t, ctx, *testAwsCfg)
 
 	db := dynamodb.NewFromConfig(*testAwsCfg)
-	pageRepository := repository.NewSummaryRepository(db)
+	pageRepository := repository.NewSummaryRepository(db, nil)
 
 	pageCrawler, err := crawler.NewPageCrawler(&crawler.PageCrawlerInput{
 		BrowserPath: "/opt/homebrew/bin/chromium", // TODO local
@@ -144,13 +146,13 @@ func Test_ExecuteSummaryTask(t *testing.T) {
 		t.Fatalf("failed to get api key")
 	}
 	client := &http.Client{}
-	chatgptApi, err := chatgpt.NewChatGPTService(apiKey, client)
+	chatgptApi, err := chatgpt.NewChatGPTService(apiKey, client, nil)
 	if err != nil {
 		t.Fatalf("failed to create ChatGPTService: %v", err)
 	}
 
-	sut := NewSummaryTask(pageRepository, pageCrawler, chatgptApi)
-	if err := sut.ExecuteSummaryTask(ctx, taskId); err != nil {
+	sut := NewSummaryTask(pageRepository, nil, pageCrawler, nil, chatgptApi, nil)
+	if err := sut.ExecuteSummaryTask(ctx, &entities.TaskMessage{TaskId: taskId}); err != nil {
 		t.Fatalf("failed to execute summary task: %v", err)
 	}
 }